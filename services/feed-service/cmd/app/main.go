@@ -8,11 +8,15 @@ import (
 	"strconv"
 	"time"
 
+	"feed-service/internal/account"
+	"feed-service/internal/audit"
+	"feed-service/internal/block"
 	"feed-service/internal/feed"
 	"feed-service/internal/kafka"
 	"feed-service/internal/ratelimit"
 	"feed-service/internal/shared/httpx"
 	"feed-service/internal/shared/redisx"
+	"feed-service/internal/shared/revoke"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
@@ -87,6 +91,8 @@ func main() {
 		}, next)
 	}
 
+	blocks := block.NewClient(os.Getenv("USER_SERVICE_URL"))
+
 	// Repo & Service
 	repo := feed.NewRepository(rdb)
 	svc := feed.NewService(
@@ -94,6 +100,7 @@ func main() {
 		feed.WithUserServiceBase(os.Getenv("USER_SERVICE_URL")),
 		feed.WithPostServiceBase(os.Getenv("POST_SERVICE_URL")), // optional enrichment endpoint
 		feed.WithDefaultFeedLimit(atoiDef(os.Getenv("FEED_DEFAULT_LIMIT"), 100)),
+		feed.WithBlockClient(blocks),
 	)
 
 	// Kafka consumer
@@ -114,16 +121,39 @@ func main() {
 			log.Printf("kafka consumer stopped: %v", err)
 		}
 	}()
+	go func() {
+		if err := kafka.StartDeletedConsumer(ctx, bootstrap, "posts.deleted", groupID+"-deleted", repo.HandlePostsDeleted); err != nil {
+			log.Printf("kafka deleted consumer stopped: %v", err)
+		}
+	}()
+	go func() {
+		if err := blocks.Watch(ctx, bootstrap, "feed-service"); err != nil {
+			log.Printf("block watcher stopped: %v", err)
+		}
+	}()
+	acc := account.New(account.NewReporter(os.Getenv("USER_SERVICE_URL")), account.Part{Name: "feeds", Eraser: svc})
+	go func() {
+		if err := acc.Watch(ctx, bootstrap); err != nil {
+			log.Printf("account watcher stopped: %v", err)
+		}
+	}()
 
 	// HTTP
+	revoked := revoke.OpenFromEnv()
+	defer revoked.Close()
+	httpx.UseRevocations(revoked)
+	audits := audit.NewPublisher(bootstrap)
+	defer audits.Close()
+	httpx.UseAudit(audits)
+
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 
 	h := feed.NewHandler(svc)
 
 	// Public:
-	mux.Handle("GET /users/{user_id}/feed", httpx.Wrap(h.GetAuthorFeed))
-	mux.Handle("GET /celebrities/{user_id}/feed", httpx.Wrap(h.GetCelebrityFeed))
+	mux.Handle("GET /users/{user_id}/feed", httpx.OptionalAuth(httpx.Wrap(h.GetAuthorFeed)))
+	mux.Handle("GET /celebrities/{user_id}/feed", httpx.OptionalAuth(httpx.Wrap(h.GetCelebrityFeed)))
 	mux.Handle("GET /celebrities", httpx.Wrap(h.ListCelebrities))
 
 	// Protected:
@@ -133,8 +163,8 @@ func main() {
 	protect("GET /feed", httpx.Wrap(h.GetHomeFeed))
 	protect("POST /feed/rebuild", rebuildLimit(httpx.Wrap(h.RebuildHomeFeed)))
 
-	protect("POST /celebrities/{user_id}", httpx.Wrap(h.PromoteCelebrity))
-	protect("DELETE /celebrities/{user_id}", httpx.Wrap(h.DemoteCelebrity))
+	protect("POST /celebrities/{user_id}", httpx.RequireRole(httpx.RoleAdmin, httpx.Wrap(h.PromoteCelebrity)))
+	protect("DELETE /celebrities/{user_id}", httpx.RequireRole(httpx.RoleAdmin, httpx.Wrap(h.DemoteCelebrity)))
 
 	protect("GET /whoami", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		uid, err := httpx.UserFromCtx(r)
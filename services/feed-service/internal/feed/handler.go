@@ -2,7 +2,6 @@ package feed
 
 import (
 	"net/http"
-	"os"
 
 	"feed-service/internal/shared/httpx"
 )
@@ -14,9 +13,10 @@ func NewHandler(s Service) *Handler { return &Handler{svc: s} }
 // Public: feed by author
 func (h *Handler) GetAuthorFeed(w http.ResponseWriter, r *http.Request) error {
 	uid := r.PathValue("user_id")
+	viewer, _ := httpx.UserFromCtx(r)
 	limit := httpx.QueryInt(r, "limit", 50)
 	offset := httpx.QueryInt(r, "offset", 0)
-	items, err := h.svc.GetAuthorFeed(r.Context(), uid, limit, offset)
+	items, err := h.svc.GetAuthorFeed(r.Context(), viewer, uid, limit, offset)
 	if err != nil {
 		return err
 	}
@@ -31,12 +31,20 @@ func (h *Handler) GetHomeFeed(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 	limit := httpx.QueryInt(r, "limit", 50)
-	offset := httpx.QueryInt(r, "offset", 0)
-	items, err := h.svc.GetHomeFeed(r.Context(), uid, limit, offset)
+	// ?offset= is kept for older clients; otherwise page by ?cursor=.
+	if offset := httpx.QueryInt(r, "offset", 0); offset > 0 {
+		items, err := h.svc.GetHomeFeed(r.Context(), uid, limit, offset)
+		if err != nil {
+			return err
+		}
+		httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+		return nil
+	}
+	page, err := h.svc.PageHomeFeed(r.Context(), uid, r.URL.Query().Get("cursor"), limit)
 	if err != nil {
 		return err
 	}
-	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	httpx.WriteJSON(w, page, http.StatusOK)
 	return nil
 }
 
@@ -59,9 +67,10 @@ func (h *Handler) RebuildHomeFeed(w http.ResponseWriter, r *http.Request) error
 // Public: feed by celebrity user_id
 func (h *Handler) GetCelebrityFeed(w http.ResponseWriter, r *http.Request) error {
 	uid := r.PathValue("user_id")
+	viewer, _ := httpx.UserFromCtx(r)
 	limit := httpx.QueryInt(r, "limit", 50)
 	offset := httpx.QueryInt(r, "offset", 0)
-	items, err := h.svc.GetCelebrityFeed(r.Context(), uid, limit, offset)
+	items, err := h.svc.GetCelebrityFeed(r.Context(), viewer, uid, limit, offset)
 	if err != nil {
 		return err
 	}
@@ -79,11 +88,8 @@ func (h *Handler) ListCelebrities(w http.ResponseWriter, r *http.Request) error
 	return nil
 }
 
-// Protected: promote a user to celebrity set (admin only via X-Admin-Token)
+// Protected: promote a user to celebrity set (admin role)
 func (h *Handler) PromoteCelebrity(w http.ResponseWriter, r *http.Request) error {
-	if os.Getenv("ADMIN_TOKEN") == "" || r.Header.Get("X-Admin-Token") != os.Getenv("ADMIN_TOKEN") {
-		return httpx.ErrUnauthorized
-	}
 	uid := r.PathValue("user_id")
 	if uid == "" {
 		return httpx.ErrUnauthorized
@@ -95,11 +101,8 @@ func (h *Handler) PromoteCelebrity(w http.ResponseWriter, r *http.Request) error
 	return nil
 }
 
-// Protected: demote a user from celebrity set (admin only via X-Admin-Token)
+// Protected: demote a user from celebrity set (admin role)
 func (h *Handler) DemoteCelebrity(w http.ResponseWriter, r *http.Request) error {
-	if os.Getenv("ADMIN_TOKEN") == "" || r.Header.Get("X-Admin-Token") != os.Getenv("ADMIN_TOKEN") {
-		return httpx.ErrUnauthorized
-	}
 	uid := r.PathValue("user_id")
 	if uid == "" {
 		return httpx.ErrUnauthorized
@@ -18,15 +18,23 @@ const (
 	keyUsersFeedFmt   = "users_feed:%s"
 	keyCelebFeedFmt   = "celebrities_feed:%s"
 	keyCelebSet       = "celebrities:set"
+	keyDeletedPostFmt = "deleted_post:%d"
 	maxPerAuthor      = 500
 	maxHomeSize       = 1000
+	homeFeedTTL       = 24 * time.Hour
 )
 
 type Repository interface {
 	HandlePostEvent(ctx context.Context, ev PostEvent) error
+	// HandlePostsDeleted takes the posts out of their author's lists. Home
+	// feeds hide them until they are rebuilt without them.
+	HandlePostsDeleted(ctx context.Context, ev PostsDeleted) error
 	GetAuthorFeed(ctx context.Context, authorID string, limit, offset int) ([]FeedEntry, error)
 	StoreHomeFeed(ctx context.Context, userID string, entries []FeedEntry) error
 	GetHomeFeed(ctx context.Context, userID string, limit, offset int) ([]FeedEntry, error)
+	// GetHomeFeedAfter returns up to limit entries ranked below after, or
+	// the top ones when it is nil.
+	GetHomeFeedAfter(ctx context.Context, userID string, after *position, limit int) ([]FeedEntry, error)
 
 	// Celebrities
 	AddCelebrity(ctx context.Context, userID string) error
@@ -34,6 +42,9 @@ type Repository interface {
 	IsCelebrity(ctx context.Context, userID string) (bool, error)
 	ListCelebrities(ctx context.Context) ([]string, error)
 	GetCelebrityFeed(ctx context.Context, userID string, limit, offset int) ([]FeedEntry, error)
+
+	// Forget drops every list kept for the user and their celebrity status.
+	Forget(ctx context.Context, userID string) error
 }
 
 type repo struct {
@@ -110,7 +121,7 @@ func (r *repo) GetAuthorFeed(ctx context.Context, authorID string, limit, offset
 }
 
 func (r *repo) StoreHomeFeed(ctx context.Context, userID string, entries []FeedEntry) error {
-	sort.Slice(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
+	sort.Slice(entries, func(i, j int) bool { return ranksBefore(entries[i], entries[j]) })
 	if len(entries) > maxHomeSize {
 		entries = entries[:maxHomeSize]
 	}
@@ -121,7 +132,7 @@ func (r *repo) StoreHomeFeed(ctx context.Context, userID string, entries []FeedE
 		b, _ := json.Marshal(e)
 		pipe.RPush(ctx, key, b)
 	}
-	pipe.Expire(ctx, key, 24*time.Hour)
+	pipe.Expire(ctx, key, homeFeedTTL)
 	_, err := pipe.Exec(ctx)
 	return err
 }
@@ -139,6 +150,81 @@ func (r *repo) GetHomeFeed(ctx context.Context, userID string, limit, offset int
 			out = append(out, e)
 		}
 	}
+	return r.dropDeleted(ctx, out)
+}
+
+// GetHomeFeedAfter compares by score rather than index, so a feed rebuilt
+// between two pages neither repeats nor skips entries. The list is at most
+// maxHomeSize long, so finding the start reads it whole.
+func (r *repo) GetHomeFeedAfter(ctx context.Context, userID string, after *position, limit int) ([]FeedEntry, error) {
+	if after == nil {
+		return r.GetHomeFeed(ctx, userID, limit, 0)
+	}
+	raws, err := r.rdb.LRange(ctx, r.userFeedKey(userID), 0, -1).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	last := FeedEntry{Score: after.Score, PostID: after.PostID}
+	var rest []FeedEntry
+	for _, s := range raws {
+		var e FeedEntry
+		if json.Unmarshal([]byte(s), &e) == nil && ranksBefore(last, e) {
+			rest = append(rest, e)
+		}
+	}
+	out, err := r.dropDeleted(ctx, rest)
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, err
+}
+
+func (r *repo) HandlePostsDeleted(ctx context.Context, ev PostsDeleted) error {
+	gone := make(map[int64]bool, len(ev.PostIDs))
+	pipe := r.rdb.Pipeline()
+	for _, id := range ev.PostIDs {
+		gone[id] = true
+		pipe.Set(ctx, fmt.Sprintf(keyDeletedPostFmt, id), 1, homeFeedTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	for _, key := range []string{r.authorKey(ev.UserID), r.celebFeedKey(ev.UserID)} {
+		raws, err := r.rdb.LRange(ctx, key, 0, -1).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		for _, s := range raws {
+			var e FeedEntry
+			if json.Unmarshal([]byte(s), &e) == nil && gone[e.PostID] {
+				if err := r.rdb.LRem(ctx, key, 0, s).Err(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dropDeleted leaves out entries of posts deleted since the feed was built.
+func (r *repo) dropDeleted(ctx context.Context, entries []FeedEntry) ([]FeedEntry, error) {
+	if len(entries) == 0 {
+		return entries, nil
+	}
+	pipe := r.rdb.Pipeline()
+	found := make([]*redis.IntCmd, len(entries))
+	for i, e := range entries {
+		found[i] = pipe.Exists(ctx, fmt.Sprintf(keyDeletedPostFmt, e.PostID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	out := entries[:0]
+	for i, e := range entries {
+		if found[i].Val() == 0 {
+			out = append(out, e)
+		}
+	}
 	return out, nil
 }
 
@@ -175,3 +261,11 @@ func (r *repo) GetCelebrityFeed(ctx context.Context, userID string, limit, offse
 	}
 	return out, nil
 }
+
+func (r *repo) Forget(ctx context.Context, userID string) error {
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(ctx, r.authorKey(userID), r.userFeedKey(userID), r.celebFeedKey(userID))
+	pipe.SRem(ctx, keyCelebSet, userID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
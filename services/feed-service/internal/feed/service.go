@@ -9,18 +9,30 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"feed-service/internal/block"
+	"feed-service/internal/shared/cursor"
 )
 
 type Service interface {
-	GetAuthorFeed(ctx context.Context, authorID string, limit, offset int) ([]FeedEntry, error)
+	// GetAuthorFeed and GetCelebrityFeed return nothing when viewerID (may be
+	// empty) and the author have blocked each other.
+	GetAuthorFeed(ctx context.Context, viewerID, authorID string, limit, offset int) ([]FeedEntry, error)
 	GetHomeFeed(ctx context.Context, userID string, limit, offset int) ([]FeedEntry, error)
+	// PageHomeFeed is GetHomeFeed paged by cursor; after is the NextCursor of
+	// the previous page.
+	PageHomeFeed(ctx context.Context, userID, after string, limit int) (*Page, error)
 	RebuildHomeFeed(ctx context.Context, userID, bearer string, limit int) error
 
 	// Celebrities
-	GetCelebrityFeed(ctx context.Context, userID string, limit, offset int) ([]FeedEntry, error)
+	GetCelebrityFeed(ctx context.Context, viewerID, userID string, limit, offset int) ([]FeedEntry, error)
 	PromoteCelebrity(ctx context.Context, userID string) error
 	DemoteCelebrity(ctx context.Context, userID string) error
 	ListCelebrities(ctx context.Context) ([]string, error)
+
+	// EraseUser serves account deletions. The user's posts are hidden from
+	// other home feeds once post-service announces them on posts.deleted.
+	EraseUser(ctx context.Context, uid string) error
 }
 
 type service struct {
@@ -29,6 +41,7 @@ type service struct {
 	postSvcBase      string
 	defaultFeedLimit int
 	httpClient       *http.Client
+	blocks           *block.Client
 }
 
 type Option func(*service)
@@ -44,6 +57,10 @@ func WithPostServiceBase(base string) Option {
 	return func(s *service) { s.postSvcBase = base }
 }
 
+func WithBlockClient(c *block.Client) Option {
+	return func(s *service) { s.blocks = c }
+}
+
 func NewService(r Repository, opts ...Option) Service {
 	s := &service{
 		repo:             r,
@@ -64,12 +81,66 @@ func envOr(k, d string) string {
 	return d
 }
 
-func (s *service) GetAuthorFeed(ctx context.Context, authorID string, limit, offset int) ([]FeedEntry, error) {
+func (s *service) blockedWith(ctx context.Context, uid string) map[string]struct{} {
+	if s.blocks == nil {
+		return nil
+	}
+	return s.blocks.Set(ctx, uid)
+}
+
+func (s *service) GetAuthorFeed(ctx context.Context, viewerID, authorID string, limit, offset int) ([]FeedEntry, error) {
+	if _, hidden := s.blockedWith(ctx, viewerID)[authorID]; hidden {
+		return []FeedEntry{}, nil
+	}
 	return s.repo.GetAuthorFeed(ctx, authorID, limit, offset)
 }
 
+// GetHomeFeed drops entries of authors blocked with the user. Stored feeds are
+// not rewritten on block; filtering on read keeps them correct either way.
 func (s *service) GetHomeFeed(ctx context.Context, userID string, limit, offset int) ([]FeedEntry, error) {
-	return s.repo.GetHomeFeed(ctx, userID, limit, offset)
+	items, err := s.repo.GetHomeFeed(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return withoutAuthors(items, s.blockedWith(ctx, userID)), nil
+}
+
+func (s *service) PageHomeFeed(ctx context.Context, userID, after string, limit int) (*Page, error) {
+	if limit <= 0 || limit > maxHomeSize {
+		limit = 50
+	}
+	var from *position
+	var pos position
+	if ok, err := cursor.Decode(after, &pos); err != nil {
+		return nil, err
+	} else if ok {
+		from = &pos
+	}
+	items, err := s.repo.GetHomeFeedAfter(ctx, userID, from, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Limit: limit}
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		page.NextCursor = cursor.Encode(position{Score: last.Score, PostID: last.PostID})
+	}
+	page.Items = withoutAuthors(items, s.blockedWith(ctx, userID))
+	return page, nil
+}
+
+func withoutAuthors(items []FeedEntry, hidden map[string]struct{}) []FeedEntry {
+	if len(hidden) == 0 {
+		return items
+	}
+	out := items[:0]
+	for _, e := range items {
+		if _, ok := hidden[e.AuthorID]; !ok {
+			out = append(out, e)
+		}
+	}
+	return out
 }
 
 func (s *service) RebuildHomeFeed(ctx context.Context, userID, bearer string, limit int) error {
@@ -107,7 +178,11 @@ func (s *service) RebuildHomeFeed(ctx context.Context, userID, bearer string, li
 	if perAuthor > 100 {
 		perAuthor = 100
 	}
+	hidden := s.blockedWith(ctx, userID)
 	for _, authorID := range rel.Items {
+		if _, ok := hidden[authorID]; ok {
+			continue
+		}
 		ents, e := s.repo.GetAuthorFeed(ctx2, authorID, perAuthor, 0)
 		if e == nil && len(ents) > 0 {
 			all = append(all, ents...)
@@ -121,7 +196,7 @@ func (s *service) RebuildHomeFeed(ctx context.Context, userID, bearer string, li
 		}
 	}
 
-	sort.Slice(all, func(i, j int) bool { return all[i].Score > all[j].Score })
+	sort.Slice(all, func(i, j int) bool { return ranksBefore(all[i], all[j]) })
 	if len(all) > limit {
 		all = all[:limit]
 	}
@@ -170,7 +245,10 @@ func (s *service) fetchAuthorRecentPosts(ctx context.Context, authorID string, l
 
 // ---- Celebrities ----
 
-func (s *service) GetCelebrityFeed(ctx context.Context, userID string, limit, offset int) ([]FeedEntry, error) {
+func (s *service) GetCelebrityFeed(ctx context.Context, viewerID, userID string, limit, offset int) ([]FeedEntry, error) {
+	if _, hidden := s.blockedWith(ctx, viewerID)[userID]; hidden {
+		return []FeedEntry{}, nil
+	}
 	return s.repo.GetCelebrityFeed(ctx, userID, limit, offset)
 }
 
@@ -185,3 +263,5 @@ func (s *service) DemoteCelebrity(ctx context.Context, userID string) error {
 func (s *service) ListCelebrities(ctx context.Context) ([]string, error) {
 	return s.repo.ListCelebrities(ctx)
 }
+
+func (s *service) EraseUser(ctx context.Context, uid string) error { return s.repo.Forget(ctx, uid) }
@@ -13,6 +13,12 @@ type PostEvent struct {
 	Views       int64     `json:"views,omitempty"`
 }
 
+// PostsDeleted is the payload of posts.deleted.
+type PostsDeleted struct {
+	UserID  string  `json:"user_id"`
+	PostIDs []int64 `json:"post_ids"`
+}
+
 type postListResp struct {
 	Items []struct {
 		ID          int64     `json:"id"`
@@ -32,3 +38,26 @@ type FeedEntry struct {
 	CreatedAt time.Time `json:"created_at"`
 	Score     float64   `json:"score"`
 }
+
+// ranksBefore orders feed entries: highest score first, ties by newest post.
+func ranksBefore(a, b FeedEntry) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.PostID > b.PostID
+}
+
+// Page is one page of a home feed. NextCursor is empty on the last page.
+// Entries of blocked authors still move the cursor, so a page can hold fewer
+// than Limit items while more follow.
+type Page struct {
+	Items      []FeedEntry `json:"items"`
+	Limit      int         `json:"limit"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// position is the last entry of a page; the next page starts right after it.
+type position struct {
+	Score  float64 `json:"s"`
+	PostID int64   `json:"p"`
+}
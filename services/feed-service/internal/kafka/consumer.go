@@ -14,6 +14,8 @@ import (
 
 type PostHandler func(ctx context.Context, ev feed.PostEvent) error
 
+type DeletedHandler func(ctx context.Context, ev feed.PostsDeleted) error
+
 func StartConsumer(ctx context.Context, bootstrap, topic, groupID string, handle PostHandler) error {
 	r := kf.NewReader(kf.ReaderConfig{
 		Brokers:  strings.Split(bootstrap, ","),
@@ -42,3 +44,31 @@ func StartConsumer(ctx context.Context, bootstrap, topic, groupID string, handle
 		}
 	}
 }
+
+// StartDeletedConsumer is StartConsumer for posts.deleted.
+func StartDeletedConsumer(ctx context.Context, bootstrap, topic, groupID string, handle DeletedHandler) error {
+	r := kf.NewReader(kf.ReaderConfig{
+		Brokers: strings.Split(bootstrap, ","),
+		GroupID: groupID,
+		Topic:   topic,
+		MaxWait: 2 * time.Second,
+	})
+	defer r.Close()
+
+	log.Printf("kafka consumer started group=%s topic=%s", groupID, topic)
+
+	for {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		var ev feed.PostsDeleted
+		if err := json.Unmarshal(m.Value, &ev); err != nil {
+			log.Printf("kafka: bad payload: %v", err)
+			continue
+		}
+		if err := handle(ctx, ev); err != nil {
+			log.Printf("handle posts deleted: %v", err)
+		}
+	}
+}
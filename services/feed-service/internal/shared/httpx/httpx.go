@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"feed-service/internal/shared/jwt"
 )
@@ -22,6 +24,7 @@ type APIError struct {
 var (
 	ctxUserIDKey    = "httpx.user_id"
 	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
 )
 
 func WriteJSON(w http.ResponseWriter, v any, code int) {
@@ -43,6 +46,8 @@ func Wrap(fn HandlerFunc) http.Handler {
 			code := http.StatusBadRequest
 			if errors.Is(err, ErrUnauthorized) {
 				code = http.StatusUnauthorized
+			} else if errors.Is(err, ErrForbidden) {
+				code = http.StatusForbidden
 			}
 			WriteError(w, code, err, "")
 		}
@@ -67,6 +72,17 @@ func BearerToken(r *http.Request) string {
 	return ""
 }
 
+// RevocationChecker reports whether an access token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti, sid, uid string, iat time.Time) (bool, error)
+}
+
+var revocations RevocationChecker
+
+// UseRevocations makes AuthMiddleware consult rc for every request.
+// The check fails open: if rc errors, the token is accepted and the error logged.
+func UseRevocations(rc RevocationChecker) { revocations = rc }
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tok := BearerToken(r)
@@ -74,16 +90,50 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "missing_bearer")
 			return
 		}
-		uid, err := jwt.Parse(tok)
-		if err != nil || uid == "" {
+		c, err := jwt.ParseClaims(tok)
+		if err != nil || c.UserID == "" {
 			WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "invalid_token")
 			return
 		}
-		ctx := context.WithValue(r.Context(), ctxUserIDKey, uid)
+		if revocations != nil {
+			revoked, err := revocations.IsRevoked(r.Context(), c.ID, c.SessionID, c.UserID, c.IssuedAt)
+			if err != nil {
+				log.Printf("revocation check: %v", err)
+			} else if revoked {
+				WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "token_revoked")
+				return
+			}
+		}
+		ctx := context.WithValue(r.Context(), ctxUserIDKey, c.UserID)
+		ctx = context.WithValue(ctx, ctxRoleKey, c.Role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// OptionalAuth attaches the caller's user id when a valid bearer token is
+// sent, and otherwise lets the request through anonymously.
+func OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header.Get("Authorization")
+		if !strings.HasPrefix(h, "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		c, err := jwt.ParseClaims(strings.TrimSpace(h[7:]))
+		if err != nil || c.UserID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if revocations != nil {
+			if revoked, err := revocations.IsRevoked(r.Context(), c.ID, c.SessionID, c.UserID, c.IssuedAt); err == nil && revoked {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxUserIDKey, c.UserID)))
+	})
+}
+
 func UserFromCtx(r *http.Request) (string, error) {
 	uid, _ := r.Context().Value(ctxUserIDKey).(string)
 	if uid == "" {
@@ -0,0 +1,60 @@
+package revoke
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// List is a read-only view of the access token denylist maintained by
+// user-service (see its internal/shared/revoke package for the key layout).
+type List struct{ r *redis.Client }
+
+func OpenFromEnv() *List {
+	addr := os.Getenv("REVOKE_REDIS_ADDR")
+	if addr == "" {
+		addr = "redis-auth:6379"
+	}
+	return &List{r: redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 500 * time.Millisecond,
+	})}
+}
+
+func (l *List) IsRevoked(ctx context.Context, jti, sid, uid string, iat time.Time) (bool, error) {
+	pipe := l.r.Pipeline()
+	var byJTI *redis.IntCmd
+	if jti != "" {
+		byJTI = pipe.Exists(ctx, "revoked:jti:"+jti)
+	}
+	var bySID *redis.IntCmd
+	if sid != "" {
+		bySID = pipe.Exists(ctx, "revoked:sid:"+sid)
+	}
+	byUser := pipe.Get(ctx, "revoked:user:"+uid)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return false, err
+	}
+	if byJTI != nil && byJTI.Val() > 0 {
+		return true, nil
+	}
+	if bySID != nil && bySID.Val() > 0 {
+		return true, nil
+	}
+	if s := byUser.Val(); s != "" {
+		cutoff, _ := strconv.ParseInt(s, 10, 64)
+		// iat has whole seconds, so a token from the cutoff's own second may
+		// predate it and is rejected too.
+		if !iat.After(time.Unix(cutoff, 0)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (l *List) Close() error { return l.r.Close() }
@@ -3,11 +3,15 @@ package main
 
 import (
 	"context"
+	"feedback-gateway/internal/account"
+	"feedback-gateway/internal/block"
 	"feedback-gateway/internal/comment"
 	"feedback-gateway/internal/like"
 	"feedback-gateway/internal/migrate"
+	"feedback-gateway/internal/post"
 	"feedback-gateway/internal/shared/db"
 	"feedback-gateway/internal/shared/httpx"
+	"feedback-gateway/internal/shared/revoke"
 	"log"
 	"net"
 	"net/http"
@@ -60,6 +64,14 @@ func initOTEL(ctx context.Context) func(context.Context) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		store := db.OpenFromEnv()
+		if err := migrate.Command(os.Stdout, os.Args[2:], migrate.Targets(store)); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 	shutdown := initOTEL(ctx)
 	defer func() {
@@ -84,16 +96,28 @@ func main() {
 	}
 
 	if os.Getenv("AUTO_MIGRATE") == "true" {
-		if err := migrate.AutoMigrateAll(store); err != nil {
+		if err := migrate.Up(store.DB); err != nil {
 			log.Fatalf("migrate: %v", err)
 		}
 	}
 
+	posts := post.NewClient(os.Getenv("POST_SERVICE_URL"))
+	blocks := block.NewClient(os.Getenv("USER_SERVICE_URL"))
+	go func() {
+		if err := blocks.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), "feedback-service"); err != nil {
+			log.Printf("block watcher stopped: %v", err)
+		}
+	}()
+
 	likeRepo := like.NewRepository(store, rdb)
-	likeSvc := like.NewService(likeRepo)
+	likeSvc := like.NewService(likeRepo, posts, blocks)
 
 	commentRepo := comment.NewRepository(store, rdb)
-	commentSvc := comment.NewService(commentRepo)
+	commentSvc := comment.NewService(commentRepo, posts, blocks)
+
+	revoked := revoke.OpenFromEnv()
+	defer revoked.Close()
+	httpx.UseRevocations(revoked)
 
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
@@ -105,6 +129,25 @@ func main() {
 	ch.WithLikeService(likeSvc)
 	mux.Handle("GET /posts/{post_id}/comments", httpx.Wrap(ch.ListByPost))
 	mux.Handle("GET /posts/{post_id}/counts", httpx.Wrap(ch.GetCounts))
+	mux.Handle("GET /internal/comments/{comment_id}/owner", httpx.InternalOnly(httpx.Wrap(ch.Owner)))
+	mux.Handle("DELETE /internal/comments/{comment_id}", httpx.InternalOnly(httpx.Wrap(ch.Remove)))
+
+	go func() {
+		if err := post.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), likeSvc, commentSvc); err != nil {
+			log.Printf("post deletion watcher stopped: %v", err)
+		}
+	}()
+
+	acc := account.New(account.NewReporter(os.Getenv("USER_SERVICE_URL")),
+		account.Part{Name: "comments", Eraser: commentSvc},
+		account.Part{Name: "likes", Eraser: likeSvc},
+	)
+	go func() {
+		if err := acc.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS")); err != nil {
+			log.Printf("account watcher stopped: %v", err)
+		}
+	}()
+	mux.Handle("GET /internal/users/{user_id}/export", httpx.InternalOnly(http.HandlerFunc(account.NewHandler(acc).Export)))
 
 	protect := func(pattern string, h http.Handler) {
 		mux.Handle(pattern, httpx.AuthMiddleware(h))
@@ -0,0 +1,70 @@
+// Package account runs this service's part of account exports and deletions
+// coordinated by user-service. Deletions arrive as users.deleted events; the
+// outcome is reported back to user-service, which tracks each request.
+package account
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	Service = "feedback-service"
+	Topic   = "users.deleted"
+
+	StateDone   = "done"
+	StateFailed = "failed"
+)
+
+// Eraser removes or anonymizes what a part of the service holds about a
+// user. EraseUser must be safe to run again for the same user.
+type Eraser interface {
+	EraseUser(ctx context.Context, uid string) error
+}
+
+// Exporter returns what a part of the service holds about a user, ready to
+// encode as JSON.
+type Exporter interface {
+	ExportUser(ctx context.Context, uid string) (any, error)
+}
+
+// Part is one kind of data the service keeps about users, named as it
+// appears in exports.
+type Part struct {
+	Name   string
+	Eraser Eraser
+}
+
+type Account struct {
+	parts    []Part
+	reporter *Reporter
+}
+
+func New(r *Reporter, parts ...Part) *Account { return &Account{parts: parts, reporter: r} }
+
+// Erase runs every part's eraser in order and stops at the first failure.
+func (a *Account) Erase(ctx context.Context, uid string) error {
+	for _, p := range a.parts {
+		if err := p.Eraser.EraseUser(ctx, uid); err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// Export collects every part that can be exported, keyed by part name.
+func (a *Account) Export(ctx context.Context, uid string) (map[string]any, error) {
+	out := make(map[string]any)
+	for _, p := range a.parts {
+		ex, ok := p.Eraser.(Exporter)
+		if !ok {
+			continue
+		}
+		v, err := ex.ExportUser(ctx, uid)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name, err)
+		}
+		out[p.Name] = v
+	}
+	return out, nil
+}
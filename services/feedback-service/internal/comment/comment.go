@@ -17,6 +17,19 @@ type PostComment struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Page is one page of a post's comments. NextCursor is empty on the last page.
+type Page struct {
+	Items      []PostComment `json:"items"`
+	Limit      int           `json:"limit"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// position is the last comment of a page; the next page starts right after it.
+type position struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint64    `json:"id"`
+}
+
 type CreateReq struct {
 	Text    string  `json:"text" validate:"required"`
 	ReplyID *uint64 `json:"reply_id"`
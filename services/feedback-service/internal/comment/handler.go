@@ -53,14 +53,22 @@ func (h *Handler) DeleteMine(w http.ResponseWriter, r *http.Request) error {
 func (h *Handler) ListByPost(w http.ResponseWriter, r *http.Request) error {
 	pid, _ := strconv.ParseUint(r.PathValue("post_id"), 10, 64)
 	limit := httpx.QueryInt(r, "limit", 50)
-	offset := httpx.QueryInt(r, "offset", 0)
-	items, err := h.svc.ListByPost(pid, limit, offset)
+	// ?offset= is kept for older clients; otherwise page by ?cursor=.
+	if offset := httpx.QueryInt(r, "offset", 0); offset > 0 {
+		items, err := h.svc.ListByPost(pid, limit, offset)
+		if err != nil {
+			return err
+		}
+		httpx.WriteJSON(w, map[string]any{
+			"items": items, "limit": limit, "offset": offset,
+		}, http.StatusOK)
+		return nil
+	}
+	page, err := h.svc.PageByPost(pid, r.URL.Query().Get("cursor"), limit)
 	if err != nil {
 		return err
 	}
-	httpx.WriteJSON(w, map[string]any{
-		"items": items, "limit": limit, "offset": offset,
-	}, http.StatusOK)
+	httpx.WriteJSON(w, page, http.StatusOK)
 	return nil
 }
 
@@ -80,3 +88,29 @@ func (h *Handler) GetCounts(w http.ResponseWriter, r *http.Request) error {
 	httpx.WriteJSON(w, map[string]any{"post_id": pid, "likes": lCount, "comments": cCount}, http.StatusOK)
 	return nil
 }
+
+// Owner tells moderation who wrote a comment.
+func (h *Handler) Owner(w http.ResponseWriter, r *http.Request) error {
+	cid, err := strconv.ParseUint(r.PathValue("comment_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	c, err := h.svc.Get(cid)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"user_id": c.UserID}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) error {
+	cid, err := strconv.ParseUint(r.PathValue("comment_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Remove(cid); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
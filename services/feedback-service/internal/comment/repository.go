@@ -13,9 +13,19 @@ import (
 type Repository interface {
 	Create(uid string, postID uint64, in CreateReq) (*PostComment, error)
 	DeleteMine(uid string, commentID uint64) error
+	Delete(commentID uint64) error
+	Get(commentID uint64) (*PostComment, error)
 	ListByPost(postID uint64, limit, offset int) ([]PostComment, error)
+	// ListByPostAfter returns up to limit comments older than after, or the
+	// newest when it is nil.
+	ListByPostAfter(postID uint64, after *position, limit int) ([]PostComment, error)
 	Counts(postID uint64) (likes int64, comments int64, err error)
 	IncSum(postID uint64, delta int) error
+	ListByUser(uid string) ([]PostComment, error)
+	// Remove deletes c and takes it off its post's count.
+	Remove(c *PostComment) error
+	// PurgePosts drops every comment on the posts and their counts.
+	PurgePosts(ids []uint64) error
 }
 
 type repo struct {
@@ -43,12 +53,57 @@ func (r *repo) DeleteMine(uid string, commentID uint64) error {
 	if err := r.db.First(&c, "id = ? AND user_id = ?", commentID, uid).Error; err != nil {
 		return err
 	}
-	if err := r.db.Delete(&PostComment{}, "id = ?", commentID).Error; err != nil {
+	return r.remove(&c)
+}
+
+func (r *repo) Get(commentID uint64) (*PostComment, error) {
+	var c PostComment
+	if err := r.db.First(&c, "id = ?", commentID).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *repo) Delete(commentID uint64) error {
+	var c PostComment
+	if err := r.db.First(&c, "id = ?", commentID).Error; err != nil {
+		return err
+	}
+	return r.remove(&c)
+}
+
+func (r *repo) remove(c *PostComment) error {
+	if err := r.db.Delete(&PostComment{}, "id = ?", c.ID).Error; err != nil {
 		return err
 	}
 	return r.IncSum(c.PostID, -1)
 }
 
+func (r *repo) Remove(c *PostComment) error { return r.remove(c) }
+
+func (r *repo) PurgePosts(ids []uint64) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id IN ?", ids).Delete(&PostComment{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("post_id IN ?", ids).Delete(&PostCommentsSum{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = ckey(id)
+	}
+	return r.rdb.Del(context.Background(), keys...).Err()
+}
+
+func (r *repo) ListByUser(uid string) ([]PostComment, error) {
+	var out []PostComment
+	err := r.db.Where("user_id = ?", uid).Order("created_at, id").Find(&out).Error
+	return out, err
+}
+
 func (r *repo) IncSum(postID uint64, delta int) error {
 	ctx := context.Background()
 
@@ -86,11 +141,21 @@ func (r *repo) IncSum(postID uint64, delta int) error {
 func (r *repo) ListByPost(postID uint64, limit, offset int) ([]PostComment, error) {
 	var out []PostComment
 	err := r.db.Where("post_id = ?", postID).
-		Order("created_at DESC").Limit(limit).Offset(offset).
+		Order("created_at DESC, id DESC").Limit(limit).Offset(offset).
 		Find(&out).Error
 	return out, err
 }
 
+func (r *repo) ListByPostAfter(postID uint64, after *position, limit int) ([]PostComment, error) {
+	q := r.db.Where("post_id = ?", postID)
+	if after != nil {
+		q = q.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+	var out []PostComment
+	err := q.Order("created_at DESC, id DESC").Limit(limit).Find(&out).Error
+	return out, err
+}
+
 func (r *repo) Counts(postID uint64) (int64, int64, error) {
 	var cs PostCommentsSum
 	var comments int64
@@ -1,26 +1,101 @@
 package comment
 
+import (
+	"context"
+
+	"feedback-gateway/internal/block"
+	"feedback-gateway/internal/post"
+	"feedback-gateway/internal/shared/cursor"
+)
+
 type Service interface {
 	Create(uid string, postID uint64, in CreateReq) (*PostComment, error)
 	DeleteMine(uid string, commentID uint64) error
+	Get(commentID uint64) (*PostComment, error)
+	// Remove deletes any comment; used by moderation.
+	Remove(commentID uint64) error
 	ListByPost(postID uint64, limit, offset int) ([]PostComment, error)
+	// PageByPost pages through a post's comments by cursor; after is the
+	// NextCursor of the previous page.
+	PageByPost(postID uint64, after string, limit int) (*Page, error)
 	CommentCount(postID uint64) (int64, error)
+	// ExportUser and EraseUser serve account exports and deletions.
+	ExportUser(ctx context.Context, uid string) (any, error)
+	EraseUser(ctx context.Context, uid string) error
+	// PurgePosts serves post deletions; see post.Watch.
+	PurgePosts(ctx context.Context, ids []uint64) error
 }
 
-type service struct{ repo Repository }
+type service struct {
+	repo   Repository
+	posts  *post.Client
+	blocks *block.Client
+}
 
-func NewService(r Repository) Service { return &service{repo: r} }
+func NewService(r Repository, posts *post.Client, blocks *block.Client) Service {
+	return &service{repo: r, posts: posts, blocks: blocks}
+}
 
 func (s *service) Create(uid string, postID uint64, in CreateReq) (*PostComment, error) {
+	ctx := context.Background()
+	if owner, err := s.posts.OwnerOf(ctx, postID); err == nil && s.blocks.IsBlocked(ctx, uid, owner) {
+		return nil, block.ErrBlocked
+	}
 	return s.repo.Create(uid, postID, in)
 }
 func (s *service) DeleteMine(uid string, commentID uint64) error {
 	return s.repo.DeleteMine(uid, commentID)
 }
+func (s *service) Get(commentID uint64) (*PostComment, error) { return s.repo.Get(commentID) }
+func (s *service) Remove(commentID uint64) error              { return s.repo.Delete(commentID) }
 func (s *service) ListByPost(postID uint64, limit, offset int) ([]PostComment, error) {
 	return s.repo.ListByPost(postID, limit, offset)
 }
+func (s *service) PageByPost(postID uint64, after string, limit int) (*Page, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var from *position
+	var pos position
+	if ok, err := cursor.Decode(after, &pos); err != nil {
+		return nil, err
+	} else if ok {
+		from = &pos
+	}
+	items, err := s.repo.ListByPostAfter(postID, from, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Items: items, Limit: limit}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := items[limit-1]
+		page.NextCursor = cursor.Encode(position{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
 func (s *service) CommentCount(postID uint64) (int64, error) {
 	_, c, err := s.repo.Counts(postID)
 	return c, err
 }
+
+func (s *service) ExportUser(ctx context.Context, uid string) (any, error) {
+	return s.repo.ListByUser(uid)
+}
+
+// EraseUser removes the user's comments one by one so every post's count
+// stays right.
+func (s *service) EraseUser(ctx context.Context, uid string) error {
+	items, err := s.repo.ListByUser(uid)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if err := s.repo.Remove(&items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) PurgePosts(ctx context.Context, ids []uint64) error { return s.repo.PurgePosts(ids) }
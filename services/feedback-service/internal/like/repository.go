@@ -14,6 +14,9 @@ type Repository interface {
 	Like(uid string, postID uint64) (int64, error)
 	Unlike(uid string, postID uint64) (int64, error)
 	GetCount(postID uint64, forUID string) (int64, bool, error)
+	ListByUser(uid string) ([]PostLike, error)
+	// PurgePosts drops every like of the posts and their counts.
+	PurgePosts(ids []uint64) error
 }
 
 type repo struct {
@@ -91,3 +94,26 @@ func (r *repo) GetCount(postID uint64, forUID string) (int64, bool, error) {
 	}
 	return val, exists > 0, nil
 }
+
+func (r *repo) ListByUser(uid string) ([]PostLike, error) {
+	var out []PostLike
+	err := r.db.Where("user_id = ?", uid).Order("created_at").Find(&out).Error
+	return out, err
+}
+
+func (r *repo) PurgePosts(ids []uint64) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id IN ?", ids).Delete(&PostLike{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("post_id IN ?", ids).Delete(&PostLikesSum{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = likeKey(id)
+	}
+	return r.rdb.Del(context.Background(), keys...).Err()
+}
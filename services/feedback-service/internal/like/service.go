@@ -1,17 +1,61 @@
 package like
 
+import (
+	"context"
+
+	"feedback-gateway/internal/block"
+	"feedback-gateway/internal/post"
+)
+
 type Service interface {
 	Like(uid string, postID uint64) (int64, error)
 	Unlike(uid string, postID uint64) (int64, error)
 	Get(postID uint64, uid string) (int64, bool, error)
+	// ExportUser and EraseUser serve account exports and deletions.
+	ExportUser(ctx context.Context, uid string) (any, error)
+	EraseUser(ctx context.Context, uid string) error
+	// PurgePosts serves post deletions; see post.Watch.
+	PurgePosts(ctx context.Context, ids []uint64) error
 }
 
-type service struct{ repo Repository }
+type service struct {
+	repo   Repository
+	posts  *post.Client
+	blocks *block.Client
+}
 
-func NewService(r Repository) Service { return &service{repo: r} }
+func NewService(r Repository, posts *post.Client, blocks *block.Client) Service {
+	return &service{repo: r, posts: posts, blocks: blocks}
+}
 
-func (s *service) Like(uid string, postID uint64) (int64, error)   { return s.repo.Like(uid, postID) }
+func (s *service) Like(uid string, postID uint64) (int64, error) {
+	ctx := context.Background()
+	if owner, err := s.posts.OwnerOf(ctx, postID); err == nil && s.blocks.IsBlocked(ctx, uid, owner) {
+		return 0, block.ErrBlocked
+	}
+	return s.repo.Like(uid, postID)
+}
 func (s *service) Unlike(uid string, postID uint64) (int64, error) { return s.repo.Unlike(uid, postID) }
 func (s *service) Get(postID uint64, uid string) (int64, bool, error) {
 	return s.repo.GetCount(postID, uid)
 }
+
+func (s *service) ExportUser(ctx context.Context, uid string) (any, error) {
+	return s.repo.ListByUser(uid)
+}
+
+// EraseUser unlikes through the normal path so every post's count stays right.
+func (s *service) EraseUser(ctx context.Context, uid string) error {
+	items, err := s.repo.ListByUser(uid)
+	if err != nil {
+		return err
+	}
+	for _, l := range items {
+		if _, err := s.repo.Unlike(uid, l.PostID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) PurgePosts(ctx context.Context, ids []uint64) error { return s.repo.PurgePosts(ids) }
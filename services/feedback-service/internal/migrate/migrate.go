@@ -1,14 +1,9 @@
+// Package migrate keeps the service database's schema in step with the
+// numbered SQL files under sql/, recording applied versions in its
+// schema_migrations table.
 package migrate
 
-import (
-	"feedback-gateway/internal/comment"
-	"feedback-gateway/internal/like"
-	"feedback-gateway/internal/shared/db"
-)
+import "feedback-gateway/internal/shared/db"
 
-func AutoMigrateAll(store *db.Store) error {
-	return store.DB.AutoMigrate(
-		&like.PostLike{}, &like.PostLikesSum{},
-		&comment.PostComment{}, &comment.PostCommentsSum{},
-	)
-}
+// Targets is the service database as the only migration target.
+func Targets(store *db.Store) []Target { return []Target{{Name: "db", DB: store.DB}} }
@@ -0,0 +1,274 @@
+package migrate
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one numbered schema change, read from sql/NNNN_name.up.sql and
+// its optional sql/NNNN_name.down.sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State is a migration together with when it was applied, nil if pending.
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+//go:embed sql/*.sql
+var files embed.FS
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    bigint PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+// lockKey serialises migrations of one database across processes.
+const lockKey = "schema_migrations"
+
+// Migrations returns every known migration in version order.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		name := e.Name()
+		base, up := strings.CutSuffix(name, ".up.sql")
+		if !up {
+			var ok bool
+			if base, ok = strings.CutSuffix(name, ".down.sql"); !ok {
+				continue
+			}
+		}
+		num, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: want NNNN_name", name)
+		}
+		v, err := strconv.ParseInt(num, 10, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("migration %s: bad version", name)
+		}
+		body, err := fs.ReadFile(files, path.Join("sql", name))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[v]
+		if m == nil {
+			m = &Migration{Version: v, Name: label}
+			byVersion[v] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", v, m.Name, label)
+		}
+		if up {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Latest is the highest known version, 0 if there are none.
+func Latest() (int64, error) {
+	ms, err := Migrations()
+	if err != nil || len(ms) == 0 {
+		return 0, err
+	}
+	return ms[len(ms)-1].Version, nil
+}
+
+func applied(db *gorm.DB) (map[int64]time.Time, error) {
+	if err := db.Exec(createTable).Error; err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Version   int64
+		AppliedAt time.Time
+	}
+	if err := db.Raw("SELECT version, applied_at FROM schema_migrations").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r.AppliedAt
+	}
+	return out, nil
+}
+
+// Status lists every known migration and whether db has it.
+func Status(db *gorm.DB) ([]State, error) {
+	ms, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]State, len(ms))
+	for i, m := range ms {
+		out[i] = State{Migration: m}
+		if at, ok := done[m.Version]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// Current is the highest version applied to db.
+func Current(db *gorm.DB) (int64, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+	var v int64
+	for k := range done {
+		v = max(v, k)
+	}
+	return v, nil
+}
+
+// Up applies every pending migration. Versions newer than this build are
+// left alone, so an older instance can still start during a rollout.
+func Up(db *gorm.DB) error {
+	ms, err := Migrations()
+	if err != nil {
+		return err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		if _, ok := done[m.Version]; !ok {
+			if err := step(db, m, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Down rolls back the last n applied migrations.
+func Down(db *gorm.DB, n int) error {
+	ms, err := Migrations()
+	if err != nil {
+		return err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return err
+	}
+	for i := len(ms) - 1; i >= 0 && n > 0; i-- {
+		if _, ok := done[ms[i].Version]; !ok {
+			continue
+		}
+		if err := step(db, ms[i], false); err != nil {
+			return err
+		}
+		n--
+	}
+	return nil
+}
+
+// To applies or rolls back migrations until exactly those up to target are
+// applied.
+func To(db *gorm.DB, target int64) error {
+	ms, err := Migrations()
+	if err != nil {
+		return err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return err
+	}
+	known := make(map[int64]bool, len(ms))
+	for _, m := range ms {
+		known[m.Version] = true
+	}
+	for v := range done {
+		if !known[v] {
+			return fmt.Errorf("database has migration %d, which this build does not know", v)
+		}
+	}
+	if target != 0 && !known[target] {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+	for i := len(ms) - 1; i >= 0; i-- {
+		if _, ok := done[ms[i].Version]; ok && ms[i].Version > target {
+			if err := step(db, ms[i], false); err != nil {
+				return err
+			}
+		}
+	}
+	for _, m := range ms {
+		if _, ok := done[m.Version]; !ok && m.Version <= target {
+			if err := step(db, m, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// step runs one migration in its own transaction. Another process may have
+// run it meanwhile, which the lock and re-check turn into a no-op.
+func step(db *gorm.DB, m Migration, up bool) error {
+	dir := "up"
+	if !up {
+		dir = "down"
+	}
+	if !up && m.Down == "" {
+		return fmt.Errorf("migration %d_%s cannot be rolled back", m.Version, m.Name)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", lockKey).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Raw("SELECT count(*) FROM schema_migrations WHERE version = ?", m.Version).Scan(&n).Error; err != nil {
+			return err
+		}
+		if up == (n > 0) {
+			return nil
+		}
+		if up {
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name).Error
+		}
+		if err := tx.Exec(m.Down).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s: %w", m.Version, m.Name, dir, err)
+	}
+	return nil
+}
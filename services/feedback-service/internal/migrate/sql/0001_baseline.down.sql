@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS post_comments_sums;
+DROP TABLE IF EXISTS post_comments;
+DROP TABLE IF EXISTS post_likes_sums;
+DROP TABLE IF EXISTS post_likes;
@@ -0,0 +1,34 @@
+-- Schema as created by AutoMigrate before versioned migrations existed. Every
+-- statement is idempotent so existing databases only get recorded.
+
+CREATE TABLE IF NOT EXISTS post_likes (
+    post_id    bigint,
+    user_id    varchar(64),
+    created_at timestamptz,
+    PRIMARY KEY (post_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_post_likes_post_id ON post_likes (post_id);
+CREATE INDEX IF NOT EXISTS idx_post_likes_user_id ON post_likes (user_id);
+
+CREATE TABLE IF NOT EXISTS post_likes_sums (
+    post_id     bigserial PRIMARY KEY,
+    likes_count bigint,
+    updated_at  timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS post_comments (
+    id         bigserial PRIMARY KEY,
+    post_id    bigint,
+    user_id    varchar(64),
+    reply_id   bigint,
+    text       text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_post_comments_post_id ON post_comments (post_id);
+CREATE INDEX IF NOT EXISTS idx_post_comments_user_id ON post_comments (user_id);
+
+CREATE TABLE IF NOT EXISTS post_comments_sums (
+    post_id        bigserial PRIMARY KEY,
+    comments_count bigint,
+    updated_at     timestamptz
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_post_comments_post_created;
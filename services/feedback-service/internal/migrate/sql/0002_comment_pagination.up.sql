@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_post_comments_post_created ON post_comments (post_id, created_at DESC, id DESC);
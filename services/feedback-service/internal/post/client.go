@@ -0,0 +1,76 @@
+package post
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const DefaultTimeout = 3 * time.Second
+
+// Client looks up post authors in post-service. Authors never change, so
+// answers are cached for the life of the process (bounded by maxCached).
+type Client struct {
+	base string
+	hc   *http.Client
+
+	mu     sync.Mutex
+	owners map[uint64]string
+}
+
+const maxCached = 100000
+
+func NewClient(base string) *Client {
+	if base == "" {
+		base = getenv("POST_SERVICE_URL", "http://post-service:8082")
+	}
+	return &Client{
+		base:   base,
+		hc:     &http.Client{Timeout: DefaultTimeout},
+		owners: make(map[uint64]string),
+	}
+}
+
+func getenv(k, d string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return d
+}
+
+func (c *Client) OwnerOf(ctx context.Context, postID uint64) (string, error) {
+	c.mu.Lock()
+	owner, ok := c.owners[postID]
+	c.mu.Unlock()
+	if ok {
+		return owner, nil
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/posts/%d", c.base, postID), nil)
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("post-service status %d", resp.StatusCode)
+	}
+	var out struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	if len(c.owners) >= maxCached {
+		c.owners = make(map[uint64]string)
+	}
+	c.owners[postID] = out.UserID
+	c.mu.Unlock()
+	return out.UserID, nil
+}
@@ -0,0 +1,78 @@
+package post
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	kf "github.com/segmentio/kafka-go"
+)
+
+// DeletedTopic carries the ids of posts post-service has deleted.
+const DeletedTopic = "posts.deleted"
+
+// Purger drops what it keeps about deleted posts. PurgePosts must be safe to
+// run again for the same posts.
+type Purger interface {
+	PurgePosts(ctx context.Context, ids []uint64) error
+}
+
+// Watch purges the likes and comments of every post announced on
+// DeletedTopic. The offset is only committed once every purger has
+// succeeded, retrying until then.
+func Watch(ctx context.Context, brokers string, purgers ...Purger) error {
+	if strings.TrimSpace(brokers) == "" {
+		brokers = "kafka:9092"
+	}
+	r := kf.NewReader(kf.ReaderConfig{
+		Brokers:     strings.Split(brokers, ","),
+		GroupID:     "feedback-service-posts",
+		Topic:       DeletedTopic,
+		StartOffset: kf.FirstOffset,
+		MaxWait:     time.Second,
+	})
+	defer r.Close()
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		var ev struct {
+			PostIDs []uint64 `json:"post_ids"`
+		}
+		if err := json.Unmarshal(m.Value, &ev); err != nil {
+			log.Printf("%s: bad payload: %v", DeletedTopic, err)
+		} else if len(ev.PostIDs) > 0 {
+			if err := purge(ctx, ev.PostIDs, purgers); err != nil {
+				return err
+			}
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+	}
+}
+
+func purge(ctx context.Context, ids []uint64, purgers []Purger) error {
+	wait := time.Second
+	for _, p := range purgers {
+		for {
+			err := p.PurgePosts(ctx, ids)
+			if err == nil {
+				break
+			}
+			log.Printf("purge posts %v: %v", ids, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			if wait < time.Minute {
+				wait *= 2
+			}
+		}
+	}
+	return nil
+}
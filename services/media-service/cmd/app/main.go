@@ -7,8 +7,10 @@ import (
 	"os"
 	"time"
 
+	"media-service/internal/account"
 	"media-service/internal/media"
 	"media-service/internal/shared/httpx"
+	"media-service/internal/shared/revoke"
 	"media-service/internal/storage/s3"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -74,6 +76,10 @@ func main() {
 	svc := media.NewService(store)
 	h := media.NewHandler(svc)
 
+	revoked := revoke.OpenFromEnv()
+	defer revoked.Close()
+	httpx.UseRevocations(revoked)
+
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
@@ -81,15 +87,23 @@ func main() {
 		_, _ = w.Write([]byte("ok"))
 	})
 
-	mux.Handle("GET /media/{key}", otelhttp.NewHandler(http.HandlerFunc(h.RedirectToSignedGet), "media.get"))
+	mux.Handle("GET /media/{key...}", otelhttp.NewHandler(http.HandlerFunc(h.RedirectToSignedGet), "media.get"))
 
 	protected := func(pattern string, handler http.Handler) {
 		mux.Handle(pattern, httpx.AuthMiddleware(handler))
 	}
 	protected("POST /media/upload", otelhttp.NewHandler(http.HandlerFunc(h.Upload), "media.upload"))
-	protected("DELETE /media/{key}", otelhttp.NewHandler(http.HandlerFunc(h.Delete), "media.delete"))
+	protected("DELETE /media/{key...}", otelhttp.NewHandler(http.HandlerFunc(h.Delete), "media.delete"))
 	protected("POST /media/presign", otelhttp.NewHandler(http.HandlerFunc(h.PresignPut), "media.presign"))
 
+	acc := account.New(account.NewReporter(os.Getenv("USER_SERVICE_URL")), account.Part{Name: "uploads", Eraser: svc})
+	go func() {
+		if err := acc.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS")); err != nil {
+			log.Printf("account watcher stopped: %v", err)
+		}
+	}()
+	mux.Handle("GET /internal/users/{user_id}/export", httpx.InternalOnly(http.HandlerFunc(account.NewHandler(acc).Export)))
+
 	addr := envOr("APP_PORT", ":8088")
 	srv := &http.Server{
 		Addr:              addr,
@@ -0,0 +1,21 @@
+package account
+
+import (
+	"net/http"
+
+	"media-service/internal/shared/httpx"
+)
+
+type Handler struct{ acc *Account }
+
+func NewHandler(a *Account) *Handler { return &Handler{acc: a} }
+
+// Export serves GET /internal/users/{user_id}/export for user-service.
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	out, err := h.acc.Export(r.Context(), r.PathValue("user_id"))
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, err, "export failed")
+		return
+	}
+	httpx.WriteJSON(w, out, http.StatusOK)
+}
@@ -1,7 +1,9 @@
 package media
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"path"
 	"strings"
 	"time"
@@ -11,16 +13,90 @@ import (
 
 type Service struct {
 	s3 *s3.Storage
+	// legacy are the prefixes uploads used before keys started with the
+	// user id (MEDIA_LEGACY_PREFIXES, comma separated); "" is the bucket root.
+	legacy []string
 }
 
-func NewService(s *s3.Storage) *Service { return &Service{s3: s} }
+func NewService(s *s3.Storage) *Service {
+	legacy := []string{""}
+	for _, p := range strings.Split(os.Getenv("MEDIA_LEGACY_PREFIXES"), ",") {
+		if p = strings.Trim(strings.TrimSpace(p), "/"); p != "" {
+			legacy = append(legacy, p+"/")
+		}
+	}
+	return &Service{s3: s, legacy: legacy}
+}
 
+// BuildKey puts a user's uploads under {userID}/ so they can be listed by
+// prefix. Anonymous uploads keep the old layout.
 func (s *Service) BuildKey(prefix, filename string, userID string) string {
 	fn := path.Base(filename)
 	now := time.Now().UTC().Format("20060102T150405")
 	p := strings.Trim(prefix, "/")
+	if userID != "" {
+		return path.Join(userID, p, now+"_"+fn)
+	}
 	if p != "" {
 		return fmt.Sprintf("%s/%s_%s_%s", p, userID, now, fn)
 	}
 	return fmt.Sprintf("%s_%s_%s", userID, now, fn)
 }
+
+// exportLinkTTL is as long as S3 allows a presigned link to live.
+const exportLinkTTL = 7 * 24 * time.Hour
+
+// owned returns the keys BuildKey made for userID: those under {userID}/
+// and, from before that layout, {legacy prefix}{userID}_*.
+func (s *Service) owned(ctx context.Context, userID string) ([]string, error) {
+	var keys []string
+	add := func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}
+	if err := s.s3.List(ctx, userID+"/", add); err != nil {
+		return nil, err
+	}
+	for _, p := range s.legacy {
+		if err := s.s3.List(ctx, p+userID+"_", add); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// ExportUser lists the user's uploads with links to download them.
+func (s *Service) ExportUser(ctx context.Context, uid string) (any, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("missing user id")
+	}
+	keys, err := s.owned(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		u, err := s.s3.PresignGet(ctx, k, exportLinkTTL)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, map[string]string{"key": k, "url": u.String()})
+	}
+	return out, nil
+}
+
+func (s *Service) EraseUser(ctx context.Context, uid string) error {
+	if uid == "" {
+		return fmt.Errorf("missing user id")
+	}
+	keys, err := s.owned(ctx, uid)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := s.s3.Remove(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
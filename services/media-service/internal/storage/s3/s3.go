@@ -71,3 +71,17 @@ func (s *Storage) PresignGet(ctx context.Context, key string, ttl time.Duration)
 func (s *Storage) PresignPut(ctx context.Context, key string, ttl time.Duration, _ string) (*url.URL, error) {
 	return s.client.PresignedPutObject(ctx, s.cfg.Bucket, key, ttl)
 }
+
+// List calls fn with every key starting with prefix, stopping at the first
+// error.
+func (s *Storage) List(ctx context.Context, prefix string, fn func(key string) error) error {
+	for obj := range s.client.ListObjects(ctx, s.cfg.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
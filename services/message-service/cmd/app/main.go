@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"time"
 
+	"message-service/internal/account"
+	"message-service/internal/block"
 	"message-service/internal/chat"
 	"message-service/internal/idem"
 	"message-service/internal/kafka"
@@ -18,6 +20,7 @@ import (
 	"message-service/internal/redisx"
 	"message-service/internal/shared/db"
 	"message-service/internal/shared/httpx"
+	"message-service/internal/shared/revoke"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -71,6 +74,14 @@ func initOTEL(ctx context.Context) func(context.Context) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		store := db.OpenFromEnv()
+		if err := migrate.Command(os.Stdout, os.Args[2:], migrate.Targets(store)); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 	shutdown := initOTEL(ctx)
 	defer func() {
@@ -95,16 +106,27 @@ func main() {
 	mediaCli := media.New(os.Getenv("MEDIA_SERVICE_URL"))
 
 	if os.Getenv("AUTO_MIGRATE") == "true" {
-		if err := migrate.AutoMigrateAll(store); err != nil {
+		if err := migrate.Up(store.Base); err != nil {
 			log.Fatalf("migrate: %v", err)
 		}
 	}
 
+	blocks := block.NewClient(os.Getenv("USER_SERVICE_URL"))
+	go func() {
+		if err := blocks.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), "message-service"); err != nil {
+			log.Printf("block watcher stopped: %v", err)
+		}
+	}()
+
 	chatRepo := chat.NewRepository(store)
-	chatSvc := chat.NewService(chatRepo, rds)
+	chatSvc := chat.NewService(chatRepo, rds, blocks)
 
 	msgRepo := message.NewRepository(store)
-	msgSvc := message.NewService(msgRepo, chatSvc, rds, kWriter, mediaCli)
+	msgSvc := message.NewService(msgRepo, chatSvc, rds, kWriter, mediaCli, blocks)
+
+	revoked := revoke.OpenFromEnv()
+	defer revoked.Close()
+	httpx.UseRevocations(revoked)
 
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
@@ -136,6 +158,21 @@ func main() {
 	protect("POST /chats/{chat_id}/add/{user_id}", httpx.Wrap(ch.AddUser))
 	protect("POST /chats/{chat_id}/leave", httpx.Wrap(ch.Leave))
 	mux.Handle("GET /chats/popular", httpx.Wrap(ch.Popular))
+	mux.Handle("GET /internal/chats/{chat_id}/owner", httpx.InternalOnly(httpx.Wrap(ch.Owner)))
+	mux.Handle("DELETE /internal/chats/{chat_id}", httpx.InternalOnly(httpx.Wrap(ch.Remove)))
+	mux.Handle("GET /internal/messages/{message_id}/owner", httpx.InternalOnly(httpx.Wrap(mh.Owner)))
+	mux.Handle("DELETE /internal/messages/{message_id}", httpx.InternalOnly(httpx.Wrap(mh.Remove)))
+
+	acc := account.New(account.NewReporter(os.Getenv("USER_SERVICE_URL")),
+		account.Part{Name: "messages", Eraser: msgSvc},
+		account.Part{Name: "chats", Eraser: chatSvc},
+	)
+	go func() {
+		if err := acc.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS")); err != nil {
+			log.Printf("account watcher stopped: %v", err)
+		}
+	}()
+	mux.Handle("GET /internal/users/{user_id}/export", httpx.InternalOnly(http.HandlerFunc(account.NewHandler(acc).Export)))
 
 	protect("GET /chats/{chat_id}/messages", readLimit(httpx.Wrap(mh.ListByChat)))
 	protect("POST /messages", sendLimit(httpx.Wrap(mh.Send)))
@@ -0,0 +1,167 @@
+package block
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"message-service/internal/shared/httpx"
+
+	kf "github.com/segmentio/kafka-go"
+)
+
+const DefaultTimeout = 2 * time.Second
+
+var ErrBlocked = fmt.Errorf("%w: blocked", httpx.ErrForbidden)
+
+// maxCached bounds the per-process cache; it is simply reset when exceeded.
+const maxCached = 50000
+
+// Client answers "has either of A and B blocked the other" from user-service
+// block sets, cached per user for BLOCK_CACHE_TTL (default 30s).
+type Client struct {
+	base  string
+	token string
+	hc    *http.Client
+	ttl   time.Duration
+
+	mu   sync.Mutex
+	sets map[string]entry
+}
+
+type entry struct {
+	ids map[string]struct{}
+	exp time.Time
+}
+
+func NewClient(base string) *Client {
+	if base == "" {
+		base = getenv("USER_SERVICE_URL", "http://user-service:8081")
+	}
+	ttl := 30 * time.Second
+	if d, err := time.ParseDuration(os.Getenv("BLOCK_CACHE_TTL")); err == nil && d > 0 {
+		ttl = d
+	}
+	return &Client{
+		base:  strings.TrimRight(base, "/"),
+		token: os.Getenv("INTERNAL_TOKEN"),
+		hc:    &http.Client{Timeout: DefaultTimeout},
+		ttl:   ttl,
+		sets:  make(map[string]entry),
+	}
+}
+
+func getenv(k, d string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return d
+}
+
+// Set returns every user uid has blocked or been blocked by. Lookups fail
+// open: if user-service is unreachable the set is empty and the error logged.
+func (c *Client) Set(ctx context.Context, uid string) map[string]struct{} {
+	if uid == "" {
+		return nil
+	}
+	c.mu.Lock()
+	e, ok := c.sets[uid]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.exp) {
+		return e.ids
+	}
+	ids, err := c.fetch(ctx, uid)
+	if err != nil {
+		log.Printf("block lookup %s: %v", uid, err)
+		return nil
+	}
+	c.mu.Lock()
+	if len(c.sets) >= maxCached {
+		c.sets = make(map[string]entry)
+	}
+	c.sets[uid] = entry{ids: ids, exp: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return ids
+}
+
+func (c *Client) IsBlocked(ctx context.Context, a, b string) bool {
+	if a == "" || b == "" || a == b {
+		return false
+	}
+	_, ok := c.Set(ctx, a)[b]
+	return ok
+}
+
+func (c *Client) Invalidate(uids ...string) {
+	c.mu.Lock()
+	for _, u := range uids {
+		delete(c.sets, u)
+	}
+	c.mu.Unlock()
+}
+
+func (c *Client) fetch(ctx context.Context, uid string) (map[string]struct{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/internal/blocks/"+url.PathEscape(uid), nil)
+	req.Header.Set("X-Internal-Token", c.token)
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user-service status %d", resp.StatusCode)
+	}
+	var out struct {
+		Items []string `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	ids := make(map[string]struct{}, len(out.Items))
+	for _, id := range out.Items {
+		ids[id] = struct{}{}
+	}
+	return ids, nil
+}
+
+// Watch drops cached sets as soon as user-service publishes a block change on
+// social.blocks. Each process uses its own consumer group so every instance
+// sees every event.
+func (c *Client) Watch(ctx context.Context, brokers, service string) error {
+	if strings.TrimSpace(brokers) == "" {
+		brokers = "kafka:9092"
+	}
+	host, _ := os.Hostname()
+	r := kf.NewReader(kf.ReaderConfig{
+		Brokers:     strings.Split(brokers, ","),
+		GroupID:     service + "-blocks-" + host,
+		Topic:       "social.blocks",
+		StartOffset: kf.LastOffset,
+		MaxWait:     time.Second,
+	})
+	defer r.Close()
+	for {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		var ev struct {
+			BlockerID string `json:"blocker_id"`
+			BlockedID string `json:"blocked_id"`
+		}
+		if err := json.Unmarshal(m.Value, &ev); err != nil {
+			log.Printf("block event: bad payload: %v", err)
+			continue
+		}
+		c.Invalidate(ev.BlockerID, ev.BlockedID)
+	}
+}
@@ -1,11 +1,14 @@
 package chat
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"message-service/internal/shared/httpx"
 	"message-service/internal/shared/validate"
+
+	"gorm.io/gorm"
 )
 
 type Handler struct{ svc Service }
@@ -128,3 +131,33 @@ func qint(r *http.Request, key string, def int) int {
 	return n
 }
 func ok() map[string]string { return map[string]string{"status": "ok"} }
+
+// Owner tells moderation who created a chat.
+func (h *Handler) Owner(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseInt(r.PathValue("chat_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	c, err := h.svc.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		httpx.WriteError(w, http.StatusNotFound, err, "")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"user_id": c.OwnerID}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseInt(r.PathValue("chat_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Remove(r.Context(), id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
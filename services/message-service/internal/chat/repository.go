@@ -13,6 +13,13 @@ type Repository interface {
 	RemoveUser(chatID int64, userID string) error
 	ListByUser(userID string, limit, offset int) ([]Chat, error)
 	IsMember(chatID int64, userID string) (bool, error)
+	// ListMembers returns the members in the order they joined.
+	ListMembers(chatID int64) ([]string, error)
+	// Involving returns every chat the user owns or belongs to.
+	Involving(userID string) ([]Chat, error)
+	SetOwner(chatID int64, ownerID string) error
+	// Delete removes the chat together with its members and messages.
+	Delete(chatID int64) error
 }
 
 type repo struct{ store *db.Store }
@@ -67,4 +74,40 @@ func (r *repo) IsMember(chatID int64, userID string) (bool, error) {
 	return n > 0, nil
 }
 
+func (r *repo) ListMembers(chatID int64) ([]string, error) {
+	var out []string
+	err := r.store.Base.Model(&ChatUser{}).Where("chat_id = ?", chatID).Order("created_at, user_id").Pluck("user_id", &out).Error
+	return out, err
+}
+
+func (r *repo) Involving(userID string) ([]Chat, error) {
+	var out []Chat
+	err := r.store.Base.
+		Where("owner_id = ? OR id IN (?)", userID, r.store.Base.Model(&ChatUser{}).Select("chat_id").Where("user_id = ?", userID)).
+		Order("id").Find(&out).Error
+	return out, err
+}
+
+func (r *repo) SetOwner(chatID int64, ownerID string) error {
+	return r.store.Base.Model(&Chat{}).Where("id = ?", chatID).Update("owner_id", ownerID).Error
+}
+
+func (r *repo) Delete(chatID int64) error {
+	return r.store.Base.Transaction(func(tx *gorm.DB) error {
+		stmts := []string{
+			"DELETE FROM message_seens WHERE message_id IN (SELECT id FROM messages WHERE chat_id = ?)",
+			"DELETE FROM messages WHERE chat_id = ?",
+		}
+		for _, q := range stmts {
+			if err := tx.Exec(q, chatID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&ChatUser{}, "chat_id = ?", chatID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Chat{}, "id = ?", chatID).Error
+	})
+}
+
 var _ = gorm.ErrRecordNotFound
@@ -3,6 +3,7 @@ package chat
 import (
 	"context"
 	"fmt"
+	"message-service/internal/block"
 	"message-service/internal/redisx"
 )
 
@@ -18,18 +19,32 @@ type Service interface {
 
 	// NEW:
 	IsMember(chatID int64, userID string) (bool, error)
+	// CheckCanSend rejects a message in a one-to-one chat whose members have
+	// blocked each other.
+	CheckCanSend(ctx context.Context, chatID int64, userID string) error
+	// Remove deletes a chat and its history; used by moderation.
+	Remove(ctx context.Context, chatID int64) error
+	// ExportUser and EraseUser serve account exports and deletions.
+	ExportUser(ctx context.Context, uid string) (any, error)
+	EraseUser(ctx context.Context, uid string) error
 }
 
 type service struct {
-	repo Repository
-	rds  *redisx.Client
+	repo   Repository
+	rds    *redisx.Client
+	blocks *block.Client
 }
 
-func NewService(r Repository, rds *redisx.Client) Service {
-	return &service{repo: r, rds: rds}
+func NewService(r Repository, rds *redisx.Client, blocks *block.Client) Service {
+	return &service{repo: r, rds: rds, blocks: blocks}
 }
 
 func (s *service) Create(owner string, in CreateReq) (*Chat, error) {
+	for _, m := range in.Members {
+		if s.blocks.IsBlocked(context.Background(), owner, m) {
+			return nil, block.ErrBlocked
+		}
+	}
 	return s.repo.Create(owner, in.Name, in.Members)
 }
 func (s *service) GetByID(chatID int64) (*Chat, error) {
@@ -44,9 +59,19 @@ func (s *service) AddUser(chatID int64, actorID string, userID string) error {
 	if chat.OwnerID != actorID {
 		return fmt.Errorf("forbidden: only owner can add users")
 	}
+	if s.blocks.IsBlocked(context.Background(), actorID, userID) {
+		return block.ErrBlocked
+	}
 	return s.repo.AddUser(chatID, userID, "member")
 }
 func (s *service) Join(chatID int64, userID string) error {
+	chat, err := s.repo.GetByID(chatID)
+	if err != nil {
+		return err
+	}
+	if s.blocks.IsBlocked(context.Background(), chat.OwnerID, userID) {
+		return block.ErrBlocked
+	}
 	return s.repo.AddUser(chatID, userID, "member")
 }
 func (s *service) Leave(chatID int64, userID string) error { return s.repo.RemoveUser(chatID, userID) }
@@ -61,3 +86,61 @@ func (s *service) TopPopular(ctx context.Context, n int64) ([]int64, error) {
 func (s *service) IsMember(chatID int64, userID string) (bool, error) {
 	return s.repo.IsMember(chatID, userID)
 }
+
+func (s *service) CheckCanSend(ctx context.Context, chatID int64, userID string) error {
+	members, err := s.repo.ListMembers(chatID)
+	if err != nil {
+		return err
+	}
+	if len(members) != 2 {
+		return nil
+	}
+	for _, m := range members {
+		if s.blocks.IsBlocked(ctx, userID, m) {
+			return block.ErrBlocked
+		}
+	}
+	return nil
+}
+
+func (s *service) Remove(ctx context.Context, chatID int64) error {
+	if err := s.repo.Delete(chatID); err != nil {
+		return err
+	}
+	s.rds.DropPopular(ctx, chatID)
+	return nil
+}
+
+func (s *service) ExportUser(ctx context.Context, uid string) (any, error) {
+	return s.repo.Involving(uid)
+}
+
+// EraseUser takes the user out of every chat. A chat they own passes to its
+// longest-standing member, or is removed when nobody is left.
+func (s *service) EraseUser(ctx context.Context, uid string) error {
+	chats, err := s.repo.Involving(uid)
+	if err != nil {
+		return err
+	}
+	for _, c := range chats {
+		if err := s.repo.RemoveUser(c.ID, uid); err != nil {
+			return err
+		}
+		if c.OwnerID != uid {
+			continue
+		}
+		members, err := s.repo.ListMembers(c.ID)
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			err = s.Remove(ctx, c.ID)
+		} else {
+			err = s.repo.SetOwner(c.ID, members[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
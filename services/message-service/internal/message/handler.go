@@ -1,6 +1,7 @@
 package message
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -9,6 +10,8 @@ import (
 	"message-service/internal/idem"
 	"message-service/internal/shared/httpx"
 	"message-service/internal/shared/validate"
+
+	"gorm.io/gorm"
 )
 
 type Handler struct {
@@ -105,13 +108,21 @@ func (h *Handler) ListByChat(w http.ResponseWriter, r *http.Request) error {
 	}
 	cid, _ := strconv.ParseInt(r.PathValue("chat_id"), 10, 64)
 	limit := qint(r, "limit", 50)
-	offset := qint(r, "offset", 0)
 
-	items, err := h.svc.ListByChat(uid, cid, limit, offset)
+	// ?offset= is kept for older clients; otherwise page by ?cursor=.
+	if offset := qint(r, "offset", 0); offset > 0 {
+		items, err := h.svc.ListByChat(uid, cid, limit, offset)
+		if err != nil {
+			return err
+		}
+		httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+		return nil
+	}
+	page, err := h.svc.PageByChat(uid, cid, r.URL.Query().Get("cursor"), limit)
 	if err != nil {
 		return err
 	}
-	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	httpx.WriteJSON(w, page, http.StatusOK)
 	return nil
 }
 
@@ -139,3 +150,33 @@ func qint(r *http.Request, key string, def int) int {
 	}
 	return n
 }
+
+// Owner tells moderation who sent a message.
+func (h *Handler) Owner(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseInt(r.PathValue("message_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	m, err := h.svc.Get(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		httpx.WriteError(w, http.StatusNotFound, err, "")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"user_id": m.UserID}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseInt(r.PathValue("message_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Remove(id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -12,6 +12,21 @@ type Message struct {
 	DeliveredTime time.Time `json:"delivered_time"`
 }
 
+// Page is one page of a chat, newest first. NextCursor is empty on the last
+// page. Hidden messages still move the cursor, so a page can hold fewer than
+// Limit items while more follow.
+type Page struct {
+	Items      []Message `json:"items"`
+	Limit      int       `json:"limit"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// position is the last message of a page. Ids grow with send order, so the id
+// alone is a stable place in the chat.
+type position struct {
+	ID int64 `json:"id"`
+}
+
 type SendReq struct {
 	ChatID   int64  `json:"chat_id" validate:"required"`
 	Text     string `json:"text"`
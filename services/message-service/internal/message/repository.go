@@ -5,6 +5,7 @@ import (
 
 	"message-service/internal/shared/db"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -12,9 +13,17 @@ type Repository interface {
 	Create(m *Message) (*Message, error)
 	MarkSeen(messageID int64, userID string) error
 	ListByChat(chatID int64, limit, offset int) ([]Message, error)
+	// ListByChatBefore returns up to limit messages older than beforeID, or
+	// the newest when it is 0.
+	ListByChatBefore(chatID, beforeID int64, limit int) ([]Message, error)
 
 	// NEW:
 	GetByID(messageID int64) (*Message, error)
+	Delete(messageID int64) error
+	ListBySender(userID string) ([]Message, error)
+	ListSeenBy(userID string) ([]MessageSeen, error)
+	// DeleteByUser removes the messages the user sent and their read marks.
+	DeleteByUser(userID string) error
 }
 
 type repo struct{ store *db.Store }
@@ -51,6 +60,16 @@ func (r *repo) ListByChat(chatID int64, limit, offset int) ([]Message, error) {
 	return out, err
 }
 
+func (r *repo) ListByChatBefore(chatID, beforeID int64, limit int) ([]Message, error) {
+	q := r.store.Base.Where("chat_id = ?", chatID)
+	if beforeID > 0 {
+		q = q.Where("id < ?", beforeID)
+	}
+	var out []Message
+	err := q.Order("id DESC").Limit(limit).Find(&out).Error
+	return out, err
+}
+
 func (r *repo) GetByID(messageID int64) (*Message, error) {
 	var m Message
 	if err := r.store.Base.First(&m, "id = ?", messageID).Error; err != nil {
@@ -58,3 +77,40 @@ func (r *repo) GetByID(messageID int64) (*Message, error) {
 	}
 	return &m, nil
 }
+
+func (r *repo) Delete(messageID int64) error {
+	return r.store.Base.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&MessageSeen{}, "message_id = ?", messageID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Message{}, "id = ?", messageID).Error
+	})
+}
+
+func (r *repo) ListBySender(userID string) ([]Message, error) {
+	var out []Message
+	err := r.store.Base.Where("user_id = ?", userID).Order("id").Find(&out).Error
+	return out, err
+}
+
+func (r *repo) ListSeenBy(userID string) ([]MessageSeen, error) {
+	var out []MessageSeen
+	err := r.store.Base.Where("user_id = ?", userID).Order("seen_at").Find(&out).Error
+	return out, err
+}
+
+func (r *repo) DeleteByUser(userID string) error {
+	return r.store.Base.Transaction(func(tx *gorm.DB) error {
+		stmts := []string{
+			"DELETE FROM message_seens WHERE user_id = ?",
+			"DELETE FROM message_seens WHERE message_id IN (SELECT id FROM messages WHERE user_id = ?)",
+			"DELETE FROM messages WHERE user_id = ?",
+		}
+		for _, q := range stmts {
+			if err := tx.Exec(q, userID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -7,29 +7,42 @@ import (
 	"strconv"
 	"time"
 
+	"message-service/internal/block"
 	"message-service/internal/chat"
 	"message-service/internal/kafka"
 	"message-service/internal/media"
 	"message-service/internal/redisx"
+	"message-service/internal/shared/cursor"
 )
 
 type Service interface {
 	Send(ctx context.Context, userID string, in SendReq) (*Message, error)
 	SendWithUpload(ctx context.Context, userID string, chatID int64, fileName string, fileData []byte, text string, bearer string) (*Message, error)
 	MarkSeen(messageID int64, userID string) error
+	// ListByChat hides messages from senders blocked with userID.
 	ListByChat(userID string, chatID int64, limit, offset int) ([]Message, error)
+	// PageByChat is ListByChat paged by cursor; after is the NextCursor of the
+	// previous page.
+	PageByChat(userID string, chatID int64, after string, limit int) (*Page, error)
+	Get(messageID int64) (*Message, error)
+	// Remove deletes a message regardless of sender; used by moderation.
+	Remove(messageID int64) error
+	// ExportUser and EraseUser serve account exports and deletions.
+	ExportUser(ctx context.Context, uid string) (any, error)
+	EraseUser(ctx context.Context, uid string) error
 }
 
 type service struct {
-	repo  Repository
-	chats chat.Service
-	rds   *redisx.Client
-	kafka *kafka.Writer
-	media *media.Client
+	repo   Repository
+	chats  chat.Service
+	rds    *redisx.Client
+	kafka  *kafka.Writer
+	media  *media.Client
+	blocks *block.Client
 }
 
-func NewService(r Repository, cs chat.Service, rds *redisx.Client, kw *kafka.Writer, mc *media.Client) Service {
-	return &service{repo: r, chats: cs, rds: rds, kafka: kw, media: mc}
+func NewService(r Repository, cs chat.Service, rds *redisx.Client, kw *kafka.Writer, mc *media.Client, bc *block.Client) Service {
+	return &service{repo: r, chats: cs, rds: rds, kafka: kw, media: mc, blocks: bc}
 }
 
 var errForbidden = errors.New("forbidden") // simple sentinel
@@ -43,6 +56,9 @@ func (s *service) Send(ctx context.Context, userID string, in SendReq) (*Message
 	} else if !ok {
 		return nil, errForbidden
 	}
+	if err := s.chats.CheckCanSend(ctx, in.ChatID, userID); err != nil {
+		return nil, err
+	}
 
 	m := &Message{
 		UserID:   userID,
@@ -72,6 +88,9 @@ func (s *service) SendWithUpload(ctx context.Context, userID string, chatID int6
 	} else if !ok {
 		return nil, errForbidden
 	}
+	if err := s.chats.CheckCanSend(ctx, chatID, userID); err != nil {
+		return nil, err
+	}
 
 	url, err := s.media.Upload("file", fileName, bytesReader(data), bearer)
 	if err != nil {
@@ -99,9 +118,71 @@ func (s *service) ListByChat(userID string, chatID int64, limit, offset int) ([]
 	} else if !ok {
 		return nil, errForbidden
 	}
-	return s.repo.ListByChat(chatID, limit, offset)
+	items, err := s.repo.ListByChat(chatID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return s.visible(userID, items), nil
 }
 
+func (s *service) PageByChat(userID string, chatID int64, after string, limit int) (*Page, error) {
+	if ok, err := s.chats.IsMember(chatID, userID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errForbidden
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var pos position
+	if _, err := cursor.Decode(after, &pos); err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListByChatBefore(chatID, pos.ID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Limit: limit}
+	if len(items) > limit {
+		items = items[:limit]
+		page.NextCursor = cursor.Encode(position{ID: items[limit-1].ID})
+	}
+	page.Items = s.visible(userID, items)
+	return page, nil
+}
+
+// visible drops messages from senders blocked with userID.
+func (s *service) visible(userID string, items []Message) []Message {
+	hidden := s.blocks.Set(context.Background(), userID)
+	if len(hidden) == 0 {
+		return items
+	}
+	out := items[:0]
+	for _, m := range items {
+		if _, ok := hidden[m.UserID]; !ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (s *service) Get(messageID int64) (*Message, error) { return s.repo.GetByID(messageID) }
+func (s *service) Remove(messageID int64) error          { return s.repo.Delete(messageID) }
+
+func (s *service) ExportUser(ctx context.Context, uid string) (any, error) {
+	sent, err := s.repo.ListBySender(uid)
+	if err != nil {
+		return nil, err
+	}
+	seen, err := s.repo.ListSeenBy(uid)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"sent": sent, "seen": seen}, nil
+}
+
+func (s *service) EraseUser(ctx context.Context, uid string) error { return s.repo.DeleteByUser(uid) }
+
 func (s *service) emit(m *Message) error {
 	b, _ := json.Marshal(map[string]any{
 		"message_id": m.ID, "chat_id": m.ChatID, "user_id": m.UserID,
@@ -0,0 +1,84 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+// Target is one database the service keeps a schema in.
+type Target struct {
+	Name string
+	DB   *gorm.DB
+}
+
+const usage = "usage: migrate status | up | down [n] | to <version>"
+
+// Command runs the `migrate` subcommand against every target in turn and
+// stops at the first failure.
+func Command(out io.Writer, args []string, targets []Target) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	var run func(Target) error
+	switch args[0] {
+	case "status":
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		defer tw.Flush()
+		fmt.Fprintln(tw, "TARGET\tVERSION\tNAME\tAPPLIED")
+		run = func(t Target) error {
+			states, err := Status(t.DB)
+			if err != nil {
+				return err
+			}
+			for _, s := range states {
+				at := "pending"
+				if s.AppliedAt != nil {
+					at = s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(tw, "%s\t%04d\t%s\t%s\n", t.Name, s.Version, s.Name, at)
+			}
+			return nil
+		}
+	case "up":
+		run = func(t Target) error { return Up(t.DB) }
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			v, err := strconv.Atoi(args[1])
+			if err != nil || v < 1 {
+				return errors.New(usage)
+			}
+			n = v
+		}
+		run = func(t Target) error { return Down(t.DB, n) }
+	case "to":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		v, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || v < 0 {
+			return errors.New(usage)
+		}
+		run = func(t Target) error { return To(t.DB, v) }
+	default:
+		return errors.New(usage)
+	}
+	for _, t := range targets {
+		if err := run(t); err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		if args[0] != "status" {
+			v, err := Current(t.DB)
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.Name, err)
+			}
+			fmt.Fprintf(out, "%s: at version %d\n", t.Name, v)
+		}
+	}
+	return nil
+}
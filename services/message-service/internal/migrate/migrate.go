@@ -1,15 +1,9 @@
+// Package migrate keeps the service database's schema in step with the
+// numbered SQL files under sql/, recording applied versions in its
+// schema_migrations table.
 package migrate
 
-import (
-	"message-service/internal/chat"
-	"message-service/internal/message"
-	"message-service/internal/shared/db"
-)
+import "message-service/internal/shared/db"
 
-func AutoMigrateAll(store *db.Store) error {
-	return store.Base.AutoMigrate(
-		&chat.Chat{}, &chat.ChatUser{},
-		&message.Message{},
-		&message.MessageSeen{},
-	)
-}
+// Targets is the service database as the only migration target.
+func Targets(store *db.Store) []Target { return []Target{{Name: "db", DB: store.Base}} }
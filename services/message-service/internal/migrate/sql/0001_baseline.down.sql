@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS message_seens;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS chat_users;
+DROP TABLE IF EXISTS chats;
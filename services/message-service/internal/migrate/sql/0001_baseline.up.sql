@@ -0,0 +1,37 @@
+-- Schema as created by AutoMigrate before versioned migrations existed. Every
+-- statement is idempotent so existing databases only get recorded.
+
+CREATE TABLE IF NOT EXISTS chats (
+    id         bigserial PRIMARY KEY,
+    name       varchar(200),
+    owner_id   varchar(64),
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS chat_users (
+    chat_id    bigint,
+    user_id    varchar(64),
+    type       varchar(32),
+    created_at timestamptz,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id             bigserial PRIMARY KEY,
+    user_id        varchar(64),
+    chat_id        bigint,
+    text           text,
+    media_url      varchar(512),
+    send_time      timestamptz,
+    delivered_time timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_messages_chat_id ON messages (chat_id);
+
+CREATE TABLE IF NOT EXISTS message_seens (
+    message_id bigint,
+    user_id    varchar(64),
+    seen_at    timestamptz,
+    PRIMARY KEY (message_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_message_seens_message_id ON message_seens (message_id);
+CREATE INDEX IF NOT EXISTS idx_message_seens_user_id ON message_seens (user_id);
@@ -32,6 +32,9 @@ func (c *Client) IncPopular(ctx context.Context, chatID int64) {
 	_ = c.R.ZIncrBy(ctx, popularKey, 1, strconv.FormatInt(chatID, 10)).Err()
 	_ = c.R.Expire(ctx, popularKey, 24*time.Hour).Err()
 }
+func (c *Client) DropPopular(ctx context.Context, chatID int64) {
+	_ = c.R.ZRem(ctx, popularKey, strconv.FormatInt(chatID, 10)).Err()
+}
 func (c *Client) TopPopular(ctx context.Context, n int64) ([]int64, error) {
 	items, err := c.R.ZRevRange(ctx, popularKey, 0, n-1).Result()
 	if err != nil {
@@ -2,10 +2,14 @@ package httpx
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"message-service/internal/shared/jwt"
 )
@@ -21,6 +25,7 @@ type APIError struct {
 var (
 	ctxUserIDKey    = "httpx.user_id"
 	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
 )
 
 func WriteJSON(w http.ResponseWriter, v any, code int) {
@@ -42,6 +47,8 @@ func Wrap(fn HandlerFunc) http.Handler {
 			code := http.StatusBadRequest
 			if errors.Is(err, ErrUnauthorized) {
 				code = http.StatusUnauthorized
+			} else if errors.Is(err, ErrForbidden) {
+				code = http.StatusForbidden
 			}
 			WriteError(w, code, err, "")
 		}
@@ -66,6 +73,17 @@ func BearerToken(r *http.Request) string {
 	return ""
 }
 
+// RevocationChecker reports whether an access token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti, sid, uid string, iat time.Time) (bool, error)
+}
+
+var revocations RevocationChecker
+
+// UseRevocations makes AuthMiddleware consult rc for every request.
+// The check fails open: if rc errors, the token is accepted and the error logged.
+func UseRevocations(rc RevocationChecker) { revocations = rc }
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		h := r.Header.Get("Authorization")
@@ -74,16 +92,40 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		tok := strings.TrimSpace(h[7:])
-		uid, err := jwt.Parse(tok)
-		if err != nil || uid == "" {
+		c, err := jwt.ParseClaims(tok)
+		if err != nil || c.UserID == "" {
 			WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "invalid_token")
 			return
 		}
-		ctx := context.WithValue(r.Context(), ctxUserIDKey, uid)
+		if revocations != nil {
+			revoked, err := revocations.IsRevoked(r.Context(), c.ID, c.SessionID, c.UserID, c.IssuedAt)
+			if err != nil {
+				log.Printf("revocation check: %v", err)
+			} else if revoked {
+				WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "token_revoked")
+				return
+			}
+		}
+		ctx := context.WithValue(r.Context(), ctxUserIDKey, c.UserID)
+		ctx = context.WithValue(ctx, ctxRoleKey, c.Role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// InternalOnly admits service-to-service calls whose X-Internal-Token matches
+// INTERNAL_TOKEN. With no INTERNAL_TOKEN configured every request is refused.
+func InternalOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := os.Getenv("INTERNAL_TOKEN")
+		got := r.Header.Get("X-Internal-Token")
+		if want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			WriteError(w, http.StatusForbidden, ErrForbidden, "internal token required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func UserFromCtx(r *http.Request) (string, error) {
 	uid, _ := r.Context().Value(ctxUserIDKey).(string)
 	if uid == "" {
@@ -2,31 +2,49 @@ package jwt
 
 import (
 	"errors"
-	"os"
+	"time"
 
 	jw "github.com/golang-jwt/jwt/v5"
 )
 
-func secret() []byte {
-	if s := os.Getenv("JWT_SECRET"); s != "" {
-		return []byte(s)
-	}
-	return []byte("replace-this-with-a-strong-secret")
+type Claims struct {
+	UserID    string
+	ID        string // jti
+	SessionID string // sid; empty in tokens issued before sessions existed
+	Role      string // "user" in tokens issued before roles existed
+	IssuedAt  time.Time
 }
 
-// Parse returns userID from the token (we only need "sub")
-func Parse(tok string) (string, error) {
-	t, err := jw.Parse(tok, func(t *jw.Token) (any, error) { return secret(), nil })
+func ParseClaims(tok string) (Claims, error) {
+	t, err := jw.Parse(tok, keyFunc, jw.WithValidMethods(validMethods))
 	if err != nil || !t.Valid {
-		return "", errors.New("invalid token")
+		return Claims{}, errors.New("invalid token")
 	}
 	mc, ok := t.Claims.(jw.MapClaims)
 	if !ok {
-		return "", errors.New("bad claims")
+		return Claims{}, errors.New("bad claims")
 	}
 	uid, _ := mc["sub"].(string)
 	if uid == "" {
-		return "", errors.New("missing sub")
+		return Claims{}, errors.New("missing sub")
+	}
+	c := Claims{UserID: uid}
+	c.ID, _ = mc["jti"].(string)
+	c.SessionID, _ = mc["sid"].(string)
+	if c.Role, _ = mc["role"].(string); c.Role == "" {
+		c.Role = "user"
+	}
+	if iat, ok := mc["iat"].(float64); ok {
+		c.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	return c, nil
+}
+
+// Parse returns userID from the token (we only need "sub")
+func Parse(tok string) (string, error) {
+	c, err := ParseClaims(tok)
+	if err != nil {
+		return "", err
 	}
-	return uid, nil
+	return c.UserID, nil
 }
@@ -13,9 +13,12 @@ import (
 	"syscall"
 	"time"
 
+	"notification-service/internal/account"
+	"notification-service/internal/block"
 	"notification-service/internal/notification"
 	"notification-service/internal/shared/httpx"
 	"notification-service/internal/shared/redisx"
+	"notification-service/internal/shared/revoke"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/segmentio/kafka-go"
@@ -151,11 +154,17 @@ func main() {
 	rdb := redisx.OpenFromEnv()
 	defer func() { _ = rdb.Close() }()
 
+	blocks := block.NewClient(os.Getenv("USER_SERVICE_URL"))
+
 	repo := notification.NewRedisRepository(rdb)
-	svc := notification.NewService(repo)
+	svc := notification.NewService(repo, blocks)
 	h := notification.NewHandler(svc)
 
 	// HTTP Router
+	revoked := revoke.OpenFromEnv()
+	defer revoked.Close()
+	httpx.UseRevocations(revoked)
+
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
@@ -171,6 +180,9 @@ func main() {
 	protect("POST /notifications/{id}/read", httpx.Wrap(h.MarkRead))
 	protect("POST /notifications/test", httpx.Wrap(h.CreateTest))
 
+	acc := account.New(account.NewReporter(os.Getenv("USER_SERVICE_URL")), account.Part{Name: "notifications", Eraser: svc})
+	mux.Handle("GET /internal/users/{user_id}/export", httpx.InternalOnly(http.HandlerFunc(account.NewHandler(acc).Export)))
+
 	// Server
 	addr := envOr("APP_PORT", ":8086")
 	srv := &http.Server{
@@ -196,6 +208,18 @@ func main() {
 		}
 	}()
 
+	go func() {
+		if err := blocks.Watch(ctx, brokers, "notification-service"); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("block watcher stopped: %v", err)
+		}
+	}()
+
+	go func() {
+		if err := acc.Watch(ctx, brokers); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("account watcher stopped: %v", err)
+		}
+	}()
+
 	// Start Kafka consumer
 	go func() {
 		log.Printf("kafka consuming topic=%s group=%s brokers=%s", topic, groupID, brokers)
@@ -0,0 +1,52 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reporter tells user-service how this service's step of a request went.
+type Reporter struct {
+	base  string
+	token string
+	hc    *http.Client
+}
+
+func NewReporter(userServiceURL string) *Reporter {
+	if userServiceURL == "" {
+		userServiceURL = "http://user-service:8081"
+	}
+	return &Reporter{
+		base:  strings.TrimRight(userServiceURL, "/"),
+		token: os.Getenv("INTERNAL_TOKEN"),
+		hc:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (r *Reporter) Report(ctx context.Context, requestID, state, detail string) error {
+	body, _ := json.Marshal(map[string]string{"state": state, "detail": detail})
+	endpoint := r.base + "/internal/account/requests/" + url.PathEscape(requestID) + "/steps/" + Service
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", r.token)
+	resp, err := r.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// An unknown request will not appear by asking again.
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("report %s: status %d", requestID, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	kf "github.com/segmentio/kafka-go"
+)
+
+// Watch erases every user announced on Topic and reports the outcome. The
+// offset is only committed once user-service has the report, so a crash in
+// between runs the deletion again.
+func (a *Account) Watch(ctx context.Context, brokers string) error {
+	if strings.TrimSpace(brokers) == "" {
+		brokers = "kafka:9092"
+	}
+	r := kf.NewReader(kf.ReaderConfig{
+		Brokers:     strings.Split(brokers, ","),
+		GroupID:     Service + "-account",
+		Topic:       Topic,
+		StartOffset: kf.FirstOffset,
+		MaxWait:     time.Second,
+	})
+	defer r.Close()
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		var ev struct {
+			UserID    string `json:"user_id"`
+			RequestID string `json:"request_id"`
+		}
+		if err := json.Unmarshal(m.Value, &ev); err != nil || ev.UserID == "" {
+			log.Printf("users.deleted: bad payload: %v", err)
+		} else {
+			state, detail := StateDone, ""
+			if err := a.Erase(ctx, ev.UserID); err != nil {
+				log.Printf("account erase %s: %v", ev.UserID, err)
+				state, detail = StateFailed, err.Error()
+			}
+			if err := a.report(ctx, ev.RequestID, state, detail); err != nil {
+				return err
+			}
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+	}
+}
+
+// report retries until user-service takes the report or ctx ends.
+func (a *Account) report(ctx context.Context, requestID, state, detail string) error {
+	wait := time.Second
+	for {
+		err := a.reporter.Report(ctx, requestID, state, detail)
+		if err == nil {
+			return nil
+		}
+		log.Printf("account report %s: %v", requestID, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if wait < time.Minute {
+			wait *= 2
+		}
+	}
+}
@@ -13,6 +13,8 @@ type Repository interface {
 	Push(ctx context.Context, n Notification) error
 	List(ctx context.Context, userID string, limit int64) ([]Notification, error)
 	MarkRead(ctx context.Context, userID, notifID string) error
+	All(ctx context.Context, userID string) ([]Notification, error)
+	Drop(ctx context.Context, userID string) error
 }
 
 type redisRepo struct {
@@ -81,3 +83,22 @@ func (r *redisRepo) MarkRead(ctx context.Context, userID, notifID string) error
 	// LSET updates in-place; maintains order and is O(1)
 	return r.rdb.LSet(ctx, k, int64(idx), updated).Err()
 }
+
+func (r *redisRepo) All(ctx context.Context, userID string) ([]Notification, error) {
+	vals, err := r.rdb.LRange(ctx, key(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Notification, 0, len(vals))
+	for _, v := range vals {
+		var n Notification
+		if json.Unmarshal([]byte(v), &n) == nil {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func (r *redisRepo) Drop(ctx context.Context, userID string) error {
+	return r.rdb.Del(ctx, key(userID)).Err()
+}
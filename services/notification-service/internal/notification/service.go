@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"notification-service/internal/block"
+
 	"github.com/google/uuid"
 )
 
@@ -11,11 +13,27 @@ type Service interface {
 	Create(ctx context.Context, userID string, kind Kind, title, body string, meta map[string]any) (Notification, error)
 	List(ctx context.Context, userID string, limit int64) ([]Notification, error)
 	MarkRead(ctx context.Context, userID, notifID string) error
+	// ExportUser and EraseUser serve account exports and deletions. Entries
+	// the user caused in other people's lists are left to expire.
+	ExportUser(ctx context.Context, uid string) (any, error)
+	EraseUser(ctx context.Context, uid string) error
+}
+
+type service struct {
+	repo   Repository
+	blocks *block.Client
 }
 
-type service struct{ repo Repository }
+func NewService(r Repository, blocks *block.Client) Service {
+	return &service{repo: r, blocks: blocks}
+}
 
-func NewService(r Repository) Service { return &service{repo: r} }
+// actorOf returns the user who caused a notification, if the producer set
+// meta["actor_id"].
+func actorOf(meta map[string]any) string {
+	s, _ := meta["actor_id"].(string)
+	return s
+}
 
 func (s *service) Create(ctx context.Context, userID string, kind Kind, title, body string, meta map[string]any) (Notification, error) {
 	n := Notification{
@@ -27,13 +45,37 @@ func (s *service) Create(ctx context.Context, userID string, kind Kind, title, b
 		Meta:      meta,
 		CreatedAt: time.Now().UTC(),
 	}
+	// Notifications from a blocked actor are dropped silently.
+	if s.blocks.IsBlocked(ctx, userID, actorOf(meta)) {
+		return n, nil
+	}
 	return n, s.repo.Push(ctx, n)
 }
 
 func (s *service) List(ctx context.Context, userID string, limit int64) ([]Notification, error) {
-	return s.repo.List(ctx, userID, limit)
+	items, err := s.repo.List(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	hidden := s.blocks.Set(ctx, userID)
+	if len(hidden) == 0 {
+		return items, nil
+	}
+	out := items[:0]
+	for _, n := range items {
+		if _, ok := hidden[actorOf(n.Meta)]; !ok {
+			out = append(out, n)
+		}
+	}
+	return out, nil
 }
 
 func (s *service) MarkRead(ctx context.Context, userID, notifID string) error {
 	return s.repo.MarkRead(ctx, userID, notifID)
 }
+
+func (s *service) ExportUser(ctx context.Context, uid string) (any, error) {
+	return s.repo.All(ctx, uid)
+}
+
+func (s *service) EraseUser(ctx context.Context, uid string) error { return s.repo.Drop(ctx, uid) }
@@ -2,14 +2,16 @@ package httpx
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
-	jw "github.com/golang-jwt/jwt/v5"
+	"notification-service/internal/shared/jwt"
 )
 
 type HandlerFunc func(http.ResponseWriter, *http.Request) error
@@ -24,7 +26,10 @@ type ctxKey string
 
 const userKey ctxKey = "user_id"
 
-var ErrUnauthorized = errors.New("unauthorized")
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+)
 
 func WriteJSON(w http.ResponseWriter, v any, code int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -39,32 +44,6 @@ func WriteError(w http.ResponseWriter, status int, err error, reason string) {
 	WriteJSON(w, APIError{Error: err.Error(), Reason: reason, Status: status}, status)
 }
 
-func secret() []byte {
-	if s := os.Getenv("JWT_SECRET"); s != "" {
-		return []byte(s)
-	}
-	return []byte("replace-this-with-a-strong-secret")
-}
-
-func parseJWT(tok string) (string, error) {
-	t, err := jw.Parse(tok, func(t *jw.Token) (any, error) { return secret(), nil })
-	if err != nil || !t.Valid {
-		return "", errors.New("invalid token")
-	}
-	mc, ok := t.Claims.(jw.MapClaims)
-	if !ok {
-		return "", errors.New("bad claims")
-	}
-	uid, _ := mc["sub"].(string)
-	if uid == "" {
-		return "", errors.New("missing sub")
-	}
-	if exp, ok := mc["exp"].(float64); ok && time.Now().Unix() > int64(exp) {
-		return "", errors.New("token expired")
-	}
-	return uid, nil
-}
-
 func Wrap(fn HandlerFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if err := fn(w, r); err != nil {
@@ -73,29 +52,66 @@ func Wrap(fn HandlerFunc) http.Handler {
 	})
 }
 
+// RevocationChecker reports whether an access token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti, sid, uid string, iat time.Time) (bool, error)
+}
+
+var revocations RevocationChecker
+
+// UseRevocations makes AuthMiddleware consult rc for every request.
+// The check fails open: if rc errors, the token is accepted and the error logged.
+func UseRevocations(rc RevocationChecker) { revocations = rc }
+
+func isRevoked(ctx context.Context, jti, sid, uid string, iat time.Time) bool {
+	if revocations == nil {
+		return false
+	}
+	revoked, err := revocations.IsRevoked(ctx, jti, sid, uid, iat)
+	if err != nil {
+		log.Printf("revocation check: %v", err)
+		return false
+	}
+	return revoked
+}
+
 func AuthMiddleware(next http.Handler) http.Handler {
-	secret := os.Getenv("JWT_SECRET")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if secret == "" {
-			next.ServeHTTP(w, r)
-			return
-		}
 		h := r.Header.Get("Authorization")
 		if !strings.HasPrefix(h, "Bearer ") {
 			WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "missing_bearer")
 			return
 		}
 		token := strings.TrimSpace(h[7:])
-		uid, err := parseJWT(token)
+		c, err := jwt.ParseClaims(token)
 		if err != nil {
 			WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "invalid_token")
 			return
 		}
-		ctx := context.WithValue(r.Context(), userKey, uid)
+		if isRevoked(r.Context(), c.ID, c.SessionID, c.UserID, c.IssuedAt) {
+			WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "token_revoked")
+			return
+		}
+		ctx := context.WithValue(r.Context(), userKey, c.UserID)
+		ctx = context.WithValue(ctx, ctxRoleKey, c.Role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// InternalOnly admits service-to-service calls whose X-Internal-Token matches
+// INTERNAL_TOKEN. With no INTERNAL_TOKEN configured every request is refused.
+func InternalOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := os.Getenv("INTERNAL_TOKEN")
+		got := r.Header.Get("X-Internal-Token")
+		if want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			WriteError(w, http.StatusForbidden, ErrForbidden, "internal token required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func UserFromCtx(r *http.Request) (string, error) {
 	uid, _ := r.Context().Value(userKey).(string)
 	if uid == "" {
@@ -0,0 +1,177 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	jw "github.com/golang-jwt/jwt/v5"
+)
+
+// Tokens are signed by user-service; its public keys are fetched from
+// JWKS_URL and cached. A kid we have not seen triggers an early refetch, so
+// freshly rotated keys are picked up without waiting for the cache to expire.
+const (
+	jwksMaxAge     = 5 * time.Minute
+	jwksMinRefetch = 30 * time.Second
+)
+
+var validMethods = []string{"EdDSA", "RS256"}
+
+type publicKey struct {
+	alg string
+	key any
+}
+
+var jwks = struct {
+	sync.Mutex
+	keys    map[string]publicKey
+	fetched time.Time
+	tried   time.Time
+	// inflight is closed when the running fetch ends; nil if none runs.
+	inflight chan struct{}
+}{}
+
+var jwksClient = &http.Client{Timeout: 5 * time.Second}
+
+func jwksURL() string {
+	if s := os.Getenv("JWKS_URL"); s != "" {
+		return s
+	}
+	return "http://user-service:8081/.well-known/jwks.json"
+}
+
+// lookup returns the key for kid, refetching the set when it is stale or the
+// kid is unknown. The fetch runs outside the lock: a known kid is served
+// from the cache meanwhile and only callers with an unknown kid wait for it.
+// A failed fetch keeps the keys we already have.
+func lookup(kid string) (publicKey, bool) {
+	jwks.Lock()
+	k, ok := jwks.keys[kid]
+	now := time.Now()
+	if ok && now.Sub(jwks.fetched) <= jwksMaxAge {
+		jwks.Unlock()
+		return k, ok
+	}
+	done := jwks.inflight
+	if done == nil && now.Sub(jwks.tried) >= jwksMinRefetch {
+		jwks.tried = now
+		done = make(chan struct{})
+		jwks.inflight = done
+		go refresh(done)
+	}
+	jwks.Unlock()
+	if ok || done == nil {
+		return k, ok
+	}
+	<-done
+	jwks.Lock()
+	defer jwks.Unlock()
+	k, ok = jwks.keys[kid]
+	return k, ok
+}
+
+func refresh(done chan struct{}) {
+	keys, err := fetchJWKS()
+	jwks.Lock()
+	if err != nil {
+		log.Printf("jwks: %v", err)
+	} else {
+		jwks.keys, jwks.fetched = keys, time.Now()
+	}
+	jwks.inflight = nil
+	jwks.Unlock()
+	close(done)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func fetchJWKS() (map[string]publicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := jwksClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", jwksURL(), resp.Status)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	out := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		pk, err := k.public()
+		if err != nil {
+			log.Printf("jwks: key %q: %v", k.Kid, err)
+			continue
+		}
+		out[k.Kid] = pk
+	}
+	return out, nil
+}
+
+func (k jwk) public() (publicKey, error) {
+	dec := base64.RawURLEncoding.DecodeString
+	switch {
+	case k.Kty == "OKP" && k.Crv == "Ed25519" && k.Alg == "EdDSA":
+		x, err := dec(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, errors.New("bad x")
+		}
+		return publicKey{alg: k.Alg, key: ed25519.PublicKey(x)}, nil
+	case k.Kty == "RSA" && k.Alg == "RS256":
+		n, err := dec(k.N)
+		if err != nil {
+			return publicKey{}, errors.New("bad n")
+		}
+		e, err := dec(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return publicKey{}, errors.New("bad e")
+		}
+		return publicKey{alg: k.Alg, key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	}
+	return publicKey{}, fmt.Errorf("unsupported key %s/%s", k.Kty, k.Alg)
+}
+
+// keyFunc accepts only tokens naming a key user-service publishes.
+func keyFunc(t *jw.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid")
+	}
+	k, ok := lookup(kid)
+	if !ok || k.alg != t.Method.Alg() {
+		return nil, errors.New("unknown key")
+	}
+	return k.key, nil
+}
@@ -8,11 +8,14 @@ import (
 	"strconv"
 	"time"
 
+	"post-service/internal/account"
+	"post-service/internal/block"
 	"post-service/internal/kafka"
 	"post-service/internal/migrate"
 	"post-service/internal/post"
 	"post-service/internal/shared/db"
 	"post-service/internal/shared/httpx"
+	"post-service/internal/shared/revoke"
 	"post-service/internal/tag"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -67,6 +70,14 @@ func initOTEL(ctx context.Context) func(context.Context) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		store := db.OpenFromEnv()
+		if err := migrate.Command(os.Stdout, os.Args[2:], migrate.Targets(store)); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 	shutdown := initOTEL(ctx)
 	defer func() {
@@ -78,7 +89,7 @@ func main() {
 	store := db.OpenFromEnv()
 
 	if os.Getenv("AUTO_MIGRATE") == "true" {
-		if err := migrate.AutoMigrateAll(store); err != nil {
+		if err := migrate.Up(store.Base); err != nil {
 			log.Fatalf("migrate: %v", err)
 		}
 	}
@@ -88,19 +99,45 @@ func main() {
 		log.Fatalf("kafka writer: %v", err)
 	}
 	defer kWriter.Close()
+	deletedWriter, err := kafka.NewWriter(os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), "posts.deleted")
+	if err != nil {
+		log.Fatalf("kafka writer: %v", err)
+	}
+	defer deletedWriter.Close()
 
 	tagRepo := tag.NewRepository(store)
 	tagSvc := tag.NewService(tagRepo)
 
 	postRepo := post.NewRepository(store)
-	postSvc := post.NewService(postRepo, tagSvc, kWriter)
+	postSvc := post.NewService(postRepo, tagSvc, kWriter, deletedWriter)
+
+	revoked := revoke.OpenFromEnv()
+	defer revoked.Close()
+	httpx.UseRevocations(revoked)
 
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 
-	ph := post.NewHandler(postSvc)
-	mux.Handle("GET /posts/{post_id}", httpx.Wrap(ph.GetByID))
-	mux.Handle("GET /users/{user_id}/posts", httpx.Wrap(ph.ListByUser))
+	blocks := block.NewClient(os.Getenv("USER_SERVICE_URL"))
+	go func() {
+		if err := blocks.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), "post-service"); err != nil {
+			log.Printf("block watcher stopped: %v", err)
+		}
+	}()
+
+	acc := account.New(account.NewReporter(os.Getenv("USER_SERVICE_URL")), account.Part{Name: "posts", Eraser: postSvc})
+	go func() {
+		if err := acc.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS")); err != nil {
+			log.Printf("account watcher stopped: %v", err)
+		}
+	}()
+	mux.Handle("GET /internal/users/{user_id}/export", httpx.InternalOnly(http.HandlerFunc(account.NewHandler(acc).Export)))
+
+	ph := post.NewHandler(postSvc, blocks)
+	mux.Handle("GET /posts/{post_id}", httpx.OptionalAuth(httpx.Wrap(ph.GetByID)))
+	mux.Handle("GET /users/{user_id}/posts", httpx.OptionalAuth(httpx.Wrap(ph.ListByUser)))
+	mux.Handle("GET /internal/posts/{post_id}/owner", httpx.InternalOnly(httpx.Wrap(ph.Owner)))
+	mux.Handle("DELETE /internal/posts/{post_id}", httpx.InternalOnly(httpx.Wrap(ph.Remove)))
 
 	protect := func(pattern string, h http.Handler) {
 		mux.Handle(pattern, httpx.AuthMiddleware(h))
@@ -0,0 +1,27 @@
+-- Schema as created by AutoMigrate before versioned migrations existed. Every
+-- statement is idempotent so existing databases only get recorded.
+
+CREATE TABLE IF NOT EXISTS posts (
+    id          bigserial PRIMARY KEY,
+    user_id     varchar(64),
+    description text,
+    media_url   varchar(512),
+    likes       bigint,
+    views       bigint,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts (user_id);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id bigint,
+    tag_id  bigint,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id         bigserial PRIMARY KEY,
+    name       varchar(120),
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
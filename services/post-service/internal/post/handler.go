@@ -6,14 +6,27 @@ import (
 	"strconv"
 	"strings"
 
+	"post-service/internal/block"
 	"post-service/internal/feedback"
 	"post-service/internal/shared/httpx"
 	"post-service/internal/shared/validate"
+
+	"gorm.io/gorm"
 )
 
-type Handler struct{ svc Service }
+type Handler struct {
+	svc    Service
+	blocks *block.Client
+}
+
+func NewHandler(s Service, blocks *block.Client) *Handler { return &Handler{svc: s, blocks: blocks} }
 
-func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+// hiddenFrom reports whether author's content must be hidden from the
+// (optionally authenticated) caller because of a block.
+func (h *Handler) hiddenFrom(r *http.Request, author string) bool {
+	viewer, _ := httpx.UserFromCtx(r)
+	return viewer != "" && h.blocks.IsBlocked(r.Context(), viewer, author)
+}
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) error {
 	uid, err := httpx.UserFromCtx(r)
@@ -71,6 +84,9 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
+	if h.hiddenFrom(r, p.UserID) {
+		return gorm.ErrRecordNotFound
+	}
 
 	// Enrich counts from feedback-service
 	ctx, cancel := context.WithTimeout(r.Context(), feedback.DefaultTimeout)
@@ -95,13 +111,24 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) error {
 
 func (h *Handler) ListByUser(w http.ResponseWriter, r *http.Request) error {
 	uid := r.PathValue("user_id")
+	if h.hiddenFrom(r, uid) {
+		return gorm.ErrRecordNotFound
+	}
 	limit := httpx.QueryInt(r, "limit", 50)
-	offset := httpx.QueryInt(r, "offset", 0)
-	items, err := h.svc.ListByUser(uid, limit, offset)
+	// ?offset= is kept for older clients; otherwise page by ?cursor=.
+	if offset := httpx.QueryInt(r, "offset", 0); offset > 0 {
+		items, err := h.svc.ListByUser(uid, limit, offset)
+		if err != nil {
+			return err
+		}
+		httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+		return nil
+	}
+	page, err := h.svc.PageByUser(uid, r.URL.Query().Get("cursor"), limit)
 	if err != nil {
 		return err
 	}
-	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	httpx.WriteJSON(w, page, http.StatusOK)
 	return nil
 }
 
@@ -113,3 +140,29 @@ func (h *Handler) AddView(w http.ResponseWriter, r *http.Request) error {
 	httpx.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
 	return nil
 }
+
+// Owner tells moderation who wrote a post.
+func (h *Handler) Owner(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseUint(r.PathValue("post_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	p, err := h.svc.GetByID(id)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"user_id": p.UserID}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Remove(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseUint(r.PathValue("post_id"), 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Remove(id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
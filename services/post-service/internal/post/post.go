@@ -13,6 +13,25 @@ type Post struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// UserPost is a post as it appears in its author's data export.
+type UserPost struct {
+	Post
+	Tags []string `json:"tags"`
+}
+
+// Page is one page of a user's posts. NextCursor is empty on the last page.
+type Page struct {
+	Items      []Post `json:"items"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// position is the last post of a page; the next page starts right after it.
+type position struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint64    `json:"id"`
+}
+
 type PostTag struct {
 	PostID uint64 `gorm:"primaryKey"`
 	TagID  uint64 `gorm:"primaryKey"`
@@ -13,8 +13,16 @@ type Repository interface {
 	Create(p *Post) (*Post, error)
 	GetByID(id uint64) (*Post, error)
 	ListByUser(userID string, limit, offset int) ([]Post, error)
+	// ListByUserAfter returns up to limit posts older than after, or the
+	// newest when it is nil.
+	ListByUserAfter(userID string, after *position, limit int) ([]Post, error)
 	AttachTags(postID uint64, tagIDs []uint64) error
 	IncView(postID uint64) error
+	Delete(postID uint64) error
+	// AllByUser returns every post of the user with its tag names.
+	AllByUser(userID string) ([]UserPost, error)
+	IDsByUser(userID string) ([]uint64, error)
+	DeleteByUser(userID string) error
 }
 
 type repo struct{ store *db.Store }
@@ -40,11 +48,21 @@ func (r *repo) ListByUser(userID string, limit, offset int) ([]Post, error) {
 	var out []Post
 	err := r.store.Base.
 		Where("user_id = ?", userID).
-		Order("created_at DESC").Limit(limit).Offset(offset).
+		Order("created_at DESC, id DESC").Limit(limit).Offset(offset).
 		Find(&out).Error
 	return out, err
 }
 
+func (r *repo) ListByUserAfter(userID string, after *position, limit int) ([]Post, error) {
+	q := r.store.Base.Where("user_id = ?", userID)
+	if after != nil {
+		q = q.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+	var out []Post
+	err := q.Order("created_at DESC, id DESC").Limit(limit).Find(&out).Error
+	return out, err
+}
+
 func (r *repo) AttachTags(postID uint64, tagIDs []uint64) error {
 	if len(tagIDs) == 0 {
 		return nil
@@ -61,4 +79,56 @@ func (r *repo) IncView(postID uint64) error {
 	return res.Error
 }
 
+func (r *repo) Delete(postID uint64) error {
+	return r.store.Base.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&PostTag{}, "post_id = ?", postID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Post{}, "id = ?", postID).Error
+	})
+}
+
+func (r *repo) AllByUser(userID string) ([]UserPost, error) {
+	var posts []Post
+	if err := r.store.Base.Where("user_id = ?", userID).Order("created_at, id").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		PostID uint64
+		Name   string
+	}
+	err := r.store.Base.Table("post_tags pt").Select("pt.post_id, t.name").
+		Joins("JOIN tags t ON t.id = pt.tag_id").
+		Where("pt.post_id IN (?)", r.store.Base.Model(&Post{}).Select("id").Where("user_id = ?", userID)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[uint64][]string)
+	for _, row := range rows {
+		tags[row.PostID] = append(tags[row.PostID], row.Name)
+	}
+	out := make([]UserPost, len(posts))
+	for i, p := range posts {
+		out[i] = UserPost{Post: p, Tags: tags[p.ID]}
+	}
+	return out, nil
+}
+
+func (r *repo) IDsByUser(userID string) ([]uint64, error) {
+	var ids []uint64
+	err := r.store.Base.Model(&Post{}).Where("user_id = ?", userID).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *repo) DeleteByUser(userID string) error {
+	return r.store.Base.Transaction(func(tx *gorm.DB) error {
+		owned := tx.Model(&Post{}).Select("id").Where("user_id = ?", userID)
+		if err := tx.Where("post_id IN (?)", owned).Delete(&PostTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&Post{}).Error
+	})
+}
+
 var _ = errors.New
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"post-service/internal/kafka"
+	"post-service/internal/shared/cursor"
 	"post-service/internal/shared/validate"
 	"post-service/internal/tag"
 )
@@ -21,18 +22,28 @@ type Service interface {
 	Create(uid string, in CreateReq) (*Post, error)
 	GetByID(id uint64) (*Post, error)
 	ListByUser(userID string, limit, offset int) ([]Post, error)
+	// PageByUser pages through a user's posts by cursor; after is the
+	// NextCursor of the previous page.
+	PageByUser(userID, after string, limit int) (*Page, error)
 	AddView(postID uint64) error
 	UploadAndCreate(uid string, filename string, file io.Reader, description string, tags []string, bearer string) (*Post, error)
+	// Remove deletes a post regardless of owner; used by moderation.
+	Remove(postID uint64) error
+	// ExportUser and EraseUser serve account exports and deletions.
+	ExportUser(ctx context.Context, uid string) (any, error)
+	EraseUser(ctx context.Context, uid string) error
 }
 
 type service struct {
-	repo  Repository
-	tags  tag.Service
-	kafka kafka.Writer
+	repo    Repository
+	tags    tag.Service
+	kafka   kafka.Writer
+	deleted kafka.Writer
 }
 
-func NewService(r Repository, t tag.Service, kw kafka.Writer) Service {
-	return &service{repo: r, tags: t, kafka: kw}
+// NewService publishes new posts with kw and removed ones with deleted.
+func NewService(r Repository, t tag.Service, kw, deleted kafka.Writer) Service {
+	return &service{repo: r, tags: t, kafka: kw, deleted: deleted}
 }
 
 func (s *service) Create(uid string, in CreateReq) (*Post, error) {
@@ -77,8 +88,68 @@ func (s *service) ListByUser(userID string, limit, offset int) ([]Post, error) {
 	return s.repo.ListByUser(userID, limit, offset)
 }
 
+func (s *service) PageByUser(userID, after string, limit int) (*Page, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var from *position
+	var pos position
+	if ok, err := cursor.Decode(after, &pos); err != nil {
+		return nil, err
+	} else if ok {
+		from = &pos
+	}
+	items, err := s.repo.ListByUserAfter(userID, from, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Items: items, Limit: limit}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := items[limit-1]
+		page.NextCursor = cursor.Encode(position{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
+
 func (s *service) AddView(postID uint64) error { return s.repo.IncView(postID) }
 
+func (s *service) Remove(postID uint64) error {
+	p, err := s.repo.GetByID(postID)
+	if err != nil {
+		return err
+	}
+	if err := s.announceDeleted(context.Background(), p.UserID, []uint64{postID}); err != nil {
+		return err
+	}
+	return s.repo.Delete(postID)
+}
+
+// announceDeleted tells feedback-service and feed-service to drop what they
+// keep about the posts. It goes out before the posts are deleted, so a
+// failure in between is fixed by running the deletion again.
+func (s *service) announceDeleted(ctx context.Context, uid string, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.deleted.WriteJSON(ctx, map[string]any{"user_id": uid, "post_ids": ids})
+}
+
+func (s *service) ExportUser(ctx context.Context, uid string) (any, error) {
+	return s.repo.AllByUser(uid)
+}
+
+func (s *service) EraseUser(ctx context.Context, uid string) error {
+	ids, err := s.repo.IDsByUser(uid)
+	if err != nil {
+		return err
+	}
+	if err := s.announceDeleted(ctx, uid, ids); err != nil {
+		return err
+	}
+	return s.repo.DeleteByUser(uid)
+}
+
 func (s *service) UploadAndCreate(uid, filename string, file io.Reader, description string, tags []string, bearer string) (*Post, error) {
 	mediaURL, err := uploadToMediaService(filename, file, bearer)
 	if err != nil {
@@ -0,0 +1,31 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalid = errors.New("invalid cursor")
+
+// Encode packs a position into an opaque, URL-safe cursor string.
+func Encode(v any) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode unpacks a cursor produced by Encode into v. An empty string leaves v
+// untouched and reports false.
+func Decode(s string, v any) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return false, ErrInvalid
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, ErrInvalid
+	}
+	return true, nil
+}
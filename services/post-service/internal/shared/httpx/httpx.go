@@ -2,11 +2,15 @@ package httpx
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"post-service/internal/shared/jwt"
 
@@ -24,6 +28,7 @@ type APIError struct {
 var (
 	ctxUserIDKey    = "httpx.user_id"
 	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
 )
 
 func WriteJSON(w http.ResponseWriter, v any, code int) {
@@ -45,6 +50,8 @@ func Wrap(fn HandlerFunc) http.Handler {
 			code := http.StatusBadRequest
 			if errors.Is(err, ErrUnauthorized) {
 				code = http.StatusUnauthorized
+			} else if errors.Is(err, ErrForbidden) {
+				code = http.StatusForbidden
 			} else if errors.Is(err, gorm.ErrRecordNotFound) {
 				code = http.StatusNotFound
 			}
@@ -59,6 +66,17 @@ func Decode[T any](r *http.Request) (T, error) {
 	return t, err
 }
 
+// RevocationChecker reports whether an access token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti, sid, uid string, iat time.Time) (bool, error)
+}
+
+var revocations RevocationChecker
+
+// UseRevocations makes AuthMiddleware consult rc for every request.
+// The check fails open: if rc errors, the token is accepted and the error logged.
+func UseRevocations(rc RevocationChecker) { revocations = rc }
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		h := r.Header.Get("Authorization")
@@ -67,16 +85,64 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		tok := strings.TrimSpace(h[7:])
-		uid, _, err := jwt.Parse(tok)
-		if err != nil || uid == "" {
+		c, err := jwt.ParseClaims(tok)
+		if err != nil || c.UserID == "" {
 			WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "invalid_token")
 			return
 		}
-		ctx := context.WithValue(r.Context(), ctxUserIDKey, uid)
+		if revocations != nil {
+			revoked, err := revocations.IsRevoked(r.Context(), c.ID, c.SessionID, c.UserID, c.IssuedAt)
+			if err != nil {
+				log.Printf("revocation check: %v", err)
+			} else if revoked {
+				WriteError(w, http.StatusUnauthorized, ErrUnauthorized, "token_revoked")
+				return
+			}
+		}
+		ctx := context.WithValue(r.Context(), ctxUserIDKey, c.UserID)
+		ctx = context.WithValue(ctx, ctxRoleKey, c.Role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// OptionalAuth attaches the caller's user id when a valid bearer token is
+// sent, and otherwise lets the request through anonymously.
+func OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header.Get("Authorization")
+		if !strings.HasPrefix(h, "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		c, err := jwt.ParseClaims(strings.TrimSpace(h[7:]))
+		if err != nil || c.UserID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if revocations != nil {
+			if revoked, err := revocations.IsRevoked(r.Context(), c.ID, c.SessionID, c.UserID, c.IssuedAt); err == nil && revoked {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxUserIDKey, c.UserID)))
+	})
+}
+
+// InternalOnly admits service-to-service calls whose X-Internal-Token matches
+// INTERNAL_TOKEN. With no INTERNAL_TOKEN configured every request is refused.
+func InternalOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := os.Getenv("INTERNAL_TOKEN")
+		got := r.Header.Get("X-Internal-Token")
+		if want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			WriteError(w, http.StatusForbidden, ErrForbidden, "internal token required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func UserFromCtx(r *http.Request) (string, error) {
 	uid, _ := r.Context().Value(ctxUserIDKey).(string)
 	if uid == "" {
@@ -2,18 +2,34 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
+	"users-service/internal/account"
+	"users-service/internal/audit"
+	"users-service/internal/auth"
+	"users-service/internal/content"
+	"users-service/internal/discovery"
+	"users-service/internal/events"
+	"users-service/internal/handle"
 	"users-service/internal/interest"
+	"users-service/internal/kafka"
+	"users-service/internal/mail"
 	"users-service/internal/migrate"
 	"users-service/internal/profile"
+	"users-service/internal/ratelimit"
+	"users-service/internal/report"
+	"users-service/internal/search"
 	"users-service/internal/shared/db"
 	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/jwt"
+	"users-service/internal/shared/revoke"
 	"users-service/internal/social"
+	"users-service/internal/suggest"
 	"users-service/internal/user"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -67,6 +83,36 @@ func initOTEL(ctx context.Context) func(context.Context) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		store := db.ConnectFromEnv()
+		if err := migrate.Command(os.Stdout, os.Args[2:], migrate.Shards(store)); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "jwt-rotate" {
+		alg := "EdDSA"
+		if len(os.Args) > 2 {
+			alg = os.Args[2]
+		}
+		kid, err := jwt.GenerateKey(os.Getenv("JWT_KEYS_DIR"), alg)
+		if err != nil {
+			log.Fatalf("jwt-rotate: %v", err)
+		}
+		fmt.Println(kid)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "grant-role" {
+		// Bootstraps the first admin; later changes go through the admin API.
+		if len(os.Args) != 4 || !httpx.HasRole(os.Args[3], httpx.RoleUser) {
+			log.Fatalf("usage: grant-role <user_id> user|moderator|admin")
+		}
+		if err := user.NewRepository(db.OpenFromEnv()).SetRole(os.Args[2], os.Args[3]); err != nil {
+			log.Fatalf("grant-role: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 	shutdown := initOTEL(ctx)
 	defer func() {
@@ -75,73 +121,245 @@ func main() {
 		_ = shutdown(c)
 	}()
 
-	store := db.OpenFromEnv()
+	keysEvery, err := time.ParseDuration(os.Getenv("JWT_KEYS_RELOAD"))
+	if err != nil || keysEvery <= 0 {
+		keysEvery = time.Minute
+	}
+	if err := jwt.LoadKeys(keysEvery); err != nil {
+		log.Fatalf("jwt keys: %v", err)
+	}
+	go jwt.WatchKeys(ctx, keysEvery)
+
+	store := db.ConnectFromEnv()
 	_ = store.Base.Use(tracing.NewPlugin())
 
 	if os.Getenv("AUTO_MIGRATE") == "true" {
-		n := atoiDef(os.Getenv("NUM_SHARDS"), 1)
-		for i := 0; i < n; i++ {
-			if err := migrate.AutoMigrateAll(store, i); err != nil {
-				log.Fatalf("migrate shard %d: %v", i, err)
+		for _, t := range migrate.Shards(store) {
+			if err := migrate.Up(t.DB); err != nil {
+				log.Fatalf("migrate %s: %v", t.Name, err)
 			}
 		}
 	}
+	if err := store.SeedShardMap(); err != nil {
+		log.Fatalf("shard map: %v", err)
+	}
+	if err := store.LoadShardMap(); err != nil {
+		log.Fatalf("shard map: %v", err)
+	}
+	mapEvery, err := time.ParseDuration(os.Getenv("SHARD_MAP_REFRESH"))
+	if err != nil || mapEvery <= 0 {
+		mapEvery = 5 * time.Second
+	}
+	go store.WatchShardMap(ctx, mapEvery)
+	lagEvery, err := time.ParseDuration(os.Getenv("REPLICA_LAG_CHECK"))
+	if err != nil || lagEvery <= 0 {
+		lagEvery = time.Second
+	}
+	go store.WatchReplicaLag(ctx, lagEvery)
+	httpx.TrackWrites(store)
+
+	revoked := revoke.OpenFromEnv()
+	defer revoked.Close()
+	httpx.UseRevocations(revoked)
+
+	auditSvc := audit.NewService(audit.NewRepository(store))
+	httpx.UseAudit(auditSvc)
+	go func() {
+		if err := audit.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), auditSvc); err != nil {
+			log.Printf("audit watch: %v", err)
+		}
+	}()
+
+	authRepo := auth.NewRepository(store)
+	authSvc := auth.NewService(authRepo, revoked)
+
+	pub, err := events.NewPublisher(os.Getenv("KAFKA_BOOTSTRAP_SERVERS"))
+	if err != nil {
+		log.Fatalf("kafka writer: %v", err)
+	}
+	defer pub.Close()
 
 	userRepo := user.NewRepository(store)
-	userSvc := user.NewService(userRepo)
+	cards := user.OpenCardCacheFromEnv()
+	defer cards.Close()
+	limits := ratelimit.OpenFromEnv()
+	defer limits.Close()
+	userSvc := user.NewService(userRepo, authSvc, mail.NewFromEnv(), pub, cards, user.NewLockoutFromEnv(limits))
+
+	socialRepo := social.NewRepository(store, userRepo)
 
 	profileRepo := profile.NewRepository(store)
-	profileSvc := profile.NewService(profileRepo)
+	profileSvc := profile.NewService(profileRepo, pub, socialRepo, userSvc)
 
 	interestRepo := interest.NewRepository(store)
-	interestSvc := interest.NewService(interestRepo)
+	interestSvc := interest.NewService(interestRepo, profileSvc)
+	catalogEvery, err := time.ParseDuration(os.Getenv("CATALOG_SYNC_INTERVAL"))
+	if err != nil || catalogEvery <= 0 {
+		catalogEvery = 10 * time.Minute
+	}
+	go interest.SyncCatalogLoop(ctx, interestRepo, store, catalogEvery)
 
-	socialRepo := social.NewRepository(store, userRepo)
-	socialSvc := social.NewService(socialRepo)
+	if os.Getenv("BACKFILL_FOLLOWERS") == "true" {
+		for _, id := range store.PhysicalIDs() {
+			n, err := socialRepo.SyncFollowers(id)
+			if err != nil {
+				log.Fatalf("backfill followers shard %d: %v", id, err)
+			}
+			log.Printf("backfill followers shard %d: %d rows", id, n)
+		}
+	}
+	go social.NewWorker(socialRepo, store).Run(ctx)
+	blockEvents, err := kafka.NewWriter(os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), social.BlocksTopic)
+	if err != nil {
+		log.Fatalf("kafka writer: %v", err)
+	}
+	defer blockEvents.Close()
+	socialSvc := social.NewService(socialRepo, blockEvents, pub, profileSvc)
+
+	searchSvc := search.NewService(search.NewRepository(store), socialSvc)
+	discoverySvc := discovery.NewService(discovery.NewRepository(store), socialSvc)
+
+	suggestCache := suggest.OpenCacheFromEnv()
+	defer suggestCache.Close()
+	suggestSvc := suggest.NewService(suggest.NewRepository(store), suggestCache, socialSvc, discoverySvc)
+	go func() {
+		if err := suggest.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), suggestSvc); err != nil {
+			log.Printf("suggest watch: %v", err)
+		}
+	}()
+
+	handleSvc := handle.NewService(handle.NewRepository(store), userSvc)
+	go handle.Sweep(ctx, handleSvc)
+
+	accountSvc := account.NewService(account.NewRepository(store), userSvc, socialSvc, handleSvc, pub, account.NewSources())
+	go func() {
+		if err := account.Watch(ctx, os.Getenv("KAFKA_BOOTSTRAP_SERVERS"), accountSvc); err != nil {
+			log.Printf("account watch: %v", err)
+		}
+	}()
+	go account.Sweep(ctx, accountSvc)
+
+	reportRepo := report.NewRepository(store, atoiDef(os.Getenv("REPORTS_SHARD"), 0))
+	reportSvc := report.NewService(reportRepo, content.NewClient(), userSvc)
 
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("GET /.well-known/jwks.json", jwt.JWKSHandler())
 
-	uh := user.NewHandler(userSvc)
+	uh := user.NewHandler(userSvc, authSvc)
 	mux.Handle("POST /users", httpx.Wrap(uh.Register))
 	mux.Handle("POST /users/login", httpx.Wrap(uh.Login))
 	mux.Handle("GET /users/{user_id}", httpx.Wrap(uh.GetByID))
+	hh := handle.NewHandler(handleSvc)
+	mux.Handle("GET /users/by-handle/{handle}", httpx.Wrap(hh.ByHandle))
+
+	ah := auth.NewHandler(authSvc)
+	mux.Handle("POST /auth/login", httpx.Wrap(uh.Login))
+	mux.Handle("POST /auth/refresh", httpx.Wrap(ah.Refresh))
+	mux.Handle("POST /auth/password/reset", httpx.Wrap(uh.RequestPasswordReset))
+	mux.Handle("POST /auth/password/reset/confirm", httpx.Wrap(uh.ConfirmPasswordReset))
+	mux.Handle("POST /auth/unlock", httpx.Wrap(uh.RequestUnlock))
+	mux.Handle("POST /auth/unlock/confirm", httpx.Wrap(uh.ConfirmUnlock))
+	mux.Handle("POST /auth/email/verify", httpx.Wrap(uh.VerifyEmail))
+	mux.Handle("POST /auth/email/change/confirm", httpx.Wrap(uh.ConfirmEmailChange))
 
 	protect := func(pattern string, h http.Handler) {
 		mux.Handle(pattern, httpx.AuthMiddleware(h))
 	}
 
+	protect("POST /auth/logout", httpx.Wrap(ah.Logout))
+	protect("GET /sessions", httpx.Wrap(ah.ListSessions))
+	protect("DELETE /sessions/{id}", httpx.Wrap(ah.EndSession))
+
 	protect("GET /whoami", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		uid, sh, err := httpx.UserFromCtx(r)
 		if err != nil {
 			httpx.WriteJSON(w, map[string]any{"error": err.Error()}, http.StatusUnauthorized)
 			return
 		}
-		httpx.WriteJSON(w, map[string]any{"user_id": uid, "shard_id": sh}, http.StatusOK)
+		httpx.WriteJSON(w, map[string]any{"user_id": uid, "shard_id": sh, "role": httpx.RoleFromCtx(r)}, http.StatusOK)
 	}))
 
 	protect("GET /users", httpx.Wrap(uh.ListMine))
+	protect("POST /users:batch", httpx.Wrap(uh.Batch))
+	protect("PUT /handle", httpx.Wrap(hh.Claim))
+	protect("DELETE /handle", httpx.Wrap(hh.Release))
+
+	ach := account.NewHandler(accountSvc)
+	protect("POST /account/export", httpx.Wrap(ach.Export))
+	protect("GET /account/export/{request_id}", httpx.Wrap(ach.Download))
+	protect("DELETE /account", httpx.Wrap(ach.Delete))
+	protect("POST /account/email/verification", httpx.Wrap(uh.ResendVerification))
+	protect("PUT /account/email", httpx.Wrap(uh.RequestEmailChange))
+	mux.Handle("GET /account/requests/{request_id}", httpx.Wrap(ach.Status))
+	mux.Handle("PUT /internal/account/requests/{request_id}/steps/{service}", httpx.InternalOnly(httpx.Wrap(ach.Report)))
 
 	ph := profile.NewHandler(profileSvc)
 	protect("PUT /profile", httpx.Wrap(ph.Upsert))
 	protect("GET /profile/{user_id}", httpx.Wrap(ph.GetPublic))
+	protect("GET /profile/privacy", httpx.Wrap(ph.GetPrivacy))
+	protect("PUT /profile/privacy", httpx.Wrap(ph.UpdatePrivacy))
 
 	ih := interest.NewHandler(interestSvc)
 	protect("POST /interests", httpx.Wrap(ih.Create))
 	protect("POST /interests/{interest_id}", httpx.Wrap(ih.Attach))
 	protect("DELETE /interests/{interest_id}", httpx.Wrap(ih.Detach))
 	protect("GET /interests", httpx.Wrap(ih.ListMine))
+	protect("GET /catalog/interests", httpx.Wrap(ih.Catalog))
+	protect("GET /catalog/cities", httpx.Wrap(ih.Cities))
+
+	dh := discovery.NewHandler(discoverySvc)
+	protect("GET /discover/interests", httpx.Wrap(dh.Interests))
+	protect("GET /discover/city", httpx.Wrap(dh.City))
+
+	sgh := suggest.NewHandler(suggestSvc)
+	protect("GET /suggestions/people", httpx.Wrap(sgh.People))
 
 	sh := social.NewHandler(socialSvc)
 	protect("POST /follow/{target_id}", httpx.Wrap(sh.Follow))
 	protect("DELETE /follow/{target_id}", httpx.Wrap(sh.Unfollow))
 	protect("GET /follow", httpx.Wrap(sh.ListFollowing))
+	protect("GET /follow/counts", httpx.Wrap(sh.FollowCounts))
+	protect("GET /followers", httpx.Wrap(sh.ListFollowers))
 	protect("POST /friends/{friend_id}", httpx.Wrap(sh.Befriend))
 	protect("DELETE /friends/{friend_id}", httpx.Wrap(sh.Unfriend))
 	protect("GET /friends", httpx.Wrap(sh.ListFriends))
+	protect("POST /friendrequests", httpx.Wrap(sh.SendFriendRequest))
+	protect("GET /friendrequests", httpx.Wrap(sh.ListFriendRequests))
+	protect("PATCH /friendrequests/{request_id}", httpx.Wrap(sh.UpdateFriendRequest))
+	protect("DELETE /friendrequests/{request_id}", httpx.Wrap(sh.CancelFriendRequest))
+	protect("POST /block", httpx.Wrap(sh.Block))
+	protect("DELETE /block", httpx.Wrap(sh.Unblock))
+	protect("GET /block", httpx.Wrap(sh.ListBlocked))
+	mux.Handle("GET /internal/blocks/{user_id}", httpx.InternalOnly(httpx.Wrap(sh.BlockSet)))
+	mux.Handle("GET /internal/blocks/{user_id}/{other_id}", httpx.InternalOnly(httpx.Wrap(sh.BlockCheck)))
 	protect("POST /relationships", httpx.Wrap(sh.CreateRelationship))
 	protect("DELETE /relationships", httpx.Wrap(sh.DeleteRelationship))
 	protect("GET /relationships", httpx.Wrap(sh.ListRelationships))
+	protect("GET /relationships/{other_id}/status", httpx.Wrap(sh.RelationStatus))
+
+	srh := search.NewHandler(searchSvc)
+	protect("GET /search/users", httpx.Wrap(srh.Users))
+
+	rh := report.NewHandler(reportSvc)
+	protect("POST /report", httpx.Wrap(rh.Create))
+
+	moderator := func(pattern string, h http.Handler) {
+		protect(pattern, httpx.RequireRole(httpx.RoleModerator, h))
+	}
+	admin := func(pattern string, h http.Handler) {
+		protect(pattern, httpx.RequireRole(httpx.RoleAdmin, h))
+	}
+	moderator("GET /admin/reports", httpx.Wrap(rh.List))
+	moderator("GET /admin/reports/{report_id}", httpx.Wrap(rh.Get))
+	moderator("PATCH /admin/reports/{report_id}", httpx.Wrap(rh.Review))
+	moderator("POST /admin/users/{user_id}/suspend", httpx.Wrap(uh.Suspend))
+	moderator("DELETE /admin/users/{user_id}/suspend", httpx.Wrap(uh.Unsuspend))
+	admin("GET /admin/users/{user_id}/logins", httpx.Wrap(uh.LoginAttempts))
+	admin("PUT /admin/users/{user_id}/role", httpx.Wrap(uh.SetRole))
+	admin("POST /admin/cities", httpx.Wrap(ih.CreateCity))
+	admin("GET /admin/audit", httpx.Wrap(audit.NewHandler(auditSvc).List))
+	admin("POST /admin/account-requests/{request_id}/retry", httpx.Wrap(ach.Retry))
 
 	addr := os.Getenv("APP_PORT")
 	if addr == "" {
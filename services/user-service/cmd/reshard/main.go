@@ -0,0 +1,60 @@
+// Command reshard inspects the logical shard map and moves logical shards
+// between physical databases. It reads the same SHARDS_JSON / LOGICAL_SHARDS
+// environment as the service:
+//
+//	reshard -list
+//	reshard -logical 7 -to 2 [-cleanup] [-settle 20s]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"users-service/internal/interest"
+	"users-service/internal/reshard"
+	"users-service/internal/shared/db"
+)
+
+func main() {
+	list := flag.Bool("list", false, "print the shard map and exit")
+	logical := flag.Int("logical", -1, "logical shard to move")
+	to := flag.Int("to", -1, "physical shard to move it to")
+	cleanup := flag.Bool("cleanup", false, "delete the moved rows from the old database afterwards")
+	settle := flag.Duration("settle", 0, "wait after each map change (default 3x SHARD_MAP_REFRESH)")
+	flag.Parse()
+
+	store := db.OpenFromEnv()
+
+	if *list {
+		for l := 0; l < store.LogicalShards(); l++ {
+			fmt.Printf("%d\t%d\n", l, store.Physical(l))
+		}
+		fmt.Printf("version %d\n", store.MapVersion())
+		return
+	}
+	if *logical < 0 || *logical >= store.LogicalShards() || *to < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *settle <= 0 {
+		every, err := time.ParseDuration(os.Getenv("SHARD_MAP_REFRESH"))
+		if err != nil || every <= 0 {
+			every = 5 * time.Second
+		}
+		*settle = 3 * every
+	}
+	home, _ := strconv.Atoi(os.Getenv("REPORTS_SHARD"))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	m := &reshard.Mover{Store: store, Settle: *settle, ReportsShard: home, Catalog: interest.NewRepository(store)}
+	if err := m.Move(ctx, *logical, *to, *cleanup); err != nil {
+		log.Fatalf("reshard: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Package account runs data exports and account deletions. Each is a Request
+// with one Step per service that holds data about the user. Requests and
+// finished exports live on the control shard, since a deletion empties the
+// user's own shard of them.
+package account
+
+import "time"
+
+const (
+	KindExport = "export"
+	KindDelete = "delete"
+
+	StatePending = "pending"
+	StateDone    = "done"
+	StateFailed  = "failed"
+)
+
+// The services taking part in each kind of request. Feed-service only
+// holds data derived from other services, so it has nothing to export.
+var (
+	exportSteps = []string{"user-service", "post-service", "feedback-service", "message-service", "notification-service", "media-service"}
+	deleteSteps = append(exportSteps[:len(exportSteps):len(exportSteps)], "feed-service")
+)
+
+type Request struct {
+	RequestID   string     `gorm:"primaryKey;size:32" json:"request_id"`
+	UserID      string     `gorm:"size:64;index" json:"user_id,omitempty"`
+	Kind        string     `gorm:"size:16" json:"kind"`
+	State       string     `gorm:"size:16" json:"state"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Steps       []Step     `gorm:"foreignKey:RequestID" json:"steps"`
+}
+
+func (Request) TableName() string { return "account_requests" }
+
+type Step struct {
+	RequestID string    `gorm:"primaryKey;size:32" json:"-"`
+	Service   string    `gorm:"primaryKey;size:32" json:"service"`
+	State     string    `gorm:"size:16" json:"state"`
+	Detail    string    `gorm:"size:255" json:"detail,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Step) TableName() string { return "account_steps" }
+
+// Archive is a finished export, kept until ExpiresAt.
+type Archive struct {
+	RequestID string `gorm:"primaryKey;size:32"`
+	Data      []byte
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (Archive) TableName() string { return "account_archives" }
+
+type DeleteReq struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// StepReq is how a service reports the outcome of its step.
+type StepReq struct {
+	State  string `json:"state" validate:"required,oneof=done failed"`
+	Detail string `json:"detail"`
+}
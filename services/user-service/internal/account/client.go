@@ -0,0 +1,71 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sources fetches what other services hold about a user from their
+// /internal/users/{user_id}/export endpoints. Calls carry INTERNAL_TOKEN.
+type Sources struct {
+	urls  map[string]string
+	token string
+	hc    *http.Client
+}
+
+func NewSources() *Sources {
+	return &Sources{
+		urls: map[string]string{
+			"post-service":         strings.TrimRight(getenv("POST_SERVICE_URL", "http://post-service:8082"), "/"),
+			"feedback-service":     strings.TrimRight(getenv("FEEDBACK_SERVICE_URL", "http://feedback-service:8084"), "/"),
+			"message-service":      strings.TrimRight(getenv("MESSAGE_SERVICE_URL", "http://message-service:8085"), "/"),
+			"notification-service": strings.TrimRight(getenv("NOTIFICATION_SERVICE_URL", "http://notification-service:8086"), "/"),
+			"media-service":        strings.TrimRight(getenv("MEDIA_SERVICE_URL", "http://media-service:8088"), "/"),
+		},
+		token: os.Getenv("INTERNAL_TOKEN"),
+		hc:    &http.Client{Timeout: time.Minute},
+	}
+}
+
+func getenv(k, d string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return d
+}
+
+// Fetch returns the service's export of uid as raw JSON.
+func (s *Sources) Fetch(ctx context.Context, service, uid string) (json.RawMessage, error) {
+	base, ok := s.urls[service]
+	if !ok {
+		return nil, fmt.Errorf("no export source for %s", service)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/internal/users/"+url.PathEscape(uid)+"/export", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", s.token)
+	resp, err := s.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("export from %s: status %d", service, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(b) {
+		return nil, fmt.Errorf("export from %s: invalid json", service)
+	}
+	return b, nil
+}
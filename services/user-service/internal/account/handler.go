@@ -0,0 +1,100 @@
+package account
+
+import (
+	"net/http"
+
+	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/validate"
+)
+
+type Handler struct{ svc Service }
+
+func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	req, err := h.svc.Export(uid)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, req, http.StatusAccepted)
+	return nil
+}
+
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	id := r.PathValue("request_id")
+	data, err := h.svc.Archive(uid, id)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="export-`+id+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+	return nil
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[DeleteReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	req, err := h.svc.Delete(uid, in.Password)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, req, http.StatusAccepted)
+	return nil
+}
+
+// Status is public: the request id is unguessable, and a deleting user no
+// longer has a token to present.
+// Status needs no token, since a deleted account has none, so it does not
+// say whose request it is.
+func (h *Handler) Status(w http.ResponseWriter, r *http.Request) error {
+	req, err := h.svc.Status(r.PathValue("request_id"))
+	if err != nil {
+		return err
+	}
+	req.UserID = ""
+	httpx.WriteJSON(w, req, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) error {
+	in, err := httpx.Decode[StepReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	if err := h.svc.Report(r.PathValue("request_id"), r.PathValue("service"), in.State, in.Detail); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Retry(w http.ResponseWriter, r *http.Request) error {
+	req, err := h.svc.Retry(r.PathValue("request_id"))
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, req, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,240 @@
+package account
+
+import (
+	"errors"
+	"time"
+
+	"users-service/internal/shared/db"
+	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/shard"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	Create(req *Request) error
+	Get(requestID string) (*Request, error)
+	// Active returns uid's unfinished request of kind, or nil if there is none.
+	Active(uid, kind string) (*Request, error)
+	// SetStep records a service's outcome and settles the request once no
+	// step is pending: done if every step is, failed otherwise.
+	SetStep(requestID, service, state, detail string) error
+	// Reopen puts the request and its failed steps back to pending and
+	// returns the steps it reopened.
+	Reopen(requestID string) ([]string, error)
+
+	PutArchive(a *Archive) error
+	GetArchive(requestID string) (*Archive, error)
+	DropArchives(before time.Time) (int64, error)
+
+	// Dump reads everything user-service holds about uid, by table, leaving
+	// out credentials.
+	Dump(uid string) (map[string][]map[string]any, error)
+	// Erase deletes uid's rows from their shard, the user row last.
+	Erase(uid string) error
+}
+
+type repo struct{ store *db.Store }
+
+func NewRepository(s *db.Store) Repository { return &repo{store: s} }
+
+func (r *repo) control() *gorm.DB { return r.store.WritePhysical(r.store.ControlID()) }
+
+func (r *repo) Create(req *Request) error { return r.control().Create(req).Error }
+
+func (r *repo) Get(requestID string) (*Request, error) {
+	var req Request
+	err := r.control().Preload("Steps", func(tx *gorm.DB) *gorm.DB { return tx.Order("service") }).
+		First(&req, "request_id = ?", requestID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httpx.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *repo) Active(uid, kind string) (*Request, error) {
+	var req Request
+	err := r.control().Where("user_id = ? AND kind = ? AND state = ?", uid, kind, StatePending).
+		Order("created_at DESC").First(&req).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(req.RequestID)
+}
+
+func (r *repo) SetStep(requestID, service, state, detail string) error {
+	if len(detail) > 255 {
+		detail = detail[:255]
+	}
+	return r.control().Transaction(func(tx *gorm.DB) error {
+		var req Request
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&req, "request_id = ?", requestID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return httpx.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		res := tx.Model(&Step{}).Where("request_id = ? AND service = ?", requestID, service).
+			Updates(map[string]any{"state": state, "detail": detail, "updated_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return httpx.ErrNotFound
+		}
+		var steps []Step
+		if err := tx.Where("request_id = ?", requestID).Find(&steps).Error; err != nil {
+			return err
+		}
+		settled := StateDone
+		for _, s := range steps {
+			if s.State == StatePending {
+				return tx.Model(&req).Updates(map[string]any{"state": StatePending, "updated_at": now}).Error
+			}
+			if s.State == StateFailed {
+				settled = StateFailed
+			}
+		}
+		return tx.Model(&req).Updates(map[string]any{"state": settled, "updated_at": now, "completed_at": now}).Error
+	})
+}
+
+func (r *repo) Reopen(requestID string) ([]string, error) {
+	var reopened []string
+	err := r.control().Transaction(func(tx *gorm.DB) error {
+		var steps []Step
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("request_id = ? AND state = ?", requestID, StateFailed).Find(&steps).Error
+		if err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			return nil
+		}
+		now := time.Now().UTC()
+		for _, s := range steps {
+			reopened = append(reopened, s.Service)
+		}
+		if err := tx.Model(&Step{}).Where("request_id = ? AND service IN ?", requestID, reopened).
+			Updates(map[string]any{"state": StatePending, "detail": "", "updated_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Request{}).Where("request_id = ?", requestID).
+			Updates(map[string]any{"state": StatePending, "updated_at": now, "completed_at": nil}).Error
+	})
+	return reopened, err
+}
+
+func (r *repo) PutArchive(a *Archive) error {
+	return r.control().Clauses(clause.OnConflict{UpdateAll: true}).Create(a).Error
+}
+
+func (r *repo) GetArchive(requestID string) (*Archive, error) {
+	var a Archive
+	err := r.control().First(&a, "request_id = ? AND expires_at > ?", requestID, time.Now()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httpx.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *repo) DropArchives(before time.Time) (int64, error) {
+	res := r.control().Delete(&Archive{}, "expires_at <= ?", before)
+	return res.RowsAffected, res.Error
+}
+
+// userTables are the tables on a user's shard that hold rows about them,
+// with the columns naming the user. The users row goes last. Friend outbox
+// rows are left to the worker, which still has to apply them to the other
+// side.
+var userTables = []struct {
+	name string
+	cols []string
+}{
+	{"profiles", []string{"user_id"}},
+	{"privacy_settings", []string{"user_id"}},
+	{"interest_users", []string{"user_id"}},
+	{"follows", []string{"user_id"}},
+	{"followers", []string{"user_id"}},
+	{"friends", []string{"user_id"}},
+	{"friend_removals", []string{"user_id", "friend_id"}},
+	{"relationships", []string{"user_id"}},
+	{"blocked_bies", []string{"user_id"}},
+	{"friend_requests", []string{"from_user_id", "to_user_id"}},
+	{"refresh_tokens", []string{"user_id"}},
+	{"one_time_tokens", []string{"user_id"}},
+	{"sessions", []string{"user_id"}},
+	{"login_attempts", []string{"user_id"}},
+	{"users", []string{"user_id"}},
+}
+
+// secret columns and tables never leave the service, not even to their owner.
+var (
+	secretTables  = map[string]bool{"refresh_tokens": true, "one_time_tokens": true}
+	secretColumns = []string{"id", "pass_hash", "token_hash"}
+)
+
+func where(tx *gorm.DB, cols []string, uid string) *gorm.DB {
+	cond := tx.Where(cols[0]+" = ?", uid)
+	for _, c := range cols[1:] {
+		cond = cond.Or(c+" = ?", uid)
+	}
+	return tx.Where(cond)
+}
+
+func (r *repo) Dump(uid string) (map[string][]map[string]any, error) {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return nil, errors.New("bad user_id")
+	}
+	out := make(map[string][]map[string]any)
+	for _, t := range userTables {
+		if secretTables[t.name] {
+			continue
+		}
+		var rows []map[string]any
+		tx := r.store.Write(sh).Table(t.name)
+		if err := where(tx, t.cols, uid).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			for _, c := range secretColumns {
+				delete(row, c)
+			}
+		}
+		out[t.name] = rows
+	}
+	return out, nil
+}
+
+func (r *repo) Erase(uid string) error {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return errors.New("bad user_id")
+	}
+	// The email directory lives on the control shard, outside the shard's
+	// transaction; deleting its rows twice on a retry is harmless.
+	if err := r.store.WritePhysical(r.store.ControlID()).Table("email_directory").Where("user_id = ?", uid).Delete(nil).Error; err != nil {
+		return err
+	}
+	return r.store.Write(sh).Transaction(func(tx *gorm.DB) error {
+		for _, t := range userTables {
+			if err := where(tx.Table(t.name), t.cols, uid).Delete(nil).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
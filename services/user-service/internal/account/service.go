@@ -0,0 +1,298 @@
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"users-service/internal/events"
+	"users-service/internal/shared/httpx"
+)
+
+var ErrNotReady = fmt.Errorf("%w: export is not ready", httpx.ErrConflict)
+
+// Users is the part of user.Service that account needs.
+type Users interface {
+	CheckPassword(uid, password string) error
+	Suspend(uid string) error
+	ForgetCard(uid string)
+}
+
+type Graph interface {
+	// Sever ends every follow, friendship, block and pending friend request
+	// involving uid, on both sides.
+	Sever(uid string) error
+}
+
+type Handles interface {
+	Release(uid string) error
+}
+
+type Publisher interface {
+	Publish(topic, key string, v any)
+}
+
+type Fetcher interface {
+	Fetch(ctx context.Context, service, uid string) (json.RawMessage, error)
+}
+
+type Service interface {
+	// Export starts building an archive of everything held about uid. A
+	// request already under way is returned instead of starting another.
+	Export(uid string) (*Request, error)
+	// Delete locks uid out and asks every service to erase their data.
+	Delete(uid, password string) (*Request, error)
+	Status(requestID string) (*Request, error)
+	// Archive returns the zip built by one of uid's exports.
+	Archive(uid, requestID string) ([]byte, error)
+	// Report records the outcome of a service's step.
+	Report(requestID, service, state, detail string) error
+	// Retry runs the failed steps of a request again.
+	Retry(requestID string) (*Request, error)
+	// Erase removes what user-service itself holds about uid. It is
+	// user-service's step of a deletion and safe to run more than once.
+	Erase(uid string) error
+	// DropExpired removes archives past their expiry.
+	DropExpired() (int64, error)
+}
+
+type service struct {
+	repo    Repository
+	users   Users
+	graph   Graph
+	handles Handles
+	pub     Publisher
+	sources Fetcher
+	keepFor time.Duration
+}
+
+func NewService(r Repository, u Users, g Graph, h Handles, p Publisher, src Fetcher) Service {
+	return &service{
+		repo:    r,
+		users:   u,
+		graph:   g,
+		handles: h,
+		pub:     p,
+		sources: src,
+		keepFor: durationEnv("ACCOUNT_EXPORT_TTL", 7*24*time.Hour),
+	}
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (s *service) open(uid, kind string, services []string) (*Request, bool, error) {
+	if req, err := s.repo.Active(uid, kind); err != nil || req != nil {
+		return req, false, err
+	}
+	now := time.Now().UTC()
+	req := &Request{RequestID: newID(), UserID: uid, Kind: kind, State: StatePending, CreatedAt: now, UpdatedAt: now}
+	for _, svc := range services {
+		req.Steps = append(req.Steps, Step{RequestID: req.RequestID, Service: svc, State: StatePending, UpdatedAt: now})
+	}
+	if err := s.repo.Create(req); err != nil {
+		return nil, false, err
+	}
+	return req, true, nil
+}
+
+func (s *service) Export(uid string) (*Request, error) {
+	req, created, err := s.open(uid, KindExport, exportSteps)
+	if err != nil || !created {
+		return req, err
+	}
+	go s.build(req.RequestID, uid, exportSteps)
+	return req, nil
+}
+
+// build collects each service's part of an export. The archive is stored
+// before the last step is reported, so a request seen as done can always be
+// downloaded. A failed part leaves the request failed and without archive.
+func (s *service) build(requestID, uid string, services []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	parts := make(map[string][]byte)
+	failed := false
+	for _, svc := range services {
+		b, err := s.part(ctx, svc, uid)
+		if err != nil {
+			log.Printf("account export %s: %s: %v", requestID, svc, err)
+			s.report(requestID, svc, StateFailed, err.Error())
+			failed = true
+			continue
+		}
+		parts[svc] = b
+	}
+	if failed {
+		for svc := range parts {
+			s.report(requestID, svc, StateDone, "")
+		}
+		return
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, svc := range services {
+		f, err := zw.Create(svc + ".json")
+		if err == nil {
+			_, err = f.Write(parts[svc])
+		}
+		if err != nil {
+			s.report(requestID, svc, StateFailed, err.Error())
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("account export %s: %v", requestID, err)
+		return
+	}
+	err := s.repo.PutArchive(&Archive{RequestID: requestID, Data: buf.Bytes(), ExpiresAt: time.Now().Add(s.keepFor)})
+	if err != nil {
+		log.Printf("account export %s: store archive: %v", requestID, err)
+		return
+	}
+	for _, svc := range services {
+		s.report(requestID, svc, StateDone, "")
+	}
+}
+
+func (s *service) part(ctx context.Context, svc, uid string) ([]byte, error) {
+	if svc != "user-service" {
+		return s.sources.Fetch(ctx, svc, uid)
+	}
+	dump, err := s.repo.Dump(uid)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(dump, "", "  ")
+}
+
+func (s *service) report(requestID, svc, state, detail string) {
+	if err := s.repo.SetStep(requestID, svc, state, detail); err != nil {
+		log.Printf("account %s: report %s: %v", requestID, svc, err)
+	}
+}
+
+func (s *service) Delete(uid, password string) (*Request, error) {
+	if err := s.users.CheckPassword(uid, password); err != nil {
+		return nil, err
+	}
+	req, _, err := s.open(uid, KindDelete, deleteSteps)
+	if err != nil {
+		return nil, err
+	}
+	// Suspending revokes every token and keeps the user out while the
+	// services work through the request.
+	if err := s.users.Suspend(uid); err != nil {
+		return nil, err
+	}
+	s.announce(req.RequestID, uid)
+	return req, nil
+}
+
+func (s *service) announce(requestID, uid string) {
+	s.pub.Publish(events.TopicUserDeleted, uid, events.UserDeleted{
+		Meta: events.NewMeta(events.TopicUserDeleted), UserID: uid, RequestID: requestID,
+	})
+}
+
+func (s *service) Status(requestID string) (*Request, error) { return s.repo.Get(requestID) }
+
+func (s *service) Archive(uid, requestID string) ([]byte, error) {
+	req, err := s.repo.Get(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserID != uid || req.Kind != KindExport {
+		return nil, httpx.ErrNotFound
+	}
+	if req.State != StateDone {
+		return nil, ErrNotReady
+	}
+	a, err := s.repo.GetArchive(requestID)
+	if err != nil {
+		return nil, err
+	}
+	return a.Data, nil
+}
+
+func (s *service) Report(requestID, service, state, detail string) error {
+	return s.repo.SetStep(requestID, service, state, detail)
+}
+
+func (s *service) Retry(requestID string) (*Request, error) {
+	req, err := s.repo.Get(requestID)
+	if err != nil {
+		return nil, err
+	}
+	reopened, err := s.repo.Reopen(requestID)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Kind {
+	case KindExport:
+		if len(reopened) > 0 {
+			// Parts are not kept between attempts, so the whole export is rebuilt.
+			go s.build(requestID, req.UserID, exportSteps)
+		}
+	case KindDelete:
+		// Pending steps may have lost their event too. Erasing is idempotent
+		// everywhere, so services that already finished just report again.
+		if req.State != StateDone {
+			s.announce(requestID, req.UserID)
+		}
+	}
+	return s.repo.Get(requestID)
+}
+
+func (s *service) Erase(uid string) error {
+	if err := s.handles.Release(uid); err != nil && !errors.Is(err, httpx.ErrNotFound) {
+		return err
+	}
+	if err := s.graph.Sever(uid); err != nil {
+		return err
+	}
+	if err := s.repo.Erase(uid); err != nil {
+		return err
+	}
+	s.users.ForgetCard(uid)
+	return nil
+}
+
+func (s *service) DropExpired() (int64, error) { return s.repo.DropArchives(time.Now()) }
+
+// Sweep drops expired export archives every ACCOUNT_SWEEP_INTERVAL.
+func Sweep(ctx context.Context, svc Service) {
+	t := time.NewTicker(durationEnv("ACCOUNT_SWEEP_INTERVAL", time.Hour))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if n, err := svc.DropExpired(); err != nil {
+				log.Printf("account sweep: %v", err)
+			} else if n > 0 {
+				log.Printf("account sweep: dropped %d archives", n)
+			}
+		}
+	}
+}
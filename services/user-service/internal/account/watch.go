@@ -0,0 +1,52 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"users-service/internal/events"
+
+	kf "github.com/segmentio/kafka-go"
+)
+
+// Watch runs user-service's own step of every deletion announced on
+// events.TopicUserDeleted.
+func Watch(ctx context.Context, brokers string, svc Service) error {
+	if strings.TrimSpace(brokers) == "" {
+		brokers = "kafka:9092"
+	}
+	r := kf.NewReader(kf.ReaderConfig{
+		Brokers:     strings.Split(brokers, ","),
+		GroupID:     "user-service-account",
+		Topic:       events.TopicUserDeleted,
+		StartOffset: kf.FirstOffset,
+		MaxWait:     time.Second,
+	})
+	defer r.Close()
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		var ev events.UserDeleted
+		if err := json.Unmarshal(m.Value, &ev); err != nil || ev.UserID == "" {
+			log.Printf("users.deleted: bad payload: %v", err)
+		} else {
+			state, detail := StateDone, ""
+			if err := svc.Erase(ev.UserID); err != nil {
+				log.Printf("account erase %s: %v", ev.UserID, err)
+				state, detail = StateFailed, err.Error()
+			}
+			if err := svc.Report(ev.RequestID, "user-service", state, detail); err != nil {
+				// Leave the offset so the deletion is run again after a restart.
+				return err
+			}
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package audit keeps the log of requests made with an elevated role. The
+// log lives on the control shard; user-service writes its own entries and
+// reads the other services' from the admin.audit topic.
+package audit
+
+import "time"
+
+const Topic = "admin.audit"
+
+// Entry is the row and also the message other services publish on Topic.
+type Entry struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	EntryID   string    `gorm:"size:32;uniqueIndex" json:"entry_id"`
+	Service   string    `gorm:"size:32;index" json:"service"`
+	ActorID   string    `gorm:"size:64;index" json:"actor_id"`
+	Role      string    `gorm:"size:16" json:"role"`
+	Action    string    `gorm:"size:160" json:"action"`
+	Path      string    `gorm:"size:255" json:"path"`
+	Status    int       `json:"status"`
+	IP        string    `gorm:"size:64" json:"ip"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (Entry) TableName() string { return "admin_audit" }
+
+type Filter struct {
+	ActorID string
+	Service string
+}
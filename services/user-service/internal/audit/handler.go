@@ -0,0 +1,23 @@
+package audit
+
+import (
+	"net/http"
+
+	"users-service/internal/shared/httpx"
+)
+
+type Handler struct{ svc Service }
+
+func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) error {
+	f := Filter{ActorID: r.URL.Query().Get("actor_id"), Service: r.URL.Query().Get("service")}
+	limit := httpx.QueryInt(r, "limit", 50)
+	offset := httpx.QueryInt(r, "offset", 0)
+	items, err := h.svc.List(f, limit, offset)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	return nil
+}
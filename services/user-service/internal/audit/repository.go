@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+
+	"users-service/internal/shared/db"
+
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Create ignores entries it already has, as redelivered messages are.
+	Create(ctx context.Context, e *Entry) error
+	// List returns entries newest first.
+	List(f Filter, limit, offset int) ([]Entry, error)
+}
+
+type repo struct{ store *db.Store }
+
+func NewRepository(s *db.Store) Repository { return &repo{store: s} }
+
+func (r *repo) Create(ctx context.Context, e *Entry) error {
+	return r.store.WritePhysical(r.store.ControlID()).WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "entry_id"}}, DoNothing: true}).
+		Create(e).Error
+}
+
+func (r *repo) List(f Filter, limit, offset int) ([]Entry, error) {
+	q := r.store.UsePhysical(r.store.ControlID()).Model(&Entry{})
+	if f.ActorID != "" {
+		q = q.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Service != "" {
+		q = q.Where("service = ?", f.Service)
+	}
+	var out []Entry
+	err := q.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&out).Error
+	return out, err
+}
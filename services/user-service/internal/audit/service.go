@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"users-service/internal/shared/httpx"
+)
+
+type Service interface {
+	// Audit records one of user-service's own requests; it implements
+	// httpx.Auditor.
+	Audit(e httpx.AuditEntry)
+	List(f Filter, limit, offset int) ([]Entry, error)
+	// Record stores an entry published by another service.
+	Record(ctx context.Context, e *Entry) error
+}
+
+type service struct{ repo Repository }
+
+func NewService(r Repository) Service { return &service{repo: r} }
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (s *service) Audit(e httpx.AuditEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := s.repo.Create(ctx, &Entry{
+		EntryID: newID(), Service: "user-service", ActorID: e.ActorID, Role: e.Role,
+		Action: e.Action, Path: e.Path, Status: e.Status, IP: e.IP, CreatedAt: e.At,
+	})
+	if err != nil {
+		// Keep the entry somewhere rather than lose it.
+		log.Printf("audit: %s (%s) %s %s -> %d: %v", e.ActorID, e.Role, e.Action, e.Path, e.Status, err)
+	}
+}
+
+func (s *service) List(f Filter, limit, offset int) ([]Entry, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.List(f, limit, offset)
+}
+
+func (s *service) Record(ctx context.Context, e *Entry) error {
+	if e.EntryID == "" {
+		e.EntryID = newID()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	return s.repo.Create(ctx, e)
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	kf "github.com/segmentio/kafka-go"
+)
+
+// Watch stores the entries other services publish on Topic.
+func Watch(ctx context.Context, brokers string, svc Service) error {
+	if strings.TrimSpace(brokers) == "" {
+		brokers = "kafka:9092"
+	}
+	r := kf.NewReader(kf.ReaderConfig{
+		Brokers:     strings.Split(brokers, ","),
+		GroupID:     "user-service-audit",
+		Topic:       Topic,
+		StartOffset: kf.FirstOffset,
+		MaxWait:     time.Second,
+	})
+	defer r.Close()
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		var e Entry
+		if err := json.Unmarshal(m.Value, &e); err != nil {
+			log.Printf("audit event: bad payload: %v", err)
+		} else if err := svc.Record(ctx, &e); err != nil {
+			// Leave the offset so the entry is read again after a restart.
+			return err
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+	}
+}
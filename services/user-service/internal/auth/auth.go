@@ -0,0 +1,75 @@
+package auth
+
+import "time"
+
+// RefreshToken is stored on the owner's shard. Only the SHA-256 of the opaque
+// token is persisted. Tokens issued from one login share a FamilyID so that
+// reuse of a rotated token can revoke the whole chain.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    string     `gorm:"size:64;index"`
+	FamilyID  string     `gorm:"size:64;index"`
+	TokenHash string     `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"index"`
+	RevokedAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// Session is one login on one device, stored on the owner's shard. Its ID is
+// the FamilyID of the refresh tokens it rotates through and the sid claim of
+// its access tokens, so ending it stops both. LastSeenAt moves on every
+// refresh, i.e. at least once per access token lifetime while in use.
+type Session struct {
+	ID         string    `gorm:"primaryKey;size:64" json:"id"`
+	UserID     string    `gorm:"size:64;index" json:"-"`
+	DeviceName string    `gorm:"size:100" json:"device_name"`
+	IP         string    `gorm:"size:64" json:"ip"`
+	UserAgent  string    `gorm:"size:255" json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `gorm:"index" json:"last_seen_at"`
+	// Current marks the session of the token that asked.
+	Current bool `gorm:"-" json:"current"`
+}
+
+// Device describes where a login comes from.
+type Device struct {
+	Name      string
+	IP        string
+	UserAgent string
+}
+
+// OneTimeToken is a hashed, expiring, single-use secret bound to one purpose
+// (password reset, account unlock, ...). It lives on the owner's shard.
+type OneTimeToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    string     `gorm:"size:64;index"`
+	Purpose   string     `gorm:"size:32;index"`
+	Data      string     `gorm:"size:255"`
+	TokenHash string     `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"index"`
+	UsedAt    *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeAccountUnlock = "account_unlock"
+	PurposeEmailVerify   = "email_verify"
+	PurposeEmailChange   = "email_change"
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutReq struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"net/http"
+
+	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/validate"
+)
+
+type Handler struct{ svc Service }
+
+func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) error {
+	in, err := httpx.Decode[RefreshReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	pair, err := h.svc.Refresh(in.RefreshToken, httpx.ClientIP(r))
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, pair, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) error {
+	c, err := httpx.ClaimsFromCtx(r)
+	if err != nil {
+		return err
+	}
+	var in LogoutReq
+	if r.ContentLength != 0 {
+		if in, err = httpx.Decode[LogoutReq](r); err != nil {
+			return err
+		}
+	}
+	if err := h.svc.Logout(c, in); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) error {
+	c, err := httpx.ClaimsFromCtx(r)
+	if err != nil {
+		return err
+	}
+	items, err := h.svc.ListSessions(c.UserID, c.SessionID)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"items": items}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) EndSession(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.EndSession(uid, r.PathValue("id")); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+	return nil
+}
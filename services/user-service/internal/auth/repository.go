@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"users-service/internal/shared/db"
+	"users-service/internal/shared/shard"
+)
+
+type Repository interface {
+	Create(t *RefreshToken) error
+	GetByHash(shardID int, hash string) (*RefreshToken, error)
+	// Revoke marks one token revoked and reports whether this call did it,
+	// so concurrent refreshes of the same token cannot both succeed.
+	Revoke(shardID int, id uint) (bool, error)
+	RevokeFamily(shardID int, familyID string) error
+	RevokeAllForUser(uid string) error
+	// Role reads the user's current role from the writer, so a change shows
+	// up in the very next token.
+	Role(uid string) (string, error)
+
+	CreateSession(s *Session) error
+	// TouchSession moves a session's last-seen time and address. It reports
+	// false if the session is gone, i.e. was ended.
+	TouchSession(uid, id, ip string) (bool, error)
+	// ListSessions returns uid's sessions seen since, most recent first.
+	ListSessions(uid string, since time.Time) ([]Session, error)
+	// DeleteSession reports whether the session existed.
+	DeleteSession(uid, id string) (bool, error)
+	DeleteSessions(uid string) error
+
+	CreateOneTime(t *OneTimeToken) error
+	// ConsumeOneTime marks a live token used and returns it; it fails if the
+	// token is unknown, expired or already used.
+	ConsumeOneTime(shardID int, purpose, hash string) (*OneTimeToken, error)
+	ExpireOneTime(uid, purpose string) error
+}
+
+type repo struct{ store *db.Store }
+
+func NewRepository(s *db.Store) Repository { return &repo{store: s} }
+
+func (r *repo) Create(t *RefreshToken) error {
+	sh, _ := shard.Extract(t.UserID)
+	return r.store.Write(sh).Create(t).Error
+}
+
+func (r *repo) GetByHash(shardID int, hash string) (*RefreshToken, error) {
+	var t RefreshToken
+	// Read from the writer: a token rotated a moment ago must not look valid on a lagging replica.
+	if err := r.store.Write(shardID).Where("token_hash = ?", hash).First(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *repo) Revoke(shardID int, id uint) (bool, error) {
+	res := r.store.Write(shardID).Model(&RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *repo) RevokeFamily(shardID int, familyID string) error {
+	return r.store.Write(shardID).Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *repo) RevokeAllForUser(uid string) error {
+	sh, _ := shard.Extract(uid)
+	return r.store.Write(sh).Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", uid).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *repo) Role(uid string) (string, error) {
+	sh, _ := shard.Extract(uid)
+	var role string
+	err := r.store.Write(sh).Table("users").Select("role").Where("user_id = ?", uid).Row().Scan(&role)
+	return role, err
+}
+
+func (r *repo) CreateSession(s *Session) error {
+	sh, _ := shard.Extract(s.UserID)
+	return r.store.Write(sh).Create(s).Error
+}
+
+func (r *repo) TouchSession(uid, id, ip string) (bool, error) {
+	sh, _ := shard.Extract(uid)
+	res := r.store.Write(sh).Model(&Session{}).Where("id = ? AND user_id = ?", id, uid).
+		Updates(map[string]any{"last_seen_at": time.Now(), "ip": ip})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *repo) ListSessions(uid string, since time.Time) ([]Session, error) {
+	sh, _ := shard.Extract(uid)
+	var out []Session
+	err := r.store.Write(sh).
+		Where("user_id = ? AND last_seen_at > ?", uid, since).
+		Order("last_seen_at DESC").Find(&out).Error
+	return out, err
+}
+
+func (r *repo) DeleteSession(uid, id string) (bool, error) {
+	sh, _ := shard.Extract(uid)
+	res := r.store.Write(sh).Delete(&Session{}, "id = ? AND user_id = ?", id, uid)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *repo) DeleteSessions(uid string) error {
+	sh, _ := shard.Extract(uid)
+	return r.store.Write(sh).Delete(&Session{}, "user_id = ?", uid).Error
+}
+
+func (r *repo) CreateOneTime(t *OneTimeToken) error {
+	sh, _ := shard.Extract(t.UserID)
+	return r.store.Write(sh).Create(t).Error
+}
+
+var errTokenUnusable = errors.New("token invalid, expired or already used")
+
+func (r *repo) ConsumeOneTime(shardID int, purpose, hash string) (*OneTimeToken, error) {
+	now := time.Now()
+	res := r.store.Write(shardID).Model(&OneTimeToken{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return nil, errTokenUnusable
+	}
+	var t OneTimeToken
+	if err := r.store.Write(shardID).Where("token_hash = ?", hash).First(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *repo) ExpireOneTime(uid, purpose string) error {
+	sh, _ := shard.Extract(uid)
+	return r.store.Write(sh).Model(&OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", uid, purpose).
+		Update("used_at", time.Now()).Error
+}
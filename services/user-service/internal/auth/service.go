@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/jwt"
+	"users-service/internal/shared/revoke"
+	"users-service/internal/shared/shard"
+)
+
+type Service interface {
+	// Issue starts a new session, and with it a refresh token family, for a
+	// fresh login.
+	Issue(uid string, shardID int, d Device) (*TokenPair, error)
+	// Refresh rotates a refresh token; ip is recorded as the session's
+	// latest address.
+	Refresh(refreshToken, ip string) (*TokenPair, error)
+	// Logout ends the session of the calling token.
+	Logout(c jwt.Claims, in LogoutReq) error
+	// RevokeAll signs the user out everywhere: sessions, refresh tokens and
+	// live access tokens.
+	RevokeAll(uid string) error
+	// ExpireAccess rejects the user's live access tokens but keeps their
+	// sessions, so the next refresh picks up a change to the user.
+	ExpireAccess(uid string) error
+
+	// ListSessions returns the user's live sessions, marking the one with id
+	// current.
+	ListSessions(uid, current string) ([]Session, error)
+	// EndSession signs one of the user's sessions out.
+	EndSession(uid, id string) error
+
+	// IssueOneTime returns a fresh single-use token for purpose, voiding any
+	// earlier unused token of the same purpose.
+	IssueOneTime(uid, purpose string, ttl time.Duration) (string, error)
+	// ConsumeOneTime redeems a token and returns the user it belongs to.
+	ConsumeOneTime(tok, purpose string) (string, error)
+	// IssueOneTimeFor and ConsumeOneTimeFor are the same for a token that
+	// carries data, such as the address an email change waits on.
+	IssueOneTimeFor(uid, purpose, data string, ttl time.Duration) (string, error)
+	ConsumeOneTimeFor(tok, purpose string) (uid, data string, err error)
+}
+
+type service struct {
+	repo       Repository
+	revoked    *revoke.List
+	refreshTTL time.Duration
+}
+
+func NewService(r Repository, rl *revoke.List) Service {
+	ttl := 30 * 24 * time.Hour
+	if s := os.Getenv("REFRESH_TOKEN_TTL"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			ttl = d
+		}
+	}
+	return &service{repo: r, revoked: rl, refreshTTL: ttl}
+}
+
+var (
+	errBadRefresh     = fmt.Errorf("%w: invalid refresh token", httpx.ErrUnauthorized)
+	errRefreshExpired = fmt.Errorf("%w: refresh token expired", httpx.ErrUnauthorized)
+	ErrNoSession      = fmt.Errorf("%w: session", httpx.ErrNotFound)
+	ErrBadOneTime     = errors.New("invalid or expired token")
+)
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashToken(tok string) string {
+	h := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(h[:])
+}
+
+// Refresh tokens look like "{user_id}.{random}" so the owner's shard can be
+// found without a global index.
+func ownerOf(tok string) (string, int, bool) {
+	i := strings.LastIndexByte(tok, '.')
+	if i <= 0 {
+		return "", 0, false
+	}
+	uid := tok[:i]
+	sh, ok := shard.Extract(uid)
+	return uid, sh, ok
+}
+
+// clip cuts client-supplied strings to their column size.
+func clip(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func (s *service) issue(uid string, shardID int, familyID string) (*TokenPair, error) {
+	role, err := s.repo.Role(uid)
+	if err != nil {
+		return nil, err
+	}
+	access, err := jwt.Make(uid, shardID, familyID, role)
+	if err != nil {
+		return nil, err
+	}
+	refresh := uid + "." + randomString(32)
+	if err := s.repo.Create(&RefreshToken{
+		UserID:    uid,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(jwt.AccessTTL().Seconds()),
+	}, nil
+}
+
+func (s *service) Issue(uid string, shardID int, d Device) (*TokenPair, error) {
+	now := time.Now()
+	sess := &Session{
+		ID:         randomString(16),
+		UserID:     uid,
+		DeviceName: clip(d.Name, 100),
+		IP:         clip(d.IP, 64),
+		UserAgent:  clip(d.UserAgent, 255),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := s.repo.CreateSession(sess); err != nil {
+		return nil, err
+	}
+	return s.issue(uid, shardID, sess.ID)
+}
+
+func (s *service) Refresh(tok, ip string) (*TokenPair, error) {
+	uid, sh, ok := ownerOf(tok)
+	if !ok {
+		return nil, errBadRefresh
+	}
+	t, err := s.repo.GetByHash(sh, hashToken(tok))
+	if err != nil || t.UserID != uid {
+		return nil, errBadRefresh
+	}
+	if t.RevokedAt != nil {
+		// A rotated token came back: assume it leaked and kill the whole family.
+		_ = s.repo.RevokeFamily(sh, t.FamilyID)
+		return nil, errBadRefresh
+	}
+	if time.Now().After(t.ExpiresAt) {
+		return nil, errRefreshExpired
+	}
+	won, err := s.repo.Revoke(sh, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !won {
+		_ = s.repo.RevokeFamily(sh, t.FamilyID)
+		return nil, errBadRefresh
+	}
+	// The session may have been ended after the token was read; refreshing
+	// then must not bring it back.
+	live, err := s.repo.TouchSession(uid, t.FamilyID, clip(ip, 64))
+	if err != nil {
+		return nil, err
+	}
+	if !live {
+		_ = s.repo.RevokeFamily(sh, t.FamilyID)
+		return nil, errBadRefresh
+	}
+	return s.issue(uid, sh, t.FamilyID)
+}
+
+func (s *service) Logout(c jwt.Claims, in LogoutReq) error {
+	ctx := context.Background()
+	if in.All {
+		return s.RevokeAll(c.UserID)
+	}
+	if err := s.revoked.RevokeToken(ctx, c.ID, c.ExpiresAt); err != nil {
+		return err
+	}
+	if c.SessionID != "" {
+		if err := s.EndSession(c.UserID, c.SessionID); err != nil && !errors.Is(err, ErrNoSession) {
+			return err
+		}
+	}
+	// Tokens from before sessions existed name their family this way only.
+	if in.RefreshToken == "" {
+		return nil
+	}
+	uid, sh, ok := ownerOf(in.RefreshToken)
+	if !ok || uid != c.UserID {
+		return errBadRefresh
+	}
+	t, err := s.repo.GetByHash(sh, hashToken(in.RefreshToken))
+	if err != nil {
+		return errBadRefresh
+	}
+	return s.repo.RevokeFamily(sh, t.FamilyID)
+}
+
+func (s *service) RevokeAll(uid string) error {
+	if err := s.repo.RevokeAllForUser(uid); err != nil {
+		return err
+	}
+	if err := s.revoked.RevokeUser(context.Background(), uid, time.Now()); err != nil {
+		return err
+	}
+	return s.repo.DeleteSessions(uid)
+}
+
+func (s *service) ExpireAccess(uid string) error {
+	return s.revoked.RevokeUser(context.Background(), uid, time.Now())
+}
+
+// ListSessions leaves out sessions idle for longer than a refresh token
+// lives; they cannot be resumed.
+func (s *service) ListSessions(uid, current string) ([]Session, error) {
+	out, err := s.repo.ListSessions(uid, time.Now().Add(-s.refreshTTL))
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Current = out[i].ID == current
+	}
+	return out, nil
+}
+
+// EndSession removes the record first so a racing refresh cannot list it
+// again, then revokes the refresh family and the access tokens.
+func (s *service) EndSession(uid, id string) error {
+	found, err := s.repo.DeleteSession(uid, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNoSession
+	}
+	sh, _ := shard.Extract(uid)
+	if err := s.repo.RevokeFamily(sh, id); err != nil {
+		return err
+	}
+	return s.revoked.RevokeSession(context.Background(), id, jwt.AccessTTL())
+}
+
+func (s *service) IssueOneTime(uid, purpose string, ttl time.Duration) (string, error) {
+	return s.IssueOneTimeFor(uid, purpose, "", ttl)
+}
+
+func (s *service) IssueOneTimeFor(uid, purpose, data string, ttl time.Duration) (string, error) {
+	if err := s.repo.ExpireOneTime(uid, purpose); err != nil {
+		return "", err
+	}
+	tok := uid + "." + randomString(32)
+	if err := s.repo.CreateOneTime(&OneTimeToken{
+		UserID:    uid,
+		Purpose:   purpose,
+		Data:      data,
+		TokenHash: hashToken(tok),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+func (s *service) ConsumeOneTime(tok, purpose string) (string, error) {
+	uid, _, err := s.ConsumeOneTimeFor(tok, purpose)
+	return uid, err
+}
+
+func (s *service) ConsumeOneTimeFor(tok, purpose string) (string, string, error) {
+	uid, sh, ok := ownerOf(tok)
+	if !ok {
+		return "", "", ErrBadOneTime
+	}
+	t, err := s.repo.ConsumeOneTime(sh, purpose, hashToken(tok))
+	if errors.Is(err, errTokenUnusable) {
+		return "", "", ErrBadOneTime
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if t.UserID != uid {
+		return "", "", ErrBadOneTime
+	}
+	return uid, t.Data, nil
+}
@@ -0,0 +1,119 @@
+package content
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const DefaultTimeout = 5 * time.Second
+
+// ErrNotFound is returned by Owner when the content no longer exists.
+var ErrNotFound = errors.New("content not found")
+
+// Client looks up and removes content owned by other services on behalf of
+// moderation.
+// Calls go to their /internal endpoints and carry INTERNAL_TOKEN.
+type Client struct {
+	posts    string
+	feedback string
+	messages string
+	token    string
+	hc       *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		posts:    strings.TrimRight(getenv("POST_SERVICE_URL", "http://post-service:8082"), "/"),
+		feedback: strings.TrimRight(getenv("FEEDBACK_SERVICE_URL", "http://feedback-service:8084"), "/"),
+		messages: strings.TrimRight(getenv("MESSAGE_SERVICE_URL", "http://message-service:8085"), "/"),
+		token:    os.Getenv("INTERNAL_TOKEN"),
+		hc:       &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+func getenv(k, d string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return d
+}
+
+// endpoint returns the /internal URL of one post, comment, message or chat.
+func (c *Client) endpoint(kind, id string) (string, error) {
+	switch kind {
+	case "post":
+		return c.posts + "/internal/posts/" + url.PathEscape(id), nil
+	case "comment":
+		return c.feedback + "/internal/comments/" + url.PathEscape(id), nil
+	case "message":
+		return c.messages + "/internal/messages/" + url.PathEscape(id), nil
+	case "chat":
+		return c.messages + "/internal/chats/" + url.PathEscape(id), nil
+	}
+	return "", fmt.Errorf("unknown content kind %s", kind)
+}
+
+// Owner returns the user who wrote a post, comment or message, or who created
+// a chat, as the owning service records it.
+func (c *Client) Owner(ctx context.Context, kind, id string) (string, error) {
+	endpoint, err := c.endpoint(kind, id)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/owner", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Internal-Token", c.token)
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("owner of %s %s: status %d", kind, id, resp.StatusCode)
+	}
+	var out struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	if out.UserID == "" {
+		return "", fmt.Errorf("owner of %s %s: empty user_id", kind, id)
+	}
+	return out.UserID, nil
+}
+
+// Remove deletes one post, comment, message or chat. Content that is already
+// gone counts as removed.
+func (c *Client) Remove(ctx context.Context, kind, id string) error {
+	endpoint, err := c.endpoint(kind, id)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Internal-Token", c.token)
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("remove %s %s: status %d", kind, id, resp.StatusCode)
+	}
+	return nil
+}
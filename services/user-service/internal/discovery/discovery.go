@@ -0,0 +1,22 @@
+package discovery
+
+// Match is a user found by discovery. Shared is how many of the viewer's
+// interests they have; city matches leave it at zero.
+type Match struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Shared int    `json:"shared,omitempty"`
+}
+
+type Page struct {
+	Results    []Match `json:"results"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	// Partial is set when some shards did not answer in time.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// position is the last match of a page; the next page starts right after it.
+type position struct {
+	Shared int    `json:"s"`
+	UserID string `json:"u"`
+}
@@ -0,0 +1,33 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+
+	"users-service/internal/shared/httpx"
+)
+
+type Handler struct{ svc Service }
+
+func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+
+func (h *Handler) Interests(w http.ResponseWriter, r *http.Request) error {
+	return h.serve(w, r, h.svc.ByInterests)
+}
+
+func (h *Handler) City(w http.ResponseWriter, r *http.Request) error {
+	return h.serve(w, r, h.svc.ByCity)
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, find func(ctx context.Context, viewer, after string, limit int) (*Page, error)) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	page, err := find(r.Context(), uid, r.URL.Query().Get("cursor"), httpx.QueryInt(r, "limit", 20))
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, page, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,95 @@
+package discovery
+
+import (
+	"context"
+
+	"users-service/internal/shared/db"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Shards() []int
+	// InterestsOf and CityOf read the viewer's own interests and city; CityOf
+	// returns 0 when none is set.
+	InterestsOf(uid string) ([]uint64, error)
+	CityOf(uid string) (uint64, error)
+	// ByInterests and ByCity return up to limit matches from one physical
+	// shard, skipping the users in exclude and starting after `from`.
+	ByInterests(ctx context.Context, physical int, ids []uint64, exclude []string, from *position, limit int) ([]Match, error)
+	ByCity(ctx context.Context, physical int, cityID uint64, exclude []string, from *position, limit int) ([]Match, error)
+}
+
+type repo struct{ store *db.Store }
+
+func NewRepository(s *db.Store) Repository { return &repo{store: s} }
+
+func (r *repo) Shards() []int { return r.store.PhysicalIDs() }
+
+func (r *repo) InterestsOf(uid string) ([]uint64, error) {
+	var ids []uint64
+	err := r.store.UseFor(uid).Table("interest_users").Where("user_id = ?", uid).Pluck("interest_id", &ids).Error
+	return ids, err
+}
+
+func (r *repo) CityOf(uid string) (uint64, error) {
+	var ids []uint64
+	err := r.store.UseFor(uid).Table("profiles").Where("user_id = ?", uid).Pluck("city_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// public keeps users whose privacy settings show field to everyone; users
+// without settings are public.
+func public(column string) string {
+	return "COALESCE(NULLIF(ps." + column + ", ''), 'public') = 'public'"
+}
+
+// candidates is the users of one physical shard that discovery may show:
+// live, on a logical shard the map places here, and not excluded.
+func (r *repo) candidates(ctx context.Context, physical int, exclude []string) *gorm.DB {
+	tx := r.store.UsePhysical(physical).WithContext(ctx).Table("users u").
+		Joins("LEFT JOIN privacy_settings ps ON ps.user_id = u.user_id").
+		Where("u.shard_id IN ? AND u.suspended_at IS NULL", r.store.LogicalOn(physical))
+	if len(exclude) > 0 {
+		tx = tx.Where("u.user_id NOT IN ?", exclude)
+	}
+	return tx
+}
+
+func (r *repo) ByInterests(ctx context.Context, physical int, ids []uint64, exclude []string, from *position, limit int) ([]Match, error) {
+	if len(r.store.LogicalOn(physical)) == 0 || len(ids) == 0 {
+		return nil, nil
+	}
+	inner := r.candidates(ctx, physical, exclude).
+		Select("u.user_id, u.name, count(*) AS shared").
+		Joins("JOIN interest_users iu ON iu.user_id = u.user_id AND iu.interest_id IN ?", ids).
+		Where(public("interests")).
+		Group("u.user_id, u.name")
+
+	tx := r.store.UsePhysical(physical).WithContext(ctx).Table("(?) AS m", inner)
+	if from != nil {
+		tx = tx.Where("shared < ? OR (shared = ? AND user_id > ?)", from.Shared, from.Shared, from.UserID)
+	}
+	var out []Match
+	err := tx.Order("shared DESC, user_id ASC").Limit(limit).Scan(&out).Error
+	return out, err
+}
+
+func (r *repo) ByCity(ctx context.Context, physical int, cityID uint64, exclude []string, from *position, limit int) ([]Match, error) {
+	if len(r.store.LogicalOn(physical)) == 0 {
+		return nil, nil
+	}
+	tx := r.candidates(ctx, physical, exclude).
+		Select("u.user_id, u.name").
+		Joins("JOIN profiles p ON p.user_id = u.user_id AND p.city_id = ?", cityID).
+		Where(public("city"))
+	if from != nil {
+		tx = tx.Where("u.user_id > ?", from.UserID)
+	}
+	var out []Match
+	err := tx.Order("u.user_id ASC").Limit(limit).Scan(&out).Error
+	return out, err
+}
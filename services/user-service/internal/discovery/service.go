@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"context"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"users-service/internal/shared/cursor"
+)
+
+type Service interface {
+	// ByInterests finds users sharing the viewer's interests, most shared
+	// first.
+	ByInterests(ctx context.Context, viewer, after string, limit int) (*Page, error)
+	// ByCity finds users living in the viewer's city.
+	ByCity(ctx context.Context, viewer, after string, limit int) (*Page, error)
+	// Candidates returns up to limit user ids from the first pages of
+	// ByInterests and then ByCity.
+	Candidates(ctx context.Context, viewer string, limit int) ([]string, error)
+}
+
+// BlockLister returns everyone a user has blocked or been blocked by.
+type BlockLister interface {
+	ListBlockedWith(uid string) ([]string, error)
+}
+
+type service struct {
+	repo         Repository
+	blocks       BlockLister
+	shardTimeout time.Duration
+}
+
+func NewService(r Repository, blocks BlockLister) Service {
+	timeout := 800 * time.Millisecond
+	if d, err := time.ParseDuration(os.Getenv("DISCOVERY_SHARD_TIMEOUT")); err == nil && d > 0 {
+		timeout = d
+	}
+	return &service{repo: r, blocks: blocks, shardTimeout: timeout}
+}
+
+const maxLimit = 50
+
+type shardQuery func(ctx context.Context, physical int, exclude []string, from *position, limit int) ([]Match, error)
+
+func (s *service) ByInterests(ctx context.Context, viewer, after string, limit int) (*Page, error) {
+	ids, err := s.repo.InterestsOf(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return &Page{Results: []Match{}}, nil
+	}
+	return s.gather(ctx, viewer, after, limit, func(ctx context.Context, physical int, exclude []string, from *position, limit int) ([]Match, error) {
+		return s.repo.ByInterests(ctx, physical, ids, exclude, from, limit)
+	})
+}
+
+func (s *service) ByCity(ctx context.Context, viewer, after string, limit int) (*Page, error) {
+	city, err := s.repo.CityOf(viewer)
+	if err != nil {
+		return nil, err
+	}
+	if city == 0 {
+		return &Page{Results: []Match{}}, nil
+	}
+	return s.gather(ctx, viewer, after, limit, func(ctx context.Context, physical int, exclude []string, from *position, limit int) ([]Match, error) {
+		return s.repo.ByCity(ctx, physical, city, exclude, from, limit)
+	})
+}
+
+func (s *service) Candidates(ctx context.Context, viewer string, limit int) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	for _, find := range []func(context.Context, string, string, int) (*Page, error){s.ByInterests, s.ByCity} {
+		if len(out) >= limit {
+			break
+		}
+		page, err := find(ctx, viewer, "", min(limit-len(out), maxLimit))
+		if err != nil {
+			return out, err
+		}
+		for _, m := range page.Results {
+			if !seen[m.UserID] {
+				seen[m.UserID] = true
+				out = append(out, m.UserID)
+			}
+		}
+	}
+	return out, nil
+}
+
+// gather runs q on every shard in parallel and merges the results into one
+// page. The viewer and everyone they have a block with are left out.
+func (s *service) gather(ctx context.Context, viewer, after string, limit int, q shardQuery) (*Page, error) {
+	if limit <= 0 || limit > maxLimit {
+		limit = 20
+	}
+	var from *position
+	var pos position
+	if ok, err := cursor.Decode(after, &pos); err != nil {
+		return nil, err
+	} else if ok {
+		from = &pos
+	}
+	exclude := []string{viewer}
+	blocked, err := s.blocks.ListBlockedWith(viewer)
+	if err != nil {
+		return nil, err
+	}
+	exclude = append(exclude, blocked...)
+
+	shards := s.repo.Shards()
+	results := make([][]Match, len(shards))
+	failed := make([]bool, len(shards))
+	var wg sync.WaitGroup
+	for i, id := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sctx, cancel := context.WithTimeout(ctx, s.shardTimeout)
+			defer cancel()
+			ms, err := q(sctx, id, exclude, from, limit+1)
+			if err != nil {
+				log.Printf("discovery shard %d: %v", id, err)
+				failed[i] = true
+				return
+			}
+			results[i] = ms
+		}()
+	}
+	wg.Wait()
+
+	page := &Page{Results: []Match{}}
+	seen := make(map[string]bool)
+	for i := range shards {
+		page.Partial = page.Partial || failed[i]
+		for _, m := range results[i] {
+			if !seen[m.UserID] {
+				seen[m.UserID] = true
+				page.Results = append(page.Results, m)
+			}
+		}
+	}
+	sort.Slice(page.Results, func(a, b int) bool {
+		ra, rb := page.Results[a], page.Results[b]
+		if ra.Shared != rb.Shared {
+			return ra.Shared > rb.Shared
+		}
+		return ra.UserID < rb.UserID
+	})
+	if len(page.Results) > limit {
+		page.Results = page.Results[:limit]
+		last := page.Results[limit-1]
+		page.NextCursor = cursor.Encode(position{Shared: last.Shared, UserID: last.UserID})
+	}
+	return page, nil
+}
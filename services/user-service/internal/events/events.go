@@ -0,0 +1,136 @@
+// Package events publishes user-service domain events to Kafka.
+//
+// Every event is a flat JSON object carrying the Meta fields plus its own
+// payload, keyed by the user id it is about so one user's events stay ordered
+// within a partition. Fields are only ever added; a breaking change bumps
+// Version.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"users-service/internal/kafka"
+)
+
+const (
+	TopicUserRegistered = "users.registered"
+	TopicFollowed       = "social.followed"
+	TopicUnfollowed     = "social.unfollowed"
+	TopicFriended       = "social.friended"
+	TopicProfileUpdated = "profile.updated"
+	TopicUserDeleted    = "users.deleted"
+)
+
+var topics = []string{TopicUserRegistered, TopicFollowed, TopicUnfollowed, TopicFriended, TopicProfileUpdated, TopicUserDeleted}
+
+type Meta struct {
+	EventID    string    `json:"event_id"`
+	Type       string    `json:"type"`
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type UserRegistered struct {
+	Meta
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+// UserDeleted asks every service to erase the user's data and report back
+// on the deletion request; see package account.
+type UserDeleted struct {
+	Meta
+	UserID    string `json:"user_id"`
+	RequestID string `json:"request_id"`
+}
+
+// Followed is published on social.followed and social.unfollowed.
+type Followed struct {
+	Meta
+	UserID   string `json:"user_id"`
+	TargetID string `json:"target_id"`
+}
+
+// Friended is published once per friendship, keyed by the user who sent the
+// request.
+type Friended struct {
+	Meta
+	UserID    string `json:"user_id"`
+	FriendID  string `json:"friend_id"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type ProfileUpdated struct {
+	Meta
+	UserID      string         `json:"user_id"`
+	Description string         `json:"description"`
+	CityID      uint64         `json:"city_id"`
+	AvatarURL   string         `json:"avatar_url"`
+	Education   map[string]any `json:"education"`
+	Hobby       map[string]any `json:"hobby"`
+}
+
+func NewMeta(typ string) Meta {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return Meta{EventID: hex.EncodeToString(b[:]), Type: typ, Version: 1, OccurredAt: time.Now().UTC()}
+}
+
+// Publisher writes events to their topics. A nil Publisher drops everything,
+// for tools that run without Kafka.
+type Publisher struct {
+	writers map[string]*kafka.Writer
+}
+
+func NewPublisher(bootstrap string) (*Publisher, error) {
+	p := &Publisher{writers: make(map[string]*kafka.Writer, len(topics))}
+	for _, t := range topics {
+		w, err := kafka.NewWriter(bootstrap, t)
+		if err != nil {
+			_ = p.Close()
+			return nil, err
+		}
+		p.writers[t] = w
+	}
+	return p, nil
+}
+
+func (p *Publisher) Close() error {
+	if p == nil {
+		return nil
+	}
+	var errs []error
+	for _, w := range p.writers {
+		errs = append(errs, w.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Publish sends v to topic under key. The change it describes is already
+// committed, so failures are logged rather than returned.
+func (p *Publisher) Publish(topic, key string, v any) {
+	if p == nil {
+		return
+	}
+	w, ok := p.writers[topic]
+	if !ok {
+		log.Printf("publish %s: unknown topic", topic)
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("publish %s: %v", topic, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Publish(ctx, key, b); err != nil {
+		log.Printf("publish %s: %v", topic, err)
+	}
+}
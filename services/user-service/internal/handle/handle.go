@@ -0,0 +1,64 @@
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"users-service/internal/shared/httpx"
+)
+
+// Entry is a row of the handle directory. The directory lives on the control
+// shard and is the only place uniqueness is decided; the owner's shard keeps
+// a copy in users.handle, and a handle resolves only while the two agree.
+type Entry struct {
+	Handle string `gorm:"primaryKey;size:30"`
+	UserID string `gorm:"size:64;index"`
+	State  string `gorm:"size:16"`
+	// HeldUntil ends a reservation or a release hold.
+	HeldUntil time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+func (Entry) TableName() string { return "handle_directory" }
+
+const (
+	// StateReserved is a claim in progress; it lapses at HeldUntil.
+	StateReserved = "reserved"
+	StateActive   = "active"
+	// StateReleased is a given-up handle that only its last owner may take
+	// back before HeldUntil, so links to it do not switch owner at once.
+	StateReleased = "released"
+)
+
+var (
+	ErrInvalid = errors.New("handle must be 3-30 letters, digits or underscores and start with a letter")
+	ErrTaken   = fmt.Errorf("%w: handle is taken", httpx.ErrConflict)
+	ErrUnknown = fmt.Errorf("%w: handle", httpx.ErrNotFound)
+)
+
+var pattern = regexp.MustCompile(`^[a-z][a-z0-9_]{2,29}$`)
+
+// reservedNames would read as part of the site rather than as a person.
+var reservedNames = map[string]bool{
+	"admin": true, "administrator": true, "api": true, "help": true, "me": true,
+	"moderator": true, "root": true, "settings": true, "support": true, "system": true,
+}
+
+// Normalize turns user input such as "@Alice_1" into the stored form.
+func Normalize(h string) (string, error) {
+	h = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(h), "@"))
+	if !pattern.MatchString(h) {
+		return "", ErrInvalid
+	}
+	if reservedNames[h] {
+		return "", ErrTaken
+	}
+	return h, nil
+}
+
+type ClaimReq struct {
+	Handle string `json:"handle" validate:"required,max=31"`
+}
@@ -0,0 +1,53 @@
+package handle
+
+import (
+	"net/http"
+
+	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/validate"
+)
+
+type Handler struct{ svc Service }
+
+func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+
+func (h *Handler) Claim(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[ClaimReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	handle, err := h.svc.Claim(uid, in.Handle)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"user_id": uid, "handle": handle}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Release(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.Release(uid); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) ByHandle(w http.ResponseWriter, r *http.Request) error {
+	card, err := h.svc.Card(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, card, http.StatusOK)
+	return nil
+}
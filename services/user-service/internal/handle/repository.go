@@ -0,0 +1,152 @@
+package handle
+
+import (
+	"errors"
+	"time"
+
+	"users-service/internal/shared/db"
+	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/shard"
+	"users-service/internal/user"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Reserve holds handle for uid until the given time. It fails with
+	// ErrTaken if another user owns it, or reserved it or had it released
+	// and their time has not run out.
+	Reserve(handle, uid string, until time.Time) error
+	// Activate makes uid's reservation final and, in the same transaction,
+	// releases every other handle uid reserved or owns, holding them for uid
+	// until the given time. It reports false, changing nothing, if the
+	// reservation lapsed and someone else has taken the handle since, or a
+	// concurrent claim of uid's released it.
+	Activate(handle, uid string, holdUntil time.Time) (bool, error)
+	// Release gives up uid's handle, holding it for uid until the given time.
+	Release(handle, uid string, holdUntil time.Time) error
+	// Drop deletes uid's reservation of handle, if it still has one.
+	Drop(handle, uid string) error
+	Lookup(handle string) (*Entry, error)
+	// Lapsed returns reservations that ran out before the given time.
+	Lapsed(before time.Time, limit int) ([]Entry, error)
+
+	// UserHandle and SetUserHandle read and write the copy on the owner's
+	// shard.
+	UserHandle(uid string) (string, error)
+	SetUserHandle(uid, handle string) error
+}
+
+type repo struct{ store *db.Store }
+
+func NewRepository(s *db.Store) Repository { return &repo{store: s} }
+
+func (r *repo) directory() *gorm.DB { return r.store.WritePhysical(r.store.ControlID()) }
+
+func (r *repo) Reserve(handle, uid string, until time.Time) error {
+	return r.directory().Transaction(func(tx *gorm.DB) error {
+		var e Entry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("handle = ?", handle).Take(&e).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&Entry{Handle: handle, UserID: uid, State: StateReserved, HeldUntil: until})
+			if res.Error == nil && res.RowsAffected == 0 {
+				return ErrTaken
+			}
+			return res.Error
+		}
+		if err != nil {
+			return err
+		}
+		if e.UserID == uid {
+			if e.State == StateActive {
+				return nil
+			}
+		} else if e.State == StateActive || time.Now().Before(e.HeldUntil) {
+			return ErrTaken
+		}
+		return tx.Model(&e).Updates(map[string]any{"user_id": uid, "state": StateReserved, "held_until": until}).Error
+	})
+}
+
+var errLapsed = errors.New("reservation lapsed")
+
+func (r *repo) Activate(handle, uid string, holdUntil time.Time) (bool, error) {
+	err := r.directory().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Entry{}).
+			Where("user_id = ? AND handle <> ? AND state IN ?", uid, handle, []string{StateReserved, StateActive}).
+			Updates(map[string]any{"state": StateReleased, "held_until": holdUntil}).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&Entry{}).
+			Where("handle = ? AND user_id = ? AND state IN ?", handle, uid, []string{StateReserved, StateActive}).
+			Updates(map[string]any{"state": StateActive, "held_until": time.Time{}})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return errLapsed
+		}
+		return res.Error
+	})
+	if errors.Is(err, errLapsed) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *repo) Release(handle, uid string, holdUntil time.Time) error {
+	return r.directory().Model(&Entry{}).
+		Where("handle = ? AND user_id = ?", handle, uid).
+		Updates(map[string]any{"state": StateReleased, "held_until": holdUntil}).Error
+}
+
+func (r *repo) Drop(handle, uid string) error {
+	return r.directory().
+		Delete(&Entry{}, "handle = ? AND user_id = ? AND state = ?", handle, uid, StateReserved).Error
+}
+
+func (r *repo) Lookup(handle string) (*Entry, error) {
+	var e Entry
+	err := r.store.UsePhysical(r.store.ControlID()).Where("handle = ?", handle).Take(&e).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *repo) Lapsed(before time.Time, limit int) ([]Entry, error) {
+	var out []Entry
+	err := r.directory().Where("state = ? AND held_until < ?", StateReserved, before).
+		Order("held_until").Limit(limit).Find(&out).Error
+	return out, err
+}
+
+func (r *repo) UserHandle(uid string) (string, error) {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return "", httpx.ErrNotFound
+	}
+	var u user.User
+	err := r.store.Write(sh).Select("handle").Where("user_id = ?", uid).Take(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", httpx.ErrNotFound
+	}
+	return u.Handle, err
+}
+
+func (r *repo) SetUserHandle(uid, handle string) error {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return httpx.ErrNotFound
+	}
+	res := r.store.Write(sh).Model(&user.User{}).Where("user_id = ?", uid).Update("handle", handle)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return httpx.ErrNotFound
+	}
+	return nil
+}
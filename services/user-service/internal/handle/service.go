@@ -0,0 +1,181 @@
+package handle
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"users-service/internal/shared/httpx"
+	"users-service/internal/user"
+)
+
+type Service interface {
+	// Claim gives uid the handle and releases the one it had. It returns the
+	// handle in stored form.
+	Claim(uid, handle string) (string, error)
+	// Release gives up uid's handle.
+	Release(uid string) error
+	// Resolve returns the user that owns handle.
+	Resolve(handle string) (string, error)
+	// Card resolves handle to the owner's public card.
+	Card(ctx context.Context, handle string) (*user.Card, error)
+	// Settle finishes or drops reservations whose claim never completed and
+	// returns how many it handled.
+	Settle() (int, error)
+}
+
+// Users is the part of the user service handles need.
+type Users interface {
+	Cards(ctx context.Context, ids []string) ([]user.Card, []string, error)
+	ForgetCard(uid string)
+}
+
+type service struct {
+	repo       Repository
+	users      Users
+	reserveFor time.Duration
+	holdFor    time.Duration
+}
+
+func NewService(r Repository, users Users) Service {
+	return &service{
+		repo:       r,
+		users:      users,
+		reserveFor: durationEnv("HANDLE_RESERVE_TTL", time.Minute),
+		holdFor:    durationEnv("HANDLE_RELEASE_HOLD", 14*24*time.Hour),
+	}
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// Claim reserves the handle in the directory, writes it to the user's shard
+// and then makes the reservation final. If the process dies in between, the
+// reservation lapses and Settle decides from the user's shard whether the
+// claim went through.
+func (s *service) Claim(uid, raw string) (string, error) {
+	h, err := Normalize(raw)
+	if err != nil {
+		return "", err
+	}
+	prev, err := s.repo.UserHandle(uid)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.Reserve(h, uid, time.Now().Add(s.reserveFor)); err != nil {
+		return "", err
+	}
+	if prev != h {
+		if err := s.repo.SetUserHandle(uid, h); err != nil {
+			_ = s.repo.Drop(h, uid)
+			return "", err
+		}
+	}
+	// Activating releases prev, and any handle a concurrent claim of uid's
+	// left behind.
+	ok, err := s.repo.Activate(h, uid, time.Now().Add(s.holdFor))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		// The reservation ran out and someone else took the handle.
+		_ = s.repo.SetUserHandle(uid, prev)
+		return "", ErrTaken
+	}
+	s.users.ForgetCard(uid)
+	return h, nil
+}
+
+func (s *service) Release(uid string) error {
+	prev, err := s.repo.UserHandle(uid)
+	if err != nil || prev == "" {
+		return err
+	}
+	if err := s.repo.SetUserHandle(uid, ""); err != nil {
+		return err
+	}
+	s.users.ForgetCard(uid)
+	return s.repo.Release(prev, uid, time.Now().Add(s.holdFor))
+}
+
+// Resolve only trusts an active entry that the owner's shard agrees with, so
+// a claim that lost a race never resolves to the loser.
+func (s *service) Resolve(raw string) (string, error) {
+	h, err := Normalize(raw)
+	if err != nil {
+		return "", ErrUnknown
+	}
+	e, err := s.repo.Lookup(h)
+	if err != nil {
+		return "", err
+	}
+	if e.State != StateActive {
+		return "", ErrUnknown
+	}
+	if cur, err := s.repo.UserHandle(e.UserID); err != nil || cur != h {
+		return "", ErrUnknown
+	}
+	return e.UserID, nil
+}
+
+func (s *service) Card(ctx context.Context, raw string) (*user.Card, error) {
+	uid, err := s.Resolve(raw)
+	if err != nil {
+		return nil, err
+	}
+	cards, _, err := s.users.Cards(ctx, []string{uid})
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return nil, ErrUnknown
+	}
+	return &cards[0], nil
+}
+
+func (s *service) Settle() (int, error) {
+	lapsed, err := s.repo.Lapsed(time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range lapsed {
+		cur, err := s.repo.UserHandle(e.UserID)
+		if err != nil && !errors.Is(err, httpx.ErrNotFound) {
+			return 0, err
+		}
+		if cur == e.Handle {
+			_, err = s.repo.Activate(e.Handle, e.UserID, time.Now().Add(s.holdFor))
+		} else {
+			err = s.repo.Drop(e.Handle, e.UserID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(lapsed), nil
+}
+
+// Sweep runs Settle periodically until ctx is done.
+func Sweep(ctx context.Context, svc Service) {
+	t := time.NewTicker(durationEnv("HANDLE_SWEEP_INTERVAL", time.Minute))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if n, err := svc.Settle(); err != nil {
+				log.Printf("handle sweep: %v", err)
+			} else if n > 0 {
+				log.Printf("handle sweep: settled %d reservations", n)
+			}
+		}
+	}
+}
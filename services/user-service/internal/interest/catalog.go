@@ -0,0 +1,210 @@
+package interest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"users-service/internal/shared/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// catalogTable is one catalog table and the column that references it.
+// ownerCol is set when refTable's primary key is (ownerCol, refCol).
+type catalogTable struct {
+	name     string
+	refTable string
+	refCol   string
+	ownerCol string
+}
+
+var (
+	interestsTable = catalogTable{name: "interests", refTable: "interest_users", refCol: "interest_id", ownerCol: "user_id"}
+	citiesTable    = catalogTable{name: "cities", refTable: "profiles", refCol: "city_id"}
+)
+
+type entry struct {
+	ID   uint64
+	Name string
+}
+
+// createEntry finds or creates name on the control shard, then copies it to
+// every other shard. A failed copy is logged; SyncCatalog fills it in later.
+func (r *repo) createEntry(t catalogTable, dst any, name string) error {
+	ctl := r.store.ControlID()
+	if err := r.store.WritePhysical(ctl).Table(t.name).FirstOrCreate(dst, "name = ?", name).Error; err != nil {
+		return err
+	}
+	var e entry
+	if err := r.store.WritePhysical(ctl).Table(t.name).Where("name = ?", name).Take(&e).Error; err != nil {
+		return err
+	}
+	for _, id := range r.store.PhysicalIDs() {
+		if id == ctl {
+			continue
+		}
+		if err := upsertEntries(r.store.WritePhysical(id), t, []entry{e}); err != nil {
+			log.Printf("catalog %s %q to shard %d: %v", t.name, name, id, err)
+		}
+	}
+	return nil
+}
+
+func upsertEntries(tx *gorm.DB, t catalogTable, es []entry) error {
+	return tx.Table(t.name).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name"}),
+	}).CreateInBatches(es, 500).Error
+}
+
+// catalogQuery reads the catalog from the control shard, ordered by name and
+// optionally narrowed to a case-insensitive name prefix.
+func (r *repo) catalogQuery(q string) *gorm.DB {
+	tx := r.store.UsePhysical(r.store.ControlID()).Order("name")
+	if q = strings.ToLower(strings.TrimSpace(q)); q != "" {
+		tx = tx.Where("lower(name) LIKE ?", likeEscaper.Replace(q)+"%")
+	}
+	return tx
+}
+
+func (r *repo) SyncCatalog(physical int) error {
+	if physical == r.store.ControlID() {
+		return nil
+	}
+	for _, t := range []catalogTable{interestsTable, citiesTable} {
+		if err := r.syncTable(physical, t); err != nil {
+			return fmt.Errorf("sync %s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// syncTable makes the shard's copy of t equal to the control shard's.
+//
+// Shards created their own interests before the catalog existed, so a local
+// id can name something else than the same id in the catalog. Such rows are
+// adopted: their name is added to the catalog if needed, references to the
+// local id are rewritten to the catalog id (see remapRefs), and the local row
+// is replaced.
+func (r *repo) syncTable(physical int, t catalogTable) error {
+	ctl := r.store.WritePhysical(r.store.ControlID())
+	var global []entry
+	if err := ctl.Table(t.name).Find(&global).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint64]string, len(global))
+	byName := make(map[string]uint64, len(global))
+	for _, e := range global {
+		byID[e.ID] = e.Name
+		byName[e.Name] = e.ID
+	}
+
+	return r.store.WritePhysical(physical).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "catalog_"+t.name).Error; err != nil {
+			return err
+		}
+		var local []entry
+		if err := tx.Table(t.name).Find(&local).Error; err != nil {
+			return err
+		}
+		var stale []uint64
+		remap := make(map[uint64]uint64)
+		for _, e := range local {
+			if name, ok := byID[e.ID]; ok && name == e.Name {
+				continue
+			}
+			stale = append(stale, e.ID)
+			id, ok := byName[e.Name]
+			if !ok {
+				created := entry{Name: e.Name}
+				if err := ctl.Table(t.name).Where("name = ?", e.Name).FirstOrCreate(&created).Error; err != nil {
+					return err
+				}
+				id = created.ID
+				byName[e.Name] = id
+				global = append(global, created)
+			}
+			if id != e.ID {
+				remap[e.ID] = id
+			}
+		}
+		if len(remap) > 0 {
+			if err := remapRefs(tx, t, remap); err != nil {
+				return err
+			}
+			log.Printf("catalog %s on shard %d: %d ids remapped", t.name, physical, len(remap))
+		}
+		if len(stale) > 0 {
+			if err := tx.Exec("DELETE FROM "+t.name+" WHERE id IN ?", stale).Error; err != nil {
+				return err
+			}
+		}
+		if len(global) == 0 {
+			return nil
+		}
+		return upsertEntries(tx, t, global)
+	})
+}
+
+// remapRefs points the references of t at new ids. When the reference is part
+// of the primary key a single UPDATE could collide with itself, for instance
+// when a user holds both ids of a chain 5->7, 7->9, so the rows are taken out
+// and put back with their new ids, dropping ones the user already has.
+func remapRefs(tx *gorm.DB, t catalogTable, remap map[uint64]uint64) error {
+	vals := make([]string, 0, len(remap))
+	args := make([]any, 0, 2*len(remap))
+	for from, to := range remap {
+		vals = append(vals, "(?::bigint, ?::bigint)")
+		args = append(args, from, to)
+	}
+	m := "(VALUES " + strings.Join(vals, ", ") + ") AS m(from_id, to_id)"
+	if t.ownerCol == "" {
+		q := fmt.Sprintf("UPDATE %s AS ref SET %s = m.to_id FROM %s WHERE ref.%s = m.from_id",
+			t.refTable, t.refCol, m, t.refCol)
+		return tx.Exec(q, args...).Error
+	}
+	stmts := []string{
+		fmt.Sprintf("CREATE TEMP TABLE catalog_remap ON COMMIT DROP AS SELECT ref.%s AS owner, m.to_id FROM %s AS ref JOIN %s ON ref.%s = m.from_id",
+			t.ownerCol, t.refTable, m, t.refCol),
+		fmt.Sprintf("DELETE FROM %s AS ref USING %s WHERE ref.%s = m.from_id", t.refTable, m, t.refCol),
+	}
+	for _, q := range stmts {
+		if err := tx.Exec(q, args...).Error; err != nil {
+			return err
+		}
+	}
+	q := fmt.Sprintf("INSERT INTO %s (%s, %s) SELECT owner, to_id FROM catalog_remap ON CONFLICT DO NOTHING",
+		t.refTable, t.ownerCol, t.refCol)
+	if err := tx.Exec(q).Error; err != nil {
+		return err
+	}
+	return tx.Exec("DROP TABLE catalog_remap").Error
+}
+
+// SyncCatalogAll runs SyncCatalog on every physical shard.
+func SyncCatalogAll(r Repository, store *db.Store) {
+	for _, id := range store.PhysicalIDs() {
+		if err := r.SyncCatalog(id); err != nil {
+			log.Printf("catalog shard %d: %v", id, err)
+		}
+	}
+}
+
+// SyncCatalogLoop runs SyncCatalogAll at once and then every interval until
+// ctx is done.
+func SyncCatalogLoop(ctx context.Context, r Repository, store *db.Store, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		SyncCatalogAll(r, store)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -12,15 +12,7 @@ type Handler struct{ svc Service }
 
 func NewHandler(s Service) *Handler { return &Handler{svc: s} }
 
-type CreateReq struct {
-	Name string `json:"name" validate:"required"`
-}
-
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) error {
-	_, shardID, err := httpx.UserFromCtx(r)
-	if err != nil {
-		return err
-	}
 	in, err := httpx.Decode[CreateReq](r)
 	if err != nil {
 		return err
@@ -28,7 +20,7 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) error {
 	if err := validate.Struct(in); err != nil {
 		return err
 	}
-	it, err := h.svc.Create(shardID, in.Name)
+	it, err := h.svc.Create(in.Name)
 	if err != nil {
 		return err
 	}
@@ -73,12 +65,66 @@ func (h *Handler) ListMine(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	limit := httpx.QueryInt(r, "limit", 50)
-	offset := httpx.QueryInt(r, "offset", 0)
-	items, err := h.svc.List(uid, limit, offset)
+	limit, offset := pageParams(r)
+	owner := r.URL.Query().Get("user_id")
+	if owner == "" {
+		owner = uid
+	}
+	items, err := h.svc.ListOf(uid, owner, limit, offset)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) CreateCity(w http.ResponseWriter, r *http.Request) error {
+	in, err := httpx.Decode[CreateReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	c, err := h.svc.CreateCity(in.Name)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, c, http.StatusCreated)
+	return nil
+}
+
+func (h *Handler) Catalog(w http.ResponseWriter, r *http.Request) error {
+	limit, offset := pageParams(r)
+	items, err := h.svc.ListInterests(r.URL.Query().Get("q"), limit, offset)
 	if err != nil {
 		return err
 	}
 	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
 	return nil
 }
+
+func (h *Handler) Cities(w http.ResponseWriter, r *http.Request) error {
+	limit, offset := pageParams(r)
+	items, err := h.svc.ListCities(r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	return nil
+}
+
+// maxLimit caps a page of any interest or city list.
+const maxLimit = 200
+
+func pageParams(r *http.Request) (limit, offset int) {
+	limit = httpx.QueryInt(r, "limit", 50)
+	if limit <= 0 || limit > maxLimit {
+		limit = 50
+	}
+	offset = httpx.QueryInt(r, "offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
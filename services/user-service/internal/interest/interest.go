@@ -1,5 +1,8 @@
 package interest
 
+// City and Interest form a catalog shared by every shard. Rows are created on
+// the control shard, which assigns the id, and copied with that id to every
+// other shard so InterestUser and Profile.CityID can be joined locally.
 type City struct {
 	ID   uint64 `gorm:"primaryKey" json:"id"`
 	Name string `gorm:"uniqueIndex;size:120" json:"name"`
@@ -10,5 +13,9 @@ type Interest struct {
 }
 type InterestUser struct {
 	UserID     string `gorm:"primaryKey;size:64"`
-	InterestID uint64 `gorm:"primaryKey"`
+	InterestID uint64 `gorm:"primaryKey;index"`
+}
+
+type CreateReq struct {
+	Name string `json:"name" validate:"required,max=120"`
 }
@@ -1,15 +1,23 @@
 package interest
 
 import (
+	"strings"
+
 	"users-service/internal/shared/db"
 	"users-service/internal/shared/shard"
-
-	"gorm.io/gorm"
 )
 
 type Repository interface {
-	// NEW:
-	Create(shardID int, name string) (*Interest, error)
+	// Create adds an interest to the catalog, or returns the one with that
+	// name.
+	Create(name string) (*Interest, error)
+	CreateCity(name string) (*City, error)
+	// ListInterests and ListCities search the catalog by name prefix.
+	ListInterests(q string, limit, offset int) ([]Interest, error)
+	ListCities(q string, limit, offset int) ([]City, error)
+	// SyncCatalog brings one physical shard's copy of the catalog in line
+	// with the control shard; see catalog.go.
+	SyncCatalog(physical int) error
 
 	Attach(uid string, interestID uint64) error
 	Detach(uid string, interestID uint64) error
@@ -20,14 +28,36 @@ type repo struct{ store *db.Store }
 
 func NewRepository(s *db.Store) Repository { return &repo{store: s} }
 
-func (r *repo) Create(shardID int, name string) (*Interest, error) {
+func (r *repo) Create(name string) (*Interest, error) {
 	in := &Interest{Name: name}
-	if err := r.store.Write(shardID).FirstOrCreate(in, "name = ?", name).Error; err != nil {
+	if err := r.createEntry(interestsTable, in, name); err != nil {
 		return nil, err
 	}
 	return in, nil
 }
 
+func (r *repo) CreateCity(name string) (*City, error) {
+	c := &City{Name: name}
+	if err := r.createEntry(citiesTable, c, name); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *repo) ListInterests(q string, limit, offset int) ([]Interest, error) {
+	var out []Interest
+	err := r.catalogQuery(q).Model(&Interest{}).Limit(limit).Offset(offset).Find(&out).Error
+	return out, err
+}
+
+func (r *repo) ListCities(q string, limit, offset int) ([]City, error) {
+	var out []City
+	err := r.catalogQuery(q).Model(&City{}).Limit(limit).Offset(offset).Find(&out).Error
+	return out, err
+}
+
 func (r *repo) Attach(uid string, interestID uint64) error {
 	sh, _ := shard.Extract(uid)
 	return r.store.Write(sh).FirstOrCreate(&InterestUser{UserID: uid, InterestID: interestID}).Error
@@ -37,12 +67,9 @@ func (r *repo) Detach(uid string, interestID uint64) error {
 	return r.store.Write(sh).Delete(&InterestUser{}, "user_id=? AND interest_id=?", uid, interestID).Error
 }
 func (r *repo) List(uid string, limit, offset int) ([]Interest, error) {
-	sh, _ := shard.Extract(uid)
 	var ints []Interest
-	err := r.store.Use(sh).
+	err := r.store.UseFor(uid).
 		Joins("JOIN interest_users iu ON iu.interest_id = interests.id AND iu.user_id = ?", uid).
 		Model(&Interest{}).Limit(limit).Offset(offset).Find(&ints).Error
 	return ints, err
 }
-
-var _ = gorm.ErrRecordNotFound
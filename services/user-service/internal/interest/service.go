@@ -1,22 +1,53 @@
 package interest
 
+import "users-service/internal/profile"
+
 type Service interface {
-	Create(shardID int, name string) (*Interest, error)
+	Create(name string) (*Interest, error)
+	CreateCity(name string) (*City, error)
+	ListInterests(q string, limit, offset int) ([]Interest, error)
+	ListCities(q string, limit, offset int) ([]City, error)
 
 	Attach(uid string, interestID uint64) error
 	Detach(uid string, interestID uint64) error
 	List(uid string, limit, offset int) ([]Interest, error)
+	// ListOf lists owner's interests for viewer, subject to owner's privacy
+	// settings.
+	ListOf(viewer, owner string, limit, offset int) ([]Interest, error)
+}
+
+// Visibility applies users' privacy settings.
+type Visibility interface {
+	CanSee(owner, viewer, field string) (bool, error)
 }
 
-type service struct{ repo Repository }
+type service struct {
+	repo Repository
+	vis  Visibility
+}
 
-func NewService(r Repository) Service { return &service{repo: r} }
+func NewService(r Repository, vis Visibility) Service { return &service{repo: r, vis: vis} }
 
-func (s *service) Create(shardID int, name string) (*Interest, error) {
-	return s.repo.Create(shardID, name)
+func (s *service) Create(name string) (*Interest, error) { return s.repo.Create(name) }
+func (s *service) CreateCity(name string) (*City, error) { return s.repo.CreateCity(name) }
+func (s *service) ListInterests(q string, limit, offset int) ([]Interest, error) {
+	return s.repo.ListInterests(q, limit, offset)
+}
+func (s *service) ListCities(q string, limit, offset int) ([]City, error) {
+	return s.repo.ListCities(q, limit, offset)
 }
 func (s *service) Attach(uid string, interestID uint64) error { return s.repo.Attach(uid, interestID) }
 func (s *service) Detach(uid string, interestID uint64) error { return s.repo.Detach(uid, interestID) }
 func (s *service) List(uid string, limit, offset int) ([]Interest, error) {
 	return s.repo.List(uid, limit, offset)
 }
+func (s *service) ListOf(viewer, owner string, limit, offset int) ([]Interest, error) {
+	ok, err := s.vis.CanSee(owner, viewer, profile.FieldInterests)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, profile.ErrHidden
+	}
+	return s.repo.List(owner, limit, offset)
+}
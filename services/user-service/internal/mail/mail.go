@@ -0,0 +1,99 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers transactional mail. Implementations must be safe for
+// concurrent use.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// NewFromEnv picks a sender by MAIL_DRIVER:
+//   - "smtp": plain SMTP to SMTP_ADDR (e.g. a local mailpit sink)
+//   - "file" (default): one .eml file per message under MAIL_DIR
+func NewFromEnv() Sender {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@socialnet.local"
+	}
+	switch strings.ToLower(os.Getenv("MAIL_DRIVER")) {
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			addr = "mailpit:1025"
+		}
+		return NewSMTPSender(addr, from, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"))
+	default:
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "mail")
+		}
+		return NewFileSender(dir, from)
+	}
+}
+
+func render(from string, m Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+type fileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir, from string) Sender { return &fileSender{dir: dir, from: from} }
+
+func (s *fileSender) Send(_ context.Context, m Message) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(m.To))
+	path := filepath.Join(s.dir, name)
+	if err := os.WriteFile(path, render(s.from, m), 0o600); err != nil {
+		return err
+	}
+	log.Printf("mail: wrote %q for %s to %s", m.Subject, m.To, path)
+	return nil
+}
+
+type smtpSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPSender(addr, from, user, password string) Sender {
+	s := &smtpSender{addr: addr, from: from}
+	if user != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		s.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return s
+}
+
+func (s *smtpSender) Send(_ context.Context, m Message) error {
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{m.To}, render(s.from, m))
+}
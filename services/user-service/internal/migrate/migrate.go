@@ -1,18 +1,23 @@
+// Package migrate keeps every physical shard's schema in step with the
+// numbered SQL files under sql/, recording applied versions in the
+// schema_migrations table of each shard.
 package migrate
 
 import (
-	"users-service/internal/interest"
-	"users-service/internal/profile"
+	"fmt"
+
 	"users-service/internal/shared/db"
-	"users-service/internal/social"
-	"users-service/internal/user"
 )
 
-func AutoMigrateAll(store *db.Store, shardID int) error {
-	return store.Write(shardID).AutoMigrate(
-		&user.User{},
-		&profile.Profile{},
-		&interest.City{}, &interest.Interest{}, &interest.InterestUser{},
-		&social.Follow{}, &social.Friend{}, &social.Relationship{},
-	)
+// Shards lists every physical shard as a migration target.
+func Shards(store *db.Store) []Target {
+	ids := store.PhysicalIDs()
+	out := make([]Target, len(ids))
+	for i, id := range ids {
+		out[i] = Target{Name: fmt.Sprintf("shard%d", id), DB: store.WritePhysical(id)}
+	}
+	return out
 }
+
+// UpShard applies pending migrations to one physical shard.
+func UpShard(store *db.Store, physical int) error { return Up(store.WritePhysical(physical)) }
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS one_time_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS blocked_bies;
+DROP TABLE IF EXISTS friend_requests;
+DROP TABLE IF EXISTS relationships;
+DROP TABLE IF EXISTS friend_outboxes;
+DROP TABLE IF EXISTS friends;
+DROP TABLE IF EXISTS followers;
+DROP TABLE IF EXISTS follows;
+DROP TABLE IF EXISTS interest_users;
+DROP TABLE IF EXISTS interests;
+DROP TABLE IF EXISTS cities;
+DROP TABLE IF EXISTS profiles;
+DROP TABLE IF EXISTS users;
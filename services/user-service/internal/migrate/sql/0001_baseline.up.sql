@@ -0,0 +1,162 @@
+-- Schema as created by AutoMigrate before versioned migrations existed. Every
+-- statement is idempotent so shards that already have it only get recorded.
+
+CREATE TABLE IF NOT EXISTS users (
+    id           bigserial PRIMARY KEY,
+    user_id      varchar(64),
+    shard_id     bigint,
+    email        varchar(120),
+    pass_hash    varchar(255),
+    name         varchar(100),
+    suspended_at timestamptz,
+    created_at   timestamptz,
+    updated_at   timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_user_id ON users (user_id);
+CREATE INDEX IF NOT EXISTS idx_users_shard_id ON users (shard_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING gin (lower(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users (lower(email) text_pattern_ops);
+
+CREATE TABLE IF NOT EXISTS profiles (
+    user_id     varchar(64) PRIMARY KEY,
+    description text,
+    city_id     bigint,
+    education   jsonb,
+    hobby       jsonb,
+    updated_at  timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS cities (
+    id   bigserial PRIMARY KEY,
+    name varchar(120)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cities_name ON cities (name);
+
+CREATE TABLE IF NOT EXISTS interests (
+    id   bigserial PRIMARY KEY,
+    name varchar(120)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_interests_name ON interests (name);
+
+CREATE TABLE IF NOT EXISTS interest_users (
+    user_id     varchar(64),
+    interest_id bigint,
+    PRIMARY KEY (user_id, interest_id)
+);
+
+CREATE TABLE IF NOT EXISTS follows (
+    user_id    varchar(64),
+    target_id  varchar(64),
+    created_at timestamptz,
+    PRIMARY KEY (user_id, target_id)
+);
+
+CREATE TABLE IF NOT EXISTS followers (
+    user_id     varchar(64),
+    follower_id varchar(64),
+    created_at  timestamptz,
+    PRIMARY KEY (user_id, follower_id)
+);
+
+CREATE TABLE IF NOT EXISTS friends (
+    user_id    varchar(64),
+    friend_id  varchar(64),
+    created_at timestamptz,
+    PRIMARY KEY (user_id, friend_id)
+);
+
+CREATE TABLE IF NOT EXISTS friend_outboxes (
+    id           bigserial PRIMARY KEY,
+    op           varchar(16),
+    user_id      varchar(64),
+    friend_id    varchar(64),
+    attempts     bigint NOT NULL DEFAULT 0,
+    last_error   varchar(500),
+    next_attempt timestamptz,
+    created_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_friend_outbox_edge ON friend_outboxes (user_id, friend_id);
+CREATE INDEX IF NOT EXISTS idx_friend_outboxes_next_attempt ON friend_outboxes (next_attempt);
+
+CREATE TABLE IF NOT EXISTS relationships (
+    user_id    varchar(64),
+    related_id varchar(64),
+    type       bigint,
+    created_at timestamptz,
+    PRIMARY KEY (user_id, related_id, type)
+);
+
+CREATE TABLE IF NOT EXISTS friend_requests (
+    request_id   varchar(64) PRIMARY KEY,
+    from_user_id varchar(64),
+    to_user_id   varchar(64),
+    status       varchar(16),
+    created_at   timestamptz,
+    updated_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_friend_requests_from_user_id ON friend_requests (from_user_id);
+CREATE INDEX IF NOT EXISTS idx_friend_requests_to_user_id ON friend_requests (to_user_id);
+CREATE INDEX IF NOT EXISTS idx_friend_requests_status ON friend_requests (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_friend_requests_pending ON friend_requests (from_user_id, to_user_id) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS blocked_bies (
+    user_id    varchar(64),
+    blocker_id varchar(64),
+    created_at timestamptz,
+    PRIMARY KEY (user_id, blocker_id)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    varchar(64),
+    family_id  varchar(64),
+    token_hash varchar(64),
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_revoked_at ON refresh_tokens (revoked_at);
+
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    varchar(64),
+    purpose    varchar(32),
+    token_hash varchar(64),
+    expires_at timestamptz,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_user_id ON one_time_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_purpose ON one_time_tokens (purpose);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_one_time_tokens_token_hash ON one_time_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_expires_at ON one_time_tokens (expires_at);
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_used_at ON one_time_tokens (used_at);
+
+CREATE TABLE IF NOT EXISTS reports (
+    report_id   varchar(64) PRIMARY KEY,
+    reporter_id varchar(64),
+    target_type varchar(16),
+    target_id   varchar(64),
+    reported_id varchar(64),
+    reason      varchar(32),
+    details     varchar(1000),
+    status      varchar(16),
+    action      varchar(32),
+    reviewer_id varchar(64),
+    note        varchar(1000),
+    resolved_at timestamptz,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_reports_reporter_id ON reports (reporter_id);
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_reports_reported_id ON reports (reported_id);
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_active ON reports (reporter_id, target_type, target_id) WHERE resolved_at IS NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS privacy_settings (
+    user_id     varchar(64) PRIMARY KEY,
+    description varchar(16),
+    city        varchar(16),
+    education   varchar(16),
+    hobby       varchar(16),
+    friends     varchar(16),
+    interests   varchar(16),
+    updated_at  timestamptz
+);
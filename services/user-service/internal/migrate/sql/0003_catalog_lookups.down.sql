@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_profiles_city_id;
+DROP INDEX IF EXISTS idx_interest_users_interest_id;
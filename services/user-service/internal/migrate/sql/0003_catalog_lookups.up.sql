@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_interest_users_interest_id ON interest_users (interest_id);
+CREATE INDEX IF NOT EXISTS idx_profiles_city_id ON profiles (city_id);
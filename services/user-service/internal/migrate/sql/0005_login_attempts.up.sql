@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id         bigserial PRIMARY KEY,
+    attempt_id varchar(32),
+    user_id    varchar(64),
+    email      varchar(120),
+    ip         varchar(64),
+    user_agent varchar(255),
+    outcome    varchar(16),
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_attempt_id ON login_attempts (attempt_id);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_id ON login_attempts (user_id);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts (email);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_created_at ON login_attempts (created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_friends_user_created;
+DROP INDEX IF EXISTS idx_followers_user_created;
+DROP INDEX IF EXISTS idx_follows_user_created;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_follows_user_created ON follows (user_id, created_at DESC, target_id DESC);
+CREATE INDEX IF NOT EXISTS idx_followers_user_created ON followers (user_id, created_at DESC, follower_id DESC);
+CREATE INDEX IF NOT EXISTS idx_friends_user_created ON friends (user_id, created_at DESC, friend_id DESC);
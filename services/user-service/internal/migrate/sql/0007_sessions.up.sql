@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id           varchar(64) PRIMARY KEY,
+    user_id      varchar(64),
+    device_name  varchar(100),
+    ip           varchar(64),
+    user_agent   varchar(255),
+    created_at   timestamptz,
+    last_seen_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_last_seen_at ON sessions (last_seen_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS handle_directory;
+ALTER TABLE users DROP COLUMN IF EXISTS handle;
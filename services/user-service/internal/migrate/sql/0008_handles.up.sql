@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS handle varchar(30);
+
+-- Only the control shard's copy is used; see internal/handle.
+CREATE TABLE IF NOT EXISTS handle_directory (
+    handle     varchar(30) PRIMARY KEY,
+    user_id    varchar(64),
+    state      varchar(16),
+    held_until timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_handle_directory_user_id ON handle_directory (user_id);
+CREATE INDEX IF NOT EXISTS idx_handle_directory_held_until ON handle_directory (held_until);
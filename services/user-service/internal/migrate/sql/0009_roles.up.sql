@@ -0,0 +1,19 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(16) NOT NULL DEFAULT 'user';
+
+-- Only the control shard's copy is used; see internal/audit.
+CREATE TABLE IF NOT EXISTS admin_audit (
+    id         bigserial PRIMARY KEY,
+    entry_id   varchar(32) NOT NULL,
+    service    varchar(32),
+    actor_id   varchar(64),
+    role       varchar(16),
+    action     varchar(160),
+    path       varchar(255),
+    status     bigint,
+    ip         varchar(64),
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_admin_audit_entry_id ON admin_audit (entry_id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_service ON admin_audit (service);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_actor_id ON admin_audit (actor_id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_created_at ON admin_audit (created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS account_archives;
+DROP TABLE IF EXISTS account_steps;
+DROP TABLE IF EXISTS account_requests;
@@ -0,0 +1,27 @@
+-- Only the control shard's copies are used; see internal/account.
+CREATE TABLE IF NOT EXISTS account_requests (
+    request_id   varchar(32) PRIMARY KEY,
+    user_id      varchar(64),
+    kind         varchar(16),
+    state        varchar(16),
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    completed_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_account_requests_user_id ON account_requests (user_id);
+
+CREATE TABLE IF NOT EXISTS account_steps (
+    request_id varchar(32) NOT NULL,
+    service    varchar(32) NOT NULL,
+    state      varchar(16),
+    detail     varchar(255),
+    updated_at timestamptz,
+    PRIMARY KEY (request_id, service)
+);
+
+CREATE TABLE IF NOT EXISTS account_archives (
+    request_id varchar(32) PRIMARY KEY,
+    data       bytea,
+    expires_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_account_archives_expires_at ON account_archives (expires_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_directory;
+ALTER TABLE one_time_tokens DROP COLUMN IF EXISTS data;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified;
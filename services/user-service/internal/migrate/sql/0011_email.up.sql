@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT false;
+ALTER TABLE one_time_tokens ADD COLUMN IF NOT EXISTS data varchar(255);
+
+-- Only the control shard's copy is used; see user.EmailEntry.
+CREATE TABLE IF NOT EXISTS email_directory (
+    email      varchar(120) PRIMARY KEY,
+    user_id    varchar(64),
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_email_directory_user_id ON email_directory (user_id);
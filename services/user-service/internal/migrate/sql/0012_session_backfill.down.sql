@@ -0,0 +1,2 @@
+-- The backfilled sessions cannot be told from real ones, so they stay.
+SELECT 1;
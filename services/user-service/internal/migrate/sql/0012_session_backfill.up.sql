@@ -0,0 +1,8 @@
+-- Refresh no longer creates a missing session, so give every live family
+-- from before sessions were recorded the session it would have had.
+INSERT INTO sessions (id, user_id, device_name, ip, user_agent, created_at, last_seen_at)
+SELECT family_id, min(user_id), '', '', '', min(created_at), max(created_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL AND expires_at > now()
+GROUP BY family_id
+ON CONFLICT (id) DO NOTHING;
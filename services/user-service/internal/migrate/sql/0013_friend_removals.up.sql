@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS friend_removals (
+    user_id    varchar(64),
+    friend_id  varchar(64),
+    removed_at timestamptz,
+    PRIMARY KEY (user_id, friend_id)
+);
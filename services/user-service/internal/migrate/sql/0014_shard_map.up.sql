@@ -0,0 +1,9 @@
+-- Only the control shard's copy is used; see internal/shared/db/shardmap.go.
+CREATE TABLE IF NOT EXISTS shard_map (
+    logical    bigint PRIMARY KEY,
+    physical   bigint,
+    frozen     boolean,
+    version    bigint,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_shard_map_version ON shard_map (version);
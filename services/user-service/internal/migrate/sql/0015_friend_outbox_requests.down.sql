@@ -0,0 +1,2 @@
+ALTER TABLE friend_outboxes DROP COLUMN IF EXISTS status;
+ALTER TABLE friend_outboxes DROP COLUMN IF EXISTS request_id;
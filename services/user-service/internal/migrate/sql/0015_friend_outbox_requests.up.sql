@@ -0,0 +1,2 @@
+ALTER TABLE friend_outboxes ADD COLUMN IF NOT EXISTS request_id varchar(64);
+ALTER TABLE friend_outboxes ADD COLUMN IF NOT EXISTS status varchar(16);
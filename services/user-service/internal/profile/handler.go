@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/validate"
 )
 
 type Handler struct{ svc Service }
@@ -19,6 +20,9 @@ func (h *Handler) Upsert(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
 	if err := h.svc.Upsert(uid, in); err != nil {
 		return err
 	}
@@ -26,8 +30,44 @@ func (h *Handler) Upsert(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 func (h *Handler) GetPublic(w http.ResponseWriter, r *http.Request) error {
-	uid := r.PathValue("user_id")
-	p, err := h.svc.GetPublic(uid)
+	viewer, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	p, err := h.svc.GetPublic(r.PathValue("user_id"), viewer)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, p, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) GetPrivacy(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	p, err := h.svc.GetPrivacy(uid)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, p, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[PrivacyReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	p, err := h.svc.UpdatePrivacy(uid, in)
 	if err != nil {
 		return err
 	}
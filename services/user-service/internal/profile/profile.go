@@ -5,15 +5,98 @@ import "time"
 type Profile struct {
 	UserID      string         `gorm:"primaryKey;size:64" json:"user_id"`
 	Description string         `json:"description"`
-	CityID      uint64         `json:"city_id"`
+	CityID      uint64         `gorm:"index" json:"city_id"`
+	AvatarURL   string         `gorm:"size:512" json:"avatar_url"`
 	Education   map[string]any `gorm:"type:jsonb" json:"education"`
 	Hobby       map[string]any `gorm:"type:jsonb" json:"hobby"`
 	UpdatedAt   time.Time      `json:"updated_at"`
+	// Hidden lists the fields withheld from this viewer.
+	Hidden []string `gorm:"-" json:"hidden,omitempty"`
+}
+
+func (p *Profile) hide(field string) {
+	switch field {
+	case FieldDescription:
+		p.Description = ""
+	case FieldCity:
+		p.CityID = 0
+	case FieldEducation:
+		p.Education = nil
+	case FieldHobby:
+		p.Hobby = nil
+	}
+	p.Hidden = append(p.Hidden, field)
 }
 
 type UpsertReq struct {
 	Description string         `json:"description"`
 	CityID      uint64         `json:"city_id"`
+	AvatarURL   string         `json:"avatar_url" validate:"omitempty,url,max=512"`
 	Education   map[string]any `json:"education"`
 	Hobby       map[string]any `json:"hobby"`
 }
+
+// Audiences a profile field can be shown to.
+const (
+	VisibilityPublic  = "public"
+	VisibilityFriends = "friends"
+	VisibilityPrivate = "private"
+)
+
+// Fields covered by privacy settings.
+const (
+	FieldDescription = "description"
+	FieldCity        = "city"
+	FieldEducation   = "education"
+	FieldHobby       = "hobby"
+	FieldFriends     = "friends"
+	FieldInterests   = "interests"
+)
+
+// Privacy holds who may see each part of a user's profile. Users without a
+// row, and empty columns, are public.
+type Privacy struct {
+	UserID      string    `gorm:"primaryKey;size:64" json:"user_id"`
+	Description string    `gorm:"size:16" json:"description"`
+	City        string    `gorm:"size:16" json:"city"`
+	Education   string    `gorm:"size:16" json:"education"`
+	Hobby       string    `gorm:"size:16" json:"hobby"`
+	Friends     string    `gorm:"size:16" json:"friends"`
+	Interests   string    `gorm:"size:16" json:"interests"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Privacy) TableName() string { return "privacy_settings" }
+
+// Level returns the audience of one field.
+func (p *Privacy) Level(field string) string {
+	var v string
+	switch field {
+	case FieldDescription:
+		v = p.Description
+	case FieldCity:
+		v = p.City
+	case FieldEducation:
+		v = p.Education
+	case FieldHobby:
+		v = p.Hobby
+	case FieldFriends:
+		v = p.Friends
+	case FieldInterests:
+		v = p.Interests
+	}
+	if v == "" {
+		return VisibilityPublic
+	}
+	return v
+}
+
+// PrivacyReq changes the given fields and leaves omitted ones as they are.
+type PrivacyReq struct {
+	Description *string `json:"description" validate:"omitempty,oneof=public friends private"`
+	City        *string `json:"city" validate:"omitempty,oneof=public friends private"`
+	Education   *string `json:"education" validate:"omitempty,oneof=public friends private"`
+	Hobby       *string `json:"hobby" validate:"omitempty,oneof=public friends private"`
+	Friends     *string `json:"friends" validate:"omitempty,oneof=public friends private"`
+	Interests   *string `json:"interests" validate:"omitempty,oneof=public friends private"`
+}
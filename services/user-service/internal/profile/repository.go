@@ -1,15 +1,21 @@
 package profile
 
 import (
+	"errors"
+
 	"users-service/internal/shared/db"
 	"users-service/internal/shared/shard"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
 	Upsert(p *Profile) error
 	GetPublic(userID string) (*Profile, error)
+	// GetPrivacy returns uid's settings, all public if none were saved.
+	GetPrivacy(uid string) (*Privacy, error)
+	SavePrivacy(p *Privacy) error
 }
 type repo struct{ store *db.Store }
 
@@ -19,14 +25,33 @@ func (r *repo) Upsert(p *Profile) error {
 	sh, _ := shard.Extract(p.UserID)
 	return r.store.Write(sh).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "user_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"description", "city_id", "education", "hobby", "updated_at"}),
+		DoUpdates: clause.AssignmentColumns([]string{"description", "city_id", "avatar_url", "education", "hobby", "updated_at"}),
 	}).Create(p).Error
 }
 func (r *repo) GetPublic(uid string) (*Profile, error) {
-	sh, _ := shard.Extract(uid)
 	var p Profile
-	if err := r.store.Use(sh).First(&p, "user_id = ?", uid).Error; err != nil {
+	if err := r.store.UseFor(uid).First(&p, "user_id = ?", uid).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *repo) GetPrivacy(uid string) (*Privacy, error) {
+	var p Privacy
+	err := r.store.UseFor(uid).First(&p, "user_id = ?", uid).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Privacy{UserID: uid}, nil
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &p, nil
 }
+
+func (r *repo) SavePrivacy(p *Privacy) error {
+	sh, _ := shard.Extract(p.UserID)
+	return r.store.Write(sh).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "city", "education", "hobby", "friends", "interests", "updated_at"}),
+	}).Create(p).Error
+}
@@ -1,19 +1,160 @@
 package profile
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"users-service/internal/events"
+	"users-service/internal/shared/httpx"
+)
 
 type Service interface {
 	Upsert(uid string, in UpsertReq) error
-	GetPublic(uid string) (*Profile, error)
+	// GetPublic returns owner's profile as viewer may see it; fields the
+	// owner's privacy settings withhold are blanked and listed in Hidden.
+	GetPublic(owner, viewer string) (*Profile, error)
+	GetPrivacy(uid string) (*Privacy, error)
+	UpdatePrivacy(uid string, in PrivacyReq) (*Privacy, error)
+	// CanSee reports whether viewer may see one of owner's fields.
+	CanSee(owner, viewer, field string) (bool, error)
+}
+
+// Relations tells how two users are connected.
+type Relations interface {
+	// IsFriend reports a friendship from a Friend edge or a friend
+	// relationship.
+	IsFriend(owner, viewer string) (bool, error)
+	IsBlocked(a, b string) (bool, error)
+}
+
+// CardCache holds the user cards built from profiles and privacy settings.
+type CardCache interface {
+	ForgetCard(uid string)
 }
-type service struct{ repo Repository }
 
-func NewService(r Repository) Service { return &service{repo: r} }
+var ErrHidden = fmt.Errorf("%w: hidden by the owner's privacy settings", httpx.ErrForbidden)
+
+type service struct {
+	repo   Repository
+	events *events.Publisher
+	rel    Relations
+	cards  CardCache
+}
+
+func NewService(r Repository, pub *events.Publisher, rel Relations, cards CardCache) Service {
+	return &service{repo: r, events: pub, rel: rel, cards: cards}
+}
 
 func (s *service) Upsert(uid string, in UpsertReq) error {
-	return s.repo.Upsert(&Profile{
-		UserID: uid, Description: in.Description, CityID: in.CityID,
+	if err := s.repo.Upsert(&Profile{
+		UserID: uid, Description: in.Description, CityID: in.CityID, AvatarURL: in.AvatarURL,
 		Education: in.Education, Hobby: in.Hobby, UpdatedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+	s.cards.ForgetCard(uid)
+	s.events.Publish(events.TopicProfileUpdated, uid, events.ProfileUpdated{
+		Meta:   events.NewMeta(events.TopicProfileUpdated),
+		UserID: uid, Description: in.Description, CityID: in.CityID, AvatarURL: in.AvatarURL,
+		Education: in.Education, Hobby: in.Hobby,
 	})
+	return nil
+}
+
+func (s *service) GetPublic(owner, viewer string) (*Profile, error) {
+	p, err := s.repo.GetPublic(owner)
+	if err != nil {
+		return nil, err
+	}
+	if owner == viewer {
+		return p, nil
+	}
+	priv, err := s.repo.GetPrivacy(owner)
+	if err != nil {
+		return nil, err
+	}
+	var aud string
+	for _, f := range []string{FieldDescription, FieldCity, FieldEducation, FieldHobby} {
+		level := priv.Level(f)
+		if level == VisibilityPublic {
+			continue
+		}
+		if aud == "" {
+			if aud, err = s.audience(owner, viewer); err != nil {
+				return nil, err
+			}
+		}
+		if !allows(level, aud) {
+			p.hide(f)
+		}
+	}
+	return p, nil
+}
+
+func (s *service) GetPrivacy(uid string) (*Privacy, error) { return s.repo.GetPrivacy(uid) }
+
+func (s *service) UpdatePrivacy(uid string, in PrivacyReq) (*Privacy, error) {
+	p, err := s.repo.GetPrivacy(uid)
+	if err != nil {
+		return nil, err
+	}
+	set := func(dst *string, v *string) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	set(&p.Description, in.Description)
+	set(&p.City, in.City)
+	set(&p.Education, in.Education)
+	set(&p.Hobby, in.Hobby)
+	set(&p.Friends, in.Friends)
+	set(&p.Interests, in.Interests)
+	p.UpdatedAt = time.Now()
+	if err := s.repo.SavePrivacy(p); err != nil {
+		return nil, err
+	}
+	s.cards.ForgetCard(uid)
+	return p, nil
+}
+
+func (s *service) CanSee(owner, viewer, field string) (bool, error) {
+	if owner == viewer {
+		return true, nil
+	}
+	priv, err := s.repo.GetPrivacy(owner)
+	if err != nil {
+		return false, err
+	}
+	level := priv.Level(field)
+	if level == VisibilityPublic {
+		return true, nil
+	}
+	aud, err := s.audience(owner, viewer)
+	if err != nil {
+		return false, err
+	}
+	return allows(level, aud), nil
+}
+
+// audience is what viewer counts as for owner's settings. A blocked pair is
+// never friends, whatever edges are left.
+func (s *service) audience(owner, viewer string) (string, error) {
+	if blocked, err := s.rel.IsBlocked(owner, viewer); err != nil || blocked {
+		return VisibilityPublic, err
+	}
+	friend, err := s.rel.IsFriend(owner, viewer)
+	if err != nil || !friend {
+		return VisibilityPublic, err
+	}
+	return VisibilityFriends, nil
+}
+
+func allows(level, audience string) bool {
+	switch level {
+	case VisibilityPublic:
+		return true
+	case VisibilityFriends:
+		return audience == VisibilityFriends
+	}
+	return false
 }
-func (s *service) GetPublic(uid string) (*Profile, error) { return s.repo.GetPublic(uid) }
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type Limiter struct{ R *redis.Client }
+
+func New(r *redis.Client) *Limiter { return &Limiter{R: r} }
+
+func OpenFromEnv() *Limiter {
+	addr := os.Getenv("RATELIMIT_REDIS_ADDR")
+	if addr == "" {
+		addr = "redis-users:6379"
+	}
+	return New(redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 500 * time.Millisecond,
+	}))
+}
+
+func (l *Limiter) Close() error { return l.R.Close() }
+
+// Fail counts one failure under key and returns the failures seen since the
+// counter was last idle for window.
+func (l *Limiter) Fail(ctx context.Context, key string, window time.Duration) (int64, error) {
+	k := "fail:" + key
+	pipe := l.R.TxPipeline()
+	incr := pipe.Incr(ctx, k)
+	pipe.Expire(ctx, k, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// Lock blocks key for d, or longer if it is already locked for longer.
+func (l *Limiter) Lock(ctx context.Context, key string, d time.Duration) error {
+	k := "lock:" + key
+	pipe := l.R.TxPipeline()
+	pipe.SetNX(ctx, k, 1, d)
+	pipe.ExpireGT(ctx, k, d)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LockedFor returns how long the longest lock among keys has left, zero if
+// none is locked.
+func (l *Limiter) LockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	pipe := l.R.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, k := range keys {
+		ttls[i] = pipe.PTTL(ctx, "lock:"+k)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	var out time.Duration
+	for _, c := range ttls {
+		out = max(out, c.Val())
+	}
+	return out, nil
+}
+
+// Clear forgets the failures and lock of each key.
+func (l *Limiter) Clear(ctx context.Context, keys ...string) error {
+	all := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		all = append(all, "fail:"+k, "lock:"+k)
+	}
+	return l.R.Del(ctx, all...).Err()
+}
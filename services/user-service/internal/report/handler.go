@@ -0,0 +1,82 @@
+package report
+
+import (
+	"net/http"
+
+	"users-service/internal/shared/httpx"
+	"users-service/internal/shared/validate"
+)
+
+type Handler struct{ svc Service }
+
+func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[CreateReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	rep, created, err := h.svc.Create(uid, in)
+	if err != nil {
+		return err
+	}
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	httpx.WriteJSON(w, rep, code)
+	return nil
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) error {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = StatusOpen
+	} else if status == "all" {
+		status = ""
+	}
+	limit := httpx.QueryInt(r, "limit", 50)
+	offset := httpx.QueryInt(r, "offset", 0)
+	items, err := h.svc.List(status, r.URL.Query().Get("target_type"), limit, offset)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) error {
+	rep, err := h.svc.Get(r.PathValue("report_id"))
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, rep, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Review(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[ReviewReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	rep, err := h.svc.Review(uid, httpx.RoleFromCtx(r), r.PathValue("report_id"), in)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, rep, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,63 @@
+package report
+
+import "time"
+
+const (
+	TargetUser    = "user"
+	TargetPost    = "post"
+	TargetComment = "comment"
+	TargetMessage = "message"
+	TargetChat    = "chat"
+)
+
+const (
+	StatusOpen      = "open"
+	StatusTriaged   = "triaged"
+	StatusActioned  = "actioned"
+	StatusDismissed = "dismissed"
+)
+
+const (
+	ActionNone          = "none"
+	ActionRemoveContent = "remove_content"
+	ActionSuspendUser   = "suspend_user"
+)
+
+// Report is one user's complaint about a user or a piece of content. All
+// reports live on a single shard (REPORTS_SHARD) so the moderation queue can
+// be listed and ordered without fanning out.
+type Report struct {
+	ReportID   string `gorm:"primaryKey;size:64" json:"report_id"`
+	ReporterID string `gorm:"size:64;index;index:idx_reports_active,unique,where:resolved_at IS NULL" json:"reporter_id"`
+	TargetType string `gorm:"size:16;index:idx_reports_target;index:idx_reports_active,unique" json:"target_type"`
+	TargetID   string `gorm:"size:64;index:idx_reports_target;index:idx_reports_active,unique" json:"target_id"`
+	// ReportedID is the user behind the target. For content it comes from the
+	// owning service, not the reporter.
+	ReportedID string     `gorm:"size:64;index" json:"reported_id,omitempty"`
+	Reason     string     `gorm:"size:32" json:"reason"`
+	Details    string     `gorm:"size:1000" json:"details,omitempty"`
+	Status     string     `gorm:"size:16;index" json:"status"`
+	Action     string     `gorm:"size:32" json:"action,omitempty"`
+	ReviewerID string     `gorm:"size:64" json:"reviewer_id,omitempty"`
+	Note       string     `gorm:"size:1000" json:"note,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// CreateReq files a report. The older {reported_id, reason} form is still
+// accepted and treated as a report against that user; for content targets
+// reported_id is ignored.
+type CreateReq struct {
+	TargetType string `json:"target_type" validate:"omitempty,oneof=user post comment message chat"`
+	TargetID   string `json:"target_id" validate:"max=64"`
+	ReportedID string `json:"reported_id" validate:"max=64"`
+	Reason     string `json:"reason" validate:"required,oneof=spam harassment hate_speech violence nudity self_harm impersonation misinformation illegal other"`
+	Details    string `json:"details" validate:"max=1000"`
+}
+
+type ReviewReq struct {
+	Status string `json:"status" validate:"required,oneof=triaged actioned dismissed"`
+	Action string `json:"action" validate:"omitempty,oneof=none remove_content suspend_user"`
+	Note   string `json:"note" validate:"max=1000"`
+}
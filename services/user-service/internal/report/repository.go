@@ -0,0 +1,87 @@
+package report
+
+import (
+	"time"
+
+	"users-service/internal/shared/db"
+)
+
+type Repository interface {
+	Create(rep *Report) error
+	Get(reportID string) (*Report, error)
+	// FindActive returns reporter's unresolved report on the target, if any.
+	FindActive(reporterID, targetType, targetID string) (*Report, error)
+	List(status, targetType string, limit, offset int) ([]Report, error)
+	// Transition moves a report out of `from`; false means it was no longer
+	// in that status.
+	Transition(rep *Report, from string) (bool, error)
+	// ResolveTarget closes every other unresolved report on the same target.
+	ResolveTarget(rep *Report) error
+}
+
+type repo struct {
+	store *db.Store
+	home  int
+}
+
+func NewRepository(s *db.Store, homeShard int) Repository { return &repo{store: s, home: homeShard} }
+
+func (r *repo) Create(rep *Report) error { return r.store.Write(r.home).Create(rep).Error }
+
+func (r *repo) Get(reportID string) (*Report, error) {
+	var rep Report
+	if err := r.store.Use(r.home).First(&rep, "report_id = ?", reportID).Error; err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+func (r *repo) FindActive(reporterID, targetType, targetID string) (*Report, error) {
+	var rep Report
+	err := r.store.Write(r.home).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND resolved_at IS NULL", reporterID, targetType, targetID).
+		First(&rep).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+func (r *repo) List(status, targetType string, limit, offset int) ([]Report, error) {
+	q := r.store.Use(r.home).Model(&Report{})
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if targetType != "" {
+		q = q.Where("target_type = ?", targetType)
+	}
+	var out []Report
+	err := q.Order("created_at ASC").Limit(limit).Offset(offset).Find(&out).Error
+	return out, err
+}
+
+func (r *repo) Transition(rep *Report, from string) (bool, error) {
+	res := r.store.Write(r.home).Model(&Report{}).
+		Where("report_id = ? AND status = ?", rep.ReportID, from).
+		Updates(map[string]any{
+			"status":      rep.Status,
+			"action":      rep.Action,
+			"reviewer_id": rep.ReviewerID,
+			"note":        rep.Note,
+			"resolved_at": rep.ResolvedAt,
+			"updated_at":  time.Now().UTC(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *repo) ResolveTarget(rep *Report) error {
+	return r.store.Write(r.home).Model(&Report{}).
+		Where("target_type = ? AND target_id = ? AND resolved_at IS NULL AND report_id <> ?", rep.TargetType, rep.TargetID, rep.ReportID).
+		Updates(map[string]any{
+			"status":      rep.Status,
+			"action":      rep.Action,
+			"reviewer_id": rep.ReviewerID,
+			"resolved_at": rep.ResolvedAt,
+			"updated_at":  time.Now().UTC(),
+		}).Error
+}
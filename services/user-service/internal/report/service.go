@@ -0,0 +1,207 @@
+package report
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"users-service/internal/content"
+	"users-service/internal/shared/httpx"
+)
+
+type Service interface {
+	// Create files a report. Reporting the same target again while the first
+	// report is still unresolved returns the existing report.
+	Create(reporterID string, in CreateReq) (*Report, bool, error)
+	Get(reportID string) (*Report, error)
+	List(status, targetType string, limit, offset int) ([]Report, error)
+	// Review records a moderator's decision. Actioning a report runs its
+	// action first, with the reviewer's role, and leaves the report untouched
+	// if that fails.
+	Review(reviewerID, reviewerRole, reportID string, in ReviewReq) (*Report, error)
+}
+
+// ContentRemover looks up and deletes content held by the owning service.
+type ContentRemover interface {
+	// Owner returns the author of a piece of content, or content.ErrNotFound
+	// if it no longer exists.
+	Owner(ctx context.Context, kind, id string) (string, error)
+	Remove(ctx context.Context, kind, id string) error
+}
+
+// Suspender locks user accounts on behalf of a caller holding role.
+type Suspender interface {
+	SuspendBy(role, uid string) error
+}
+
+type service struct {
+	repo    Repository
+	content ContentRemover
+	users   Suspender
+}
+
+func NewService(r Repository, content ContentRemover, users Suspender) Service {
+	return &service{repo: r, content: content, users: users}
+}
+
+var (
+	ErrReportNotFound = fmt.Errorf("%w: report", httpx.ErrNotFound)
+	ErrReportClosed   = errors.New("report is already resolved")
+	ErrNoTarget       = errors.New("target_id or reported_id is required")
+	ErrSelfReport     = errors.New("cannot report yourself")
+	ErrBadAction      = errors.New("action does not apply to this report")
+	ErrTargetGone     = fmt.Errorf("%w: reported content", httpx.ErrNotFound)
+)
+
+func newReportID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (s *service) Create(reporterID string, in CreateReq) (*Report, bool, error) {
+	if in.TargetType == "" {
+		in.TargetType = TargetUser
+	}
+	if in.TargetType == TargetUser {
+		if in.TargetID == "" {
+			in.TargetID = in.ReportedID
+		}
+		in.ReportedID = in.TargetID
+	}
+	if in.TargetID == "" {
+		return nil, false, ErrNoTarget
+	}
+	if in.TargetType != TargetUser {
+		// The reporter's reported_id is not trusted for content; the owning
+		// service says who wrote it.
+		owner, err := s.owner(in.TargetType, in.TargetID)
+		if err != nil {
+			return nil, false, err
+		}
+		in.ReportedID = owner
+	}
+	if in.ReportedID == reporterID {
+		return nil, false, ErrSelfReport
+	}
+	if rep, err := s.repo.FindActive(reporterID, in.TargetType, in.TargetID); err == nil {
+		return rep, false, nil
+	}
+	rep := &Report{
+		ReportID:   newReportID(),
+		ReporterID: reporterID,
+		TargetType: in.TargetType,
+		TargetID:   in.TargetID,
+		ReportedID: in.ReportedID,
+		Reason:     in.Reason,
+		Details:    in.Details,
+		Status:     StatusOpen,
+	}
+	if err := s.repo.Create(rep); err != nil {
+		return nil, false, err
+	}
+	return rep, true, nil
+}
+
+func (s *service) Get(reportID string) (*Report, error) {
+	rep, err := s.repo.Get(reportID)
+	if err != nil {
+		return nil, ErrReportNotFound
+	}
+	return rep, nil
+}
+
+func (s *service) List(status, targetType string, limit, offset int) ([]Report, error) {
+	return s.repo.List(status, targetType, limit, offset)
+}
+
+func (s *service) Review(reviewerID, reviewerRole, reportID string, in ReviewReq) (*Report, error) {
+	rep, err := s.Get(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if rep.ResolvedAt != nil {
+		return nil, ErrReportClosed
+	}
+	action := in.Action
+	if action == "" {
+		action = ActionNone
+	}
+	if in.Status != StatusActioned && action != ActionNone {
+		return nil, ErrBadAction
+	}
+	if in.Status == StatusActioned {
+		if err := s.apply(rep, action, reviewerRole); err != nil {
+			return nil, err
+		}
+	}
+
+	from := rep.Status
+	rep.Status = in.Status
+	rep.ReviewerID = reviewerID
+	rep.Note = in.Note
+	if action != ActionNone {
+		rep.Action = action
+	}
+	if in.Status != StatusTriaged {
+		now := time.Now().UTC()
+		rep.ResolvedAt = &now
+	}
+	ok, err := s.repo.Transition(rep, from)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrReportClosed
+	}
+	if rep.Status == StatusActioned && action != ActionNone {
+		if err := s.repo.ResolveTarget(rep); err != nil {
+			return nil, err
+		}
+	}
+	return rep, nil
+}
+
+// apply carries out a moderation action against the report's target.
+func (s *service) apply(rep *Report, action, role string) error {
+	switch action {
+	case ActionNone:
+		return nil
+	case ActionRemoveContent:
+		if rep.TargetType == TargetUser {
+			return ErrBadAction
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return s.content.Remove(ctx, rep.TargetType, rep.TargetID)
+	case ActionSuspendUser:
+		uid := rep.ReportedID
+		if rep.TargetType != TargetUser {
+			// Reports filed before owners were looked up carry whatever the
+			// reporter sent, so ask again.
+			owner, err := s.owner(rep.TargetType, rep.TargetID)
+			if err != nil {
+				return err
+			}
+			uid = owner
+		}
+		if uid == "" {
+			return ErrBadAction
+		}
+		return s.users.SuspendBy(role, uid)
+	}
+	return ErrBadAction
+}
+
+func (s *service) owner(kind, id string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	owner, err := s.content.Owner(ctx, kind, id)
+	if errors.Is(err, content.ErrNotFound) {
+		return "", ErrTargetGone
+	}
+	return owner, err
+}
@@ -0,0 +1,318 @@
+// Package reshard moves a logical shard from one physical database to another
+// while the service keeps running.
+//
+// A move copies the logical shard's rows while it stays writable, freezes it
+// (writes fail with db.ErrShardFrozen, reads continue), copies again to pick
+// up late changes, then points the shard map at the new database. Running
+// instances follow the map within SHARD_MAP_REFRESH, so Settle must be longer
+// than that.
+package reshard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"users-service/internal/auth"
+	"users-service/internal/interest"
+	"users-service/internal/migrate"
+	"users-service/internal/profile"
+	"users-service/internal/report"
+	"users-service/internal/shared/db"
+	"users-service/internal/shared/shard"
+	"users-service/internal/social"
+	"users-service/internal/user"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const batchSize = 500
+
+// table describes how rows of one logical shard are found in a table.
+type table struct {
+	model any
+	// owners are user id columns; a row belongs to every logical shard named
+	// by one of them.
+	owners []string
+	// keys identify a row across databases.
+	keys []string
+	// serial tables have a database-assigned id that is not copied.
+	serial bool
+	// all marks tables that belong wholesale to one logical shard.
+	all bool
+}
+
+func tables(reportsShard, logical int) []table {
+	ts := []table{
+		{model: &user.User{}, owners: []string{"user_id"}, keys: []string{"user_id"}, serial: true},
+		{model: &profile.Profile{}, owners: []string{"user_id"}, keys: []string{"user_id"}},
+		{model: &profile.Privacy{}, owners: []string{"user_id"}, keys: []string{"user_id"}},
+		{model: &interest.InterestUser{}, owners: []string{"user_id"}, keys: []string{"user_id", "interest_id"}},
+		{model: &social.Follow{}, owners: []string{"user_id"}, keys: []string{"user_id", "target_id"}},
+		{model: &social.Follower{}, owners: []string{"user_id"}, keys: []string{"user_id", "follower_id"}},
+		{model: &social.Friend{}, owners: []string{"user_id"}, keys: []string{"user_id", "friend_id"}},
+		{model: &social.FriendRemoval{}, owners: []string{"user_id"}, keys: []string{"user_id", "friend_id"}},
+		{model: &social.Relationship{}, owners: []string{"user_id"}, keys: []string{"user_id", "related_id", "type"}},
+		{model: &social.BlockedBy{}, owners: []string{"user_id"}, keys: []string{"user_id", "blocker_id"}},
+		{model: &social.FriendRequest{}, owners: []string{"from_user_id", "to_user_id"}, keys: []string{"request_id"}},
+		{model: &auth.RefreshToken{}, owners: []string{"user_id"}, keys: []string{"token_hash"}, serial: true},
+		{model: &auth.Session{}, owners: []string{"user_id"}, keys: []string{"id"}},
+		{model: &auth.OneTimeToken{}, owners: []string{"user_id"}, keys: []string{"token_hash"}, serial: true},
+		{model: &user.LoginAttempt{}, owners: []string{"user_id"}, keys: []string{"attempt_id"}, serial: true},
+	}
+	if logical == reportsShard {
+		ts = append(ts, table{model: &report.Report{}, keys: []string{"report_id"}, all: true})
+	}
+	return ts
+}
+
+type Mover struct {
+	Store *db.Store
+	// Settle is how long to wait after a map change before relying on every
+	// instance having seen it.
+	Settle time.Duration
+	// ReportsShard is the logical shard holding the moderation queue.
+	ReportsShard int
+	// Catalog brings a database's cities and interests in line with the
+	// control shard, so interest_users and profiles keep valid ids when
+	// copied.
+	Catalog interface{ SyncCatalog(physical int) error }
+}
+
+// Move relocates logical to the physical shard `to`. With cleanup set, the
+// rows left behind on the old database are deleted once the map has flipped.
+func (m *Mover) Move(ctx context.Context, logical, to int, cleanup bool) error {
+	from := m.Store.Physical(logical)
+	if from == to {
+		return fmt.Errorf("logical shard %d already lives on physical shard %d", logical, to)
+	}
+	if _, ok := m.Store.ShardInfo(to); !ok {
+		return fmt.Errorf("unknown physical shard %d", to)
+	}
+	if err := migrate.UpShard(m.Store, to); err != nil {
+		return fmt.Errorf("migrate target: %w", err)
+	}
+	for _, p := range []int{from, to} {
+		if err := m.Catalog.SyncCatalog(p); err != nil {
+			return fmt.Errorf("catalog on %d: %w", p, err)
+		}
+	}
+	ts := tables(m.ReportsShard, logical)
+
+	log.Printf("reshard: copying logical %d from %d to %d", logical, from, to)
+	if err := m.copyAll(ctx, ts, logical, from, to, false); err != nil {
+		return err
+	}
+
+	if _, err := m.Store.AssignShard(logical, from, true); err != nil {
+		return fmt.Errorf("freeze: %w", err)
+	}
+	log.Printf("reshard: logical %d frozen, waiting %s", logical, m.Settle)
+	if err := m.wait(ctx); err != nil {
+		return m.unfreeze(logical, from, err)
+	}
+	if err := m.copyAll(ctx, ts, logical, from, to, true); err != nil {
+		return m.unfreeze(logical, from, err)
+	}
+
+	ver, err := m.Store.AssignShard(logical, to, false)
+	if err != nil {
+		return m.unfreeze(logical, from, fmt.Errorf("flip: %w", err))
+	}
+	log.Printf("reshard: logical %d now on %d (map version %d)", logical, to, ver)
+
+	if !cleanup {
+		return nil
+	}
+	if err := m.wait(ctx); err != nil {
+		return err
+	}
+	return m.cleanup(ctx, ts, logical, from)
+}
+
+func (m *Mover) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(m.Settle):
+		return nil
+	}
+}
+
+func (m *Mover) unfreeze(logical, from int, cause error) error {
+	if _, err := m.Store.AssignShard(logical, from, false); err != nil {
+		return fmt.Errorf("%w (and unfreeze failed: %v)", cause, err)
+	}
+	return cause
+}
+
+func tableName(tx *gorm.DB, model any) (string, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
+
+// owned selects the rows of one logical shard.
+func owned(tx *gorm.DB, t table, logical int) *gorm.DB {
+	if t.all {
+		return tx
+	}
+	prefix := fmt.Sprintf("%d-%%", logical)
+	conds := make([]string, len(t.owners))
+	args := make([]any, len(t.owners))
+	for i, c := range t.owners {
+		conds[i] = c + " LIKE ?"
+		args[i] = prefix
+	}
+	return tx.Where(strings.Join(conds, " OR "), args...)
+}
+
+func rowKey(keys []string, row map[string]any) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprint(row[k])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// copyAll upserts every row of the logical shard into the target. With prune
+// set it also deletes target rows that no longer exist on the source, which
+// is only exact while the shard is frozen.
+func (m *Mover) copyAll(ctx context.Context, ts []table, logical, from, to int, prune bool) error {
+	for _, t := range ts {
+		name, err := tableName(m.Store.Base, t.model)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]struct{})
+		n := 0
+		for offset := 0; ; offset += batchSize {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var rows []map[string]any
+			q := owned(m.Store.WritePhysical(from).Table(name), t, logical)
+			if err := q.Order(strings.Join(t.keys, ", ")).Limit(batchSize).Offset(offset).Find(&rows).Error; err != nil {
+				return fmt.Errorf("read %s: %w", name, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+			for _, row := range rows {
+				if t.serial {
+					delete(row, "id")
+				}
+				seen[rowKey(t.keys, row)] = struct{}{}
+			}
+			if err := upsert(m.Store.WritePhysical(to).Table(name), t.keys, rows); err != nil {
+				return fmt.Errorf("write %s: %w", name, err)
+			}
+			n += len(rows)
+			if len(rows) < batchSize {
+				break
+			}
+		}
+		removed := 0
+		if prune {
+			if removed, err = m.prune(name, t, logical, to, seen); err != nil {
+				return err
+			}
+		}
+		log.Printf("reshard: %s: %d copied, %d pruned", name, n, removed)
+	}
+	return nil
+}
+
+func upsert(tx *gorm.DB, keys []string, rows []map[string]any) error {
+	cols := make([]clause.Column, len(keys))
+	isKey := make(map[string]bool, len(keys))
+	for i, k := range keys {
+		cols[i] = clause.Column{Name: k}
+		isKey[k] = true
+	}
+	var update []string
+	for c := range rows[0] {
+		if !isKey[c] {
+			update = append(update, c)
+		}
+	}
+	sort.Strings(update)
+	oc := clause.OnConflict{Columns: cols, DoNothing: len(update) == 0}
+	if len(update) > 0 {
+		oc.DoUpdates = clause.AssignmentColumns(update)
+	}
+	return tx.Clauses(oc).Create(&rows).Error
+}
+
+func (m *Mover) prune(name string, t table, logical, to int, keep map[string]struct{}) (int, error) {
+	var rows []map[string]any
+	if err := owned(m.Store.WritePhysical(to).Table(name), t, logical).Select(t.keys).Find(&rows).Error; err != nil {
+		return 0, fmt.Errorf("scan %s on target: %w", name, err)
+	}
+	n := 0
+	for _, row := range rows {
+		if _, ok := keep[rowKey(t.keys, row)]; ok {
+			continue
+		}
+		if err := deleteRow(m.Store.WritePhysical(to).Table(name), t.keys, row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func deleteRow(tx *gorm.DB, keys []string, row map[string]any) error {
+	for _, k := range keys {
+		tx = tx.Where(k+" = ?", row[k])
+	}
+	return tx.Delete(nil).Error
+}
+
+// cleanup removes the moved shard's rows from its old database. Rows another
+// logical shard on that database still owns (e.g. the other side of a friend
+// request) are kept.
+func (m *Mover) cleanup(ctx context.Context, ts []table, logical, from int) error {
+	for _, t := range ts {
+		name, err := tableName(m.Store.Base, t.model)
+		if err != nil {
+			return err
+		}
+		var rows []map[string]any
+		cols := append(append([]string{}, t.keys...), t.owners...)
+		if err := owned(m.Store.WritePhysical(from).Table(name), t, logical).Select(cols).Find(&rows).Error; err != nil {
+			return fmt.Errorf("scan %s: %w", name, err)
+		}
+		n := 0
+		for _, row := range rows {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if m.stillOwned(t, row, logical, from) {
+				continue
+			}
+			if err := deleteRow(m.Store.WritePhysical(from).Table(name), t.keys, row); err != nil {
+				return fmt.Errorf("delete from %s: %w", name, err)
+			}
+			n++
+		}
+		log.Printf("reshard: %s: %d rows removed from %d", name, n, from)
+	}
+	return nil
+}
+
+func (m *Mover) stillOwned(t table, row map[string]any, logical, from int) bool {
+	for _, c := range t.owners {
+		uid, _ := row[c].(string)
+		if l, ok := shard.Extract(uid); ok && l != logical && m.Store.Physical(l) == from {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package search
+
+import (
+	"net/http"
+
+	"users-service/internal/shared/httpx"
+)
+
+type Handler struct{ svc Service }
+
+func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+
+func (h *Handler) Users(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	page, err := h.svc.Search(r.Context(), uid, q.Get("q"), q.Get("cursor"), httpx.QueryInt(r, "limit", 20))
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, page, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"users-service/internal/shared/db"
+)
+
+type Repository interface {
+	Shards() []int
+	// SearchShard returns up to limit hits from one physical shard, ranked and
+	// starting after `from` when it is set.
+	SearchShard(ctx context.Context, physical int, q string, from *position, limit int) ([]Hit, error)
+}
+
+type repo struct{ store *db.Store }
+
+func NewRepository(s *db.Store) Repository { return &repo{store: s} }
+
+func (r *repo) Shards() []int { return r.store.PhysicalIDs() }
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// score favours email prefix matches, then name prefix matches, then trigram
+// similarity of the name. Matching rows are served by idx_users_name_trgm and
+// idx_users_email_prefix.
+const score = `GREATEST(
+	similarity(lower(name), @q)::float8,
+	CASE WHEN lower(name) LIKE @prefix THEN 0.9 ELSE 0 END,
+	CASE WHEN lower(email) LIKE @prefix THEN 1.0 ELSE 0 END
+)`
+
+func (r *repo) SearchShard(ctx context.Context, physical int, q string, from *position, limit int) ([]Hit, error) {
+	// Rows of a logical shard being moved can exist on two databases; only
+	// the copy the shard map points at counts.
+	logical := r.store.LogicalOn(physical)
+	if len(logical) == 0 {
+		return nil, nil
+	}
+	args := map[string]any{
+		"q":       q,
+		"prefix":  likeEscaper.Replace(q) + "%",
+		"infix":   "%" + likeEscaper.Replace(q) + "%",
+		"logical": logical,
+	}
+	inner := r.store.UsePhysical(physical).WithContext(ctx).Table("users").
+		Select("user_id, name, "+score+" AS score", args).
+		Where("shard_id IN @logical AND suspended_at IS NULL", args).
+		Where("lower(name) LIKE @infix OR lower(name) % @q OR lower(email) LIKE @prefix", args)
+
+	tx := r.store.UsePhysical(physical).WithContext(ctx).Table("(?) AS hits", inner)
+	if from != nil {
+		tx = tx.Where("score < ? OR (score = ? AND user_id > ?)", from.Score, from.Score, from.UserID)
+	}
+	var out []Hit
+	err := tx.Order("score DESC, user_id ASC").Limit(limit).Scan(&out).Error
+	return out, err
+}
@@ -0,0 +1,22 @@
+package search
+
+// Hit is one user matching a search. Score is the rank the results are
+// ordered by, highest first.
+type Hit struct {
+	UserID string  `json:"user_id"`
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+}
+
+type Page struct {
+	Results    []Hit  `json:"results"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Partial is set when some shards did not answer in time.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// position is the last hit of a page; the next page starts right after it.
+type position struct {
+	Score  float64 `json:"s"`
+	UserID string  `json:"u"`
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"users-service/internal/shared/cursor"
+)
+
+type Service interface {
+	// Search looks for users by name or email prefix on every shard. The
+	// viewer's blocked users are left out.
+	Search(ctx context.Context, viewer, q, after string, limit int) (*Page, error)
+}
+
+// BlockLister returns everyone a user has blocked or been blocked by.
+type BlockLister interface {
+	ListBlockedWith(uid string) ([]string, error)
+}
+
+type service struct {
+	repo         Repository
+	blocks       BlockLister
+	shardTimeout time.Duration
+}
+
+func NewService(r Repository, blocks BlockLister) Service {
+	timeout := 800 * time.Millisecond
+	if d, err := time.ParseDuration(os.Getenv("SEARCH_SHARD_TIMEOUT")); err == nil && d > 0 {
+		timeout = d
+	}
+	return &service{repo: r, blocks: blocks, shardTimeout: timeout}
+}
+
+var ErrQueryTooShort = errors.New("query must be at least 2 characters")
+
+const maxLimit = 50
+
+func (s *service) Search(ctx context.Context, viewer, q, after string, limit int) (*Page, error) {
+	q = strings.ToLower(strings.TrimSpace(q))
+	if utf8.RuneCountInString(q) < 2 {
+		return nil, ErrQueryTooShort
+	}
+	if limit <= 0 || limit > maxLimit {
+		limit = 20
+	}
+	var from *position
+	var pos position
+	if ok, err := cursor.Decode(after, &pos); err != nil {
+		return nil, err
+	} else if ok {
+		from = &pos
+	}
+
+	shards := s.repo.Shards()
+	results := make([][]Hit, len(shards))
+	failed := make([]bool, len(shards))
+	var wg sync.WaitGroup
+	for i, id := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sctx, cancel := context.WithTimeout(ctx, s.shardTimeout)
+			defer cancel()
+			hits, err := s.repo.SearchShard(sctx, id, q, from, limit+1)
+			if err != nil {
+				log.Printf("search shard %d: %v", id, err)
+				failed[i] = true
+				return
+			}
+			results[i] = hits
+		}()
+	}
+	wg.Wait()
+
+	page := &Page{Results: []Hit{}}
+	var merged []Hit
+	seen := make(map[string]bool)
+	for i := range shards {
+		page.Partial = page.Partial || failed[i]
+		for _, h := range results[i] {
+			if !seen[h.UserID] {
+				seen[h.UserID] = true
+				merged = append(merged, h)
+			}
+		}
+	}
+	sort.Slice(merged, func(a, b int) bool {
+		if merged[a].Score != merged[b].Score {
+			return merged[a].Score > merged[b].Score
+		}
+		return merged[a].UserID < merged[b].UserID
+	})
+	if len(merged) > limit {
+		merged = merged[:limit]
+		last := merged[limit-1]
+		page.NextCursor = cursor.Encode(position{Score: last.Score, UserID: last.UserID})
+	}
+
+	hidden := map[string]bool{viewer: true}
+	if ids, err := s.blocks.ListBlockedWith(viewer); err == nil {
+		for _, id := range ids {
+			hidden[id] = true
+		}
+	}
+	for _, h := range merged {
+		if !hidden[h.UserID] {
+			page.Results = append(page.Results, h)
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,301 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"users-service/internal/shared/shard"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var (
+	replicaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "user_db_replica_lag_seconds",
+		Help: "Replication lag of each read replica; -1 when it cannot be measured.",
+	}, []string{"shard", "replica"})
+	replicaInUse = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "user_db_replica_in_use",
+		Help: "1 while the replica serves reads, 0 while it is skipped for lag.",
+	}, []string{"shard", "replica"})
+	pinnedReads = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_db_pinned_reads_total",
+		Help: "Reads sent to the writer because the user wrote recently.",
+	})
+	pinErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_db_pin_errors_total",
+		Help: "Failed Redis calls for read-your-writes pins.",
+	})
+)
+
+// replicaSet is the read policy of one physical shard. Replicas lagging
+// behind the writer by more than maxLag are skipped; with none left, reads go
+// to the writer.
+type replicaSet struct {
+	shard   string
+	dsns    []string
+	healthy []atomic.Bool
+	mon     []*sql.DB
+}
+
+func newReplicaSet(c ShardCfg) *replicaSet {
+	rs := &replicaSet{
+		shard:   strconv.Itoa(c.ID),
+		dsns:    c.Readers,
+		healthy: make([]atomic.Bool, len(c.Readers)),
+		mon:     make([]*sql.DB, len(c.Readers)),
+	}
+	for i := range rs.healthy {
+		rs.healthy[i].Store(true)
+	}
+	return rs
+}
+
+func (rs *replicaSet) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	if len(pools) != len(rs.healthy) {
+		return pools[rand.Intn(len(pools))]
+	}
+	var ok []int
+	for i := range rs.healthy {
+		if rs.healthy[i].Load() {
+			ok = append(ok, i)
+		}
+	}
+	if len(ok) == 0 {
+		// UsePhysical routes to the writer in this case; this only covers a
+		// replica dropping out between that check and the query.
+		return pools[rand.Intn(len(pools))]
+	}
+	return pools[ok[rand.Intn(len(ok))]]
+}
+
+func (rs *replicaSet) anyHealthy() bool {
+	for i := range rs.healthy {
+		if rs.healthy[i].Load() {
+			return true
+		}
+	}
+	return false
+}
+
+// lagQuery reports zero once the replica has replayed everything it received,
+// so an idle primary does not make its replicas look stale. A replica without
+// a WAL receiver has stopped receiving and would look caught up forever, so
+// it reports -1 instead.
+const lagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN NOT EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE pid IS NOT NULL) THEN -1
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+var errNoReceiver = errors.New("no WAL receiver")
+
+func (rs *replicaSet) check(ctx context.Context, maxLag time.Duration) {
+	for i, dsn := range rs.dsns {
+		lag, err := rs.measure(ctx, i, dsn)
+		labels := prometheus.Labels{"shard": rs.shard, "replica": strconv.Itoa(i)}
+		if err != nil {
+			replicaLag.With(labels).Set(-1)
+			if rs.healthy[i].Swap(false) {
+				log.Printf("replica %s/%d skipped: %v", rs.shard, i, err)
+			}
+			replicaInUse.With(labels).Set(0)
+			continue
+		}
+		replicaLag.With(labels).Set(lag.Seconds())
+		ok := lag <= maxLag
+		if was := rs.healthy[i].Swap(ok); was != ok {
+			log.Printf("replica %s/%d in use: %t (lag %s)", rs.shard, i, ok, lag)
+		}
+		if ok {
+			replicaInUse.With(labels).Set(1)
+		} else {
+			replicaInUse.With(labels).Set(0)
+		}
+	}
+}
+
+func (rs *replicaSet) measure(ctx context.Context, i int, dsn string) (time.Duration, error) {
+	if rs.mon[i] == nil {
+		g, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err != nil {
+			return 0, err
+		}
+		sqlDB, err := g.DB()
+		if err != nil {
+			return 0, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		rs.mon[i] = sqlDB
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var secs float64
+	if err := rs.mon[i].QueryRowContext(ctx, lagQuery).Scan(&secs); err != nil {
+		return 0, err
+	}
+	if secs < 0 {
+		return 0, errNoReceiver
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+// WatchReplicaLag measures every replica's lag every `every` until ctx is
+// done, taking replicas further behind than REPLICA_MAX_LAG (default 1s) out
+// of rotation.
+func (s *Store) WatchReplicaLag(ctx context.Context, every time.Duration) {
+	maxLag := durationEnv("REPLICA_MAX_LAG", time.Second)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		for _, rs := range s.replicas {
+			rs.check(ctx, maxLag)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// writePins remembers when each user last wrote, so their own reads can be
+// kept on the writer until replicas have caught up. Pins are kept under
+// db:pin:{uid} in PIN_REDIS_ADDR, expiring with the window, so a read on
+// another instance than the write sees them too. Pins this process wrote or
+// found in Redis are also kept in memory, which spares Redis the common case
+// and covers for it while it is down. Users found unpinned are not asked
+// about again for PIN_MISS_CACHE (default 100ms), so Redis sees at most one
+// lookup per user and interval; a write on another instance can go unseen for
+// that long.
+type writePins struct {
+	window  time.Duration
+	missTTL time.Duration
+	r       *redis.Client
+	mu      sync.Mutex
+	at      map[string]time.Time
+	miss    map[string]time.Time
+}
+
+func newWritePins() *writePins {
+	addr := os.Getenv("PIN_REDIS_ADDR")
+	if addr == "" {
+		addr = "redis-users:6379"
+	}
+	return &writePins{
+		window:  durationEnv("READ_YOUR_WRITES_WINDOW", 5*time.Second),
+		missTTL: durationEnv("PIN_MISS_CACHE", 100*time.Millisecond),
+		at:      make(map[string]time.Time),
+		miss:    make(map[string]time.Time),
+		r: redis.NewClient(&redis.Options{
+			Addr:         addr,
+			DialTimeout:  time.Second,
+			ReadTimeout:  200 * time.Millisecond,
+			WriteTimeout: 200 * time.Millisecond,
+		}),
+	}
+}
+
+func pinKey(uid string) string { return "db:pin:" + uid }
+
+// remember records that uid's pin started at, or that uid has none when at is
+// zero. p.mu must be held.
+func (p *writePins) remember(uid string, at time.Time) {
+	now := time.Now()
+	if at.IsZero() {
+		p.miss[uid] = now
+		prune(p.miss, now, p.missTTL)
+		return
+	}
+	p.at[uid] = at
+	delete(p.miss, uid)
+	prune(p.at, now, p.window)
+}
+
+func prune(m map[string]time.Time, now time.Time, ttl time.Duration) {
+	if len(m) <= 10000 {
+		return
+	}
+	for k, t := range m {
+		if now.Sub(t) > ttl {
+			delete(m, k)
+		}
+	}
+}
+
+func (p *writePins) mark(uid string) {
+	p.mu.Lock()
+	p.remember(uid, time.Now())
+	p.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := p.r.Set(ctx, pinKey(uid), 1, p.window).Err(); err != nil {
+		pinErrors.Inc()
+	}
+}
+
+func (p *writePins) pinned(uid string) bool {
+	p.mu.Lock()
+	t, ok := p.at[uid]
+	m, missed := p.miss[uid]
+	p.mu.Unlock()
+	if ok && time.Since(t) < p.window {
+		return true
+	}
+	if missed && time.Since(m) < p.missTTL {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	ttl, err := p.r.PTTL(ctx, pinKey(uid)).Result()
+	if err != nil {
+		pinErrors.Inc()
+		return false
+	}
+	// PTTL is negative for a missing key.
+	var at time.Time
+	if ttl > 0 {
+		at = time.Now().Add(ttl - p.window)
+	}
+	p.mu.Lock()
+	p.remember(uid, at)
+	p.mu.Unlock()
+	return ttl > 0
+}
+
+// MarkWrite pins uid's reads to the writer for READ_YOUR_WRITES_WINDOW
+// (default 5s). Keep the window above REPLICA_MAX_LAG plus the lag check
+// interval so a replica still in rotation has the write once the pin expires.
+func (s *Store) MarkWrite(uid string) { s.pins.mark(uid) }
+
+// UseFor is Use for data owned by uid: reads go to the writer while uid has
+// written recently.
+func (s *Store) UseFor(uid string) *gorm.DB {
+	sh, _ := shard.Extract(uid)
+	if s.pins.pinned(uid) {
+		pinnedReads.Inc()
+		return s.WritePhysical(s.Physical(sh))
+	}
+	return s.Use(sh)
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
@@ -7,6 +7,9 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -24,20 +27,82 @@ type ShardCfg struct {
 type Store struct {
 	Base   *gorm.DB
 	shards map[int]ShardCfg
+	// controlID is the physical shard holding the shard map.
+	controlID int
+	logical   int
+
+	mu     sync.RWMutex
+	assign []int  // logical -> physical
+	frozen []bool // logical shards refusing writes while being moved
+	ver    int64
+
+	replicas map[int]*replicaSet
+	pins     *writePins
 }
 
+// Use returns a handle on the database currently holding logical shard
+// shardID, reading from its replicas.
 func (s *Store) Use(shardID int) *gorm.DB {
-	return s.Base.Clauses(dbresolver.Use(fmt.Sprintf("shard%d", shardID)))
+	return s.UsePhysical(s.Physical(shardID))
 }
+
+// Write is Use pinned to the writer. While the logical shard is frozen for a
+// move the returned handle carries ErrShardFrozen and executes nothing.
 func (s *Store) Write(shardID int) *gorm.DB {
+	tx := s.WritePhysical(s.Physical(shardID))
+	if s.isFrozen(shardID) {
+		_ = tx.AddError(ErrShardFrozen)
+	}
+	return tx
+}
+
+// UsePhysical reads from the shard's replicas, or from its writer while every
+// replica is lagging.
+func (s *Store) UsePhysical(id int) *gorm.DB {
+	if rs, ok := s.replicas[id]; ok && len(rs.dsns) > 0 && !rs.anyHealthy() {
+		return s.WritePhysical(id)
+	}
+	return s.Base.Clauses(dbresolver.Use(fmt.Sprintf("shard%d", id)))
+}
+func (s *Store) WritePhysical(id int) *gorm.DB {
 	return s.Base.Clauses(
-		dbresolver.Use(fmt.Sprintf("shard%d", shardID)),
+		dbresolver.Use(fmt.Sprintf("shard%d", id)),
 		dbresolver.Write,
 	)
 }
 func (s *Store) ShardInfo(id int) (ShardCfg, bool) { c, ok := s.shards[id]; return c, ok }
 
+// PhysicalIDs lists the configured physical shards in ascending order.
+func (s *Store) PhysicalIDs() []int {
+	ids := make([]int, 0, len(s.shards))
+	for id := range s.shards {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// ControlID is the physical shard holding the shard map and other global
+// tables.
+func (s *Store) ControlID() int { return s.controlID }
+
+// LogicalShards is the fixed number of logical shards user ids are spread
+// over (LOGICAL_SHARDS, default NUM_SHARDS).
+func (s *Store) LogicalShards() int { return s.logical }
+
+// OpenFromEnv connects to SHARDS_JSON and loads the shard map.
 func OpenFromEnv() *Store {
+	st := ConnectFromEnv()
+	if err := st.LoadShardMap(); err != nil {
+		log.Fatalf("shard map: %v", err)
+	}
+	return st
+}
+
+// ConnectFromEnv connects to SHARDS_JSON without reading the shard map, for
+// work that addresses physical shards only, such as migrations. Call
+// LoadShardMap before routing by user.
+func ConnectFromEnv() *Store {
 	raw := os.Getenv("SHARDS_JSON")
 	if raw == "" {
 		log.Fatal("SHARDS_JSON not set")
@@ -58,15 +123,17 @@ func OpenFromEnv() *Store {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetConnMaxLifetime(30 * time.Minute)
 
+	replicas := make(map[int]*replicaSet, len(shards))
 	makeCfg := func(s ShardCfg) dbresolver.Config {
 		var readers []gorm.Dialector
+		replicas[s.ID] = newReplicaSet(s)
 		for _, r := range s.Readers {
 			readers = append(readers, postgres.Open(r))
 		}
 		return dbresolver.Config{
 			Sources:  []gorm.Dialector{postgres.Open(s.Writer)},
 			Replicas: readers,
-			Policy:   dbresolver.RandomPolicy{},
+			Policy:   replicas[s.ID],
 		}
 	}
 
@@ -82,7 +149,24 @@ func OpenFromEnv() *Store {
 	for _, s := range shards {
 		imap[s.ID] = s
 	}
-	return &Store{Base: base, shards: imap}
+	st := &Store{
+		Base: base, shards: imap, controlID: shards[0].ID, logical: LogicalShardsFromEnv(len(shards)),
+		replicas: replicas,
+		pins:     newWritePins(),
+	}
+	return st
+}
+
+// LogicalShardsFromEnv reads LOGICAL_SHARDS, falling back to NUM_SHARDS and
+// then def. It must never change once users exist: the logical shard is part
+// of every user id.
+func LogicalShardsFromEnv(def int) int {
+	for _, k := range []string{"LOGICAL_SHARDS", "NUM_SHARDS"} {
+		if n, err := strconv.Atoi(os.Getenv(k)); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
 }
 
 func openWithRetry(dsn string, attempts int, sleep time.Duration) (*gorm.DB, error) {
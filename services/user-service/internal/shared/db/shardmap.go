@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"users-service/internal/shared/httpx"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ShardMapEntry is one row of the logical -> physical shard map kept on the
+// control shard (the first entry of SHARDS_JSON). Every change bumps the
+// version; running instances poll the highest version and reload on change.
+type ShardMapEntry struct {
+	Logical   int `gorm:"primaryKey;autoIncrement:false"`
+	Physical  int
+	Frozen    bool
+	Version   int64 `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+func (ShardMapEntry) TableName() string { return "shard_map" }
+
+var ErrShardFrozen = fmt.Errorf("%w: shard is being moved, retry shortly", httpx.ErrUnavailable)
+
+// Physical returns the physical shard currently holding a logical shard.
+func (s *Store) Physical(logical int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if logical < 0 || logical >= len(s.assign) {
+		return logical
+	}
+	return s.assign[logical]
+}
+
+// LogicalOn lists the logical shards currently mapped to a physical shard.
+func (s *Store) LogicalOn(physical int) []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []int
+	for l, p := range s.assign {
+		if p == physical {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (s *Store) isFrozen(logical int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return logical >= 0 && logical < len(s.frozen) && s.frozen[logical]
+}
+
+// MapVersion is the version of the shard map this instance is routing with.
+func (s *Store) MapVersion() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ver
+}
+
+// SeedShardMap fills an empty shard map, spreading the logical shards
+// round-robin over the physical ones. The table itself comes from migration
+// 0014.
+func (s *Store) SeedShardMap() error {
+	ctl := s.WritePhysical(s.controlID)
+	var n int64
+	if err := ctl.Model(&ShardMapEntry{}).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	ids := s.PhysicalIDs()
+	rows := make([]ShardMapEntry, s.logical)
+	for i := range rows {
+		rows[i] = ShardMapEntry{Logical: i, Physical: ids[i%len(ids)], Version: 1}
+	}
+	return ctl.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// LoadShardMap reads the shard map from the control shard.
+func (s *Store) LoadShardMap() error { return s.reloadShardMap() }
+
+func (s *Store) reloadShardMap() error {
+	var rows []ShardMapEntry
+	if err := s.WritePhysical(s.controlID).Order("logical").Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) != s.logical {
+		return fmt.Errorf("shard map has %d logical shards, expected %d", len(rows), s.logical)
+	}
+	assign := make([]int, len(rows))
+	frozen := make([]bool, len(rows))
+	var ver int64
+	for i, r := range rows {
+		if r.Logical != i {
+			return fmt.Errorf("shard map is missing logical shard %d", i)
+		}
+		if _, ok := s.shards[r.Physical]; !ok {
+			return fmt.Errorf("logical shard %d maps to unknown physical shard %d", i, r.Physical)
+		}
+		assign[i], frozen[i] = r.Physical, r.Frozen
+		ver = max(ver, r.Version)
+	}
+	s.mu.Lock()
+	s.assign, s.frozen, s.ver = assign, frozen, ver
+	s.mu.Unlock()
+	return nil
+}
+
+// WatchShardMap reloads the shard map whenever its version changes, checking
+// every interval until ctx is done.
+func (s *Store) WatchShardMap(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		var v int64
+		if err := s.WritePhysical(s.controlID).Model(&ShardMapEntry{}).
+			Select("COALESCE(MAX(version), 0)").Scan(&v).Error; err != nil {
+			log.Printf("shard map poll: %v", err)
+			continue
+		}
+		if v == s.MapVersion() {
+			continue
+		}
+		if err := s.reloadShardMap(); err != nil {
+			log.Printf("shard map reload: %v", err)
+			continue
+		}
+		log.Printf("shard map reloaded at version %d", v)
+	}
+}
+
+// AssignShard points a logical shard at a physical one and sets its frozen
+// flag as a new map version.
+func (s *Store) AssignShard(logical, physical int, frozen bool) (int64, error) {
+	if _, ok := s.shards[physical]; !ok {
+		return 0, fmt.Errorf("unknown physical shard %d", physical)
+	}
+	var ver int64
+	err := s.WritePhysical(s.controlID).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE shard_map IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&ShardMapEntry{}).Select("COALESCE(MAX(version), 0)").Scan(&ver).Error; err != nil {
+			return err
+		}
+		ver++
+		res := tx.Model(&ShardMapEntry{}).Where("logical = ?", logical).Updates(map[string]any{
+			"physical":   physical,
+			"frozen":     frozen,
+			"version":    ver,
+			"updated_at": time.Now().UTC(),
+		})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return fmt.Errorf("unknown logical shard %d", logical)
+		}
+		return res.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ver, s.reloadShardMap()
+}
@@ -2,11 +2,17 @@ package httpx
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"users-service/internal/shared/jwt"
 )
@@ -17,8 +23,23 @@ func Wrap(fn HandlerFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if err := fn(w, r); err != nil {
 			code := http.StatusBadRequest
-			if errors.Is(err, ErrUnauthorized) {
+			switch {
+			case errors.Is(err, ErrUnauthorized):
 				code = http.StatusUnauthorized
+			case errors.Is(err, ErrForbidden):
+				code = http.StatusForbidden
+			case errors.Is(err, ErrNotFound):
+				code = http.StatusNotFound
+			case errors.Is(err, ErrUnavailable):
+				code = http.StatusServiceUnavailable
+			case errors.Is(err, ErrConflict):
+				code = http.StatusConflict
+			case errors.Is(err, ErrTooManyRequests):
+				code = http.StatusTooManyRequests
+			}
+			var ra RetryAfter
+			if errors.As(err, &ra) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(ra.RetryAfter().Seconds()))))
 			}
 			WriteJSON(w, map[string]any{"error": err.Error()}, code)
 		}
@@ -41,10 +62,44 @@ var (
 	// Use stable string keys to avoid mismatches if multiple copies of the package are linked.
 	ctxUserIDKey  = "httpx.user_id"
 	ctxShardIDKey = "httpx.shard_id"
+	ctxClaimsKey  = "httpx.claims"
 
 	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrUnavailable  = errors.New("temporarily unavailable")
+	ErrConflict     = errors.New("conflict")
+
+	ErrTooManyRequests = errors.New("too many requests")
 )
 
+// RetryAfter is implemented by errors that tell the client when to try again.
+type RetryAfter interface {
+	RetryAfter() time.Duration
+}
+
+// RevocationChecker reports whether an access token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti, sid, uid string, iat time.Time) (bool, error)
+}
+
+var revocations RevocationChecker
+
+// UseRevocations makes AuthMiddleware consult rc for every request.
+// The check fails open: if rc errors, the token is accepted and the error logged.
+func UseRevocations(rc RevocationChecker) { revocations = rc }
+
+// WriteTracker is told which user made each state-changing request.
+type WriteTracker interface {
+	MarkWrite(uid string)
+}
+
+var writes WriteTracker
+
+// TrackWrites makes AuthMiddleware report every authenticated non-GET request
+// to wt once it has been handled, so the user's next reads can see it.
+func TrackWrites(wt WriteTracker) { writes = wt }
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		h := r.Header.Get("Authorization")
@@ -53,14 +108,41 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		tok := strings.TrimSpace(h[7:])
-		uid, sh, err := jwt.Parse(tok)
-		if err != nil || uid == "" {
+		c, err := jwt.ParseClaims(tok)
+		if err != nil || c.UserID == "" {
 			WriteJSON(w, map[string]any{"error": "unauthorized", "reason": "bad token"}, http.StatusUnauthorized)
 			return
 		}
-		ctx := context.WithValue(r.Context(), ctxUserIDKey, uid)
-		ctx = context.WithValue(ctx, ctxShardIDKey, sh)
+		if revocations != nil {
+			revoked, err := revocations.IsRevoked(r.Context(), c.ID, c.SessionID, c.UserID, c.IssuedAt)
+			if err != nil {
+				log.Printf("revocation check: %v", err)
+			} else if revoked {
+				WriteJSON(w, map[string]any{"error": "unauthorized", "reason": "token revoked"}, http.StatusUnauthorized)
+				return
+			}
+		}
+		ctx := context.WithValue(r.Context(), ctxUserIDKey, c.UserID)
+		ctx = context.WithValue(ctx, ctxShardIDKey, c.ShardID)
+		ctx = context.WithValue(ctx, ctxClaimsKey, c)
 		next.ServeHTTP(w, r.WithContext(ctx))
+		if writes != nil && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writes.MarkWrite(c.UserID)
+		}
+	})
+}
+
+// InternalOnly admits service-to-service calls whose X-Internal-Token matches
+// INTERNAL_TOKEN. With no INTERNAL_TOKEN configured every request is refused.
+func InternalOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := os.Getenv("INTERNAL_TOKEN")
+		got := r.Header.Get("X-Internal-Token")
+		if want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			WriteJSON(w, map[string]any{"error": "forbidden", "reason": "internal token required"}, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
 	})
 }
 
@@ -73,6 +155,31 @@ func UserFromCtx(r *http.Request) (string, int, error) {
 	return uid, sh, nil
 }
 
+// ClaimsFromCtx returns the verified access token claims of the request.
+func ClaimsFromCtx(r *http.Request) (jwt.Claims, error) {
+	c, ok := r.Context().Value(ctxClaimsKey).(jwt.Claims)
+	if !ok || c.UserID == "" {
+		return jwt.Claims{}, ErrUnauthorized
+	}
+	return c, nil
+}
+
+// ClientIP is the caller's address as seen by the gateway, which sets
+// X-Real-IP. The header is only believed from TRUSTED_PROXIES; anyone else
+// gets the connection's address.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil && trustedProxies().contains(ip) {
+		if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); real != "" {
+			return real
+		}
+	}
+	return host
+}
+
 func QueryInt(r *http.Request, key string, def int) int {
 	s := r.URL.Query().Get(key)
 	if s == "" {
@@ -0,0 +1,100 @@
+package httpx
+
+import (
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TRUSTED_PROXIES lists, comma-separated, the CIDRs, addresses and host names
+// of the proxies allowed to set X-Real-IP. It defaults to the gateway.
+const defaultTrustedProxies = "api-gateway"
+
+// proxyResolveEvery is how long resolved host names are kept, so a gateway
+// that comes back with a new address is picked up.
+const proxyResolveEvery = time.Minute
+
+type proxySet struct {
+	nets  []*net.IPNet
+	ips   []net.IP
+	hosts []string
+
+	mu       sync.Mutex
+	resolved []net.IP
+	at       time.Time
+}
+
+var (
+	proxiesOnce sync.Once
+	proxies     *proxySet
+)
+
+func trustedProxies() *proxySet {
+	proxiesOnce.Do(func() {
+		list, ok := os.LookupEnv("TRUSTED_PROXIES")
+		if !ok {
+			list = defaultTrustedProxies
+		}
+		proxies = parseProxies(list)
+	})
+	return proxies
+}
+
+func parseProxies(list string) *proxySet {
+	p := &proxySet{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if _, n, err := net.ParseCIDR(item); err == nil {
+			p.nets = append(p.nets, n)
+		} else if ip := net.ParseIP(item); ip != nil {
+			p.ips = append(p.ips, ip)
+		} else {
+			p.hosts = append(p.hosts, item)
+		}
+	}
+	return p
+}
+
+func (p *proxySet) contains(ip net.IP) bool {
+	for _, n := range p.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	for _, i := range p.ips {
+		if i.Equal(ip) {
+			return true
+		}
+	}
+	if len(p.hosts) == 0 {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.at) > proxyResolveEvery {
+		var resolved []net.IP
+		for _, h := range p.hosts {
+			ips, err := net.LookupIP(h)
+			if err != nil {
+				continue
+			}
+			resolved = append(resolved, ips...)
+		}
+		// Keep the last answer if the names do not resolve right now.
+		if len(resolved) > 0 || p.at.IsZero() {
+			p.resolved = resolved
+		}
+		p.at = time.Now()
+	}
+	for _, r := range p.resolved {
+		if r.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
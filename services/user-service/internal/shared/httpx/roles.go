@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"users-service/internal/shared/jwt"
+)
+
+// Roles, least privileged first. Each role may do everything the ones before
+// it may.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRank = map[string]int{RoleUser: 1, RoleModerator: 2, RoleAdmin: 3}
+
+// HasRole reports whether a caller with role have may act as want.
+func HasRole(have, want string) bool {
+	return roleRank[want] > 0 && roleRank[have] >= roleRank[want]
+}
+
+// RoleFromCtx returns the role carried by the caller's access token.
+func RoleFromCtx(r *http.Request) string {
+	c, _ := r.Context().Value(ctxClaimsKey).(jwt.Claims)
+	return c.Role
+}
+
+// AuditEntry is one request that needed an elevated role, refused or not.
+type AuditEntry struct {
+	ActorID string
+	Role    string
+	Action  string // route pattern, e.g. "PATCH /admin/reports/{report_id}"
+	Path    string
+	Status  int
+	IP      string
+	At      time.Time
+}
+
+// Auditor records the requests that go through RequireRole.
+type Auditor interface {
+	Audit(e AuditEntry)
+}
+
+var auditor Auditor
+
+// UseAudit makes RequireRole hand every request to a. Without an auditor the
+// entries are only logged.
+func UseAudit(a Auditor) { auditor = a }
+
+// RequireRole lets through callers whose token carries role or a higher one
+// and audits every request it sees. It must run inside AuthMiddleware.
+func RequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid, _, _ := UserFromCtx(r)
+		have := RoleFromCtx(r)
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		if uid == "" || !HasRole(have, role) {
+			WriteJSON(sw, map[string]any{"error": "forbidden", "reason": "role required"}, http.StatusForbidden)
+		} else {
+			next.ServeHTTP(sw, r)
+		}
+		e := AuditEntry{
+			ActorID: uid, Role: have, Action: r.Pattern, Path: r.URL.Path,
+			Status: sw.status, IP: ClientIP(r), At: time.Now().UTC(),
+		}
+		if auditor == nil {
+			log.Printf("audit: %s (%s) %s %s -> %d", e.ActorID, e.Role, e.Action, e.Path, e.Status)
+			return
+		}
+		auditor.Audit(e)
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"os"
 	"time"
@@ -8,36 +10,102 @@ import (
 	jw "github.com/golang-jwt/jwt/v5"
 )
 
-func secret() []byte {
-	if s := os.Getenv("JWT_SECRET"); s != "" {
-		return []byte(s)
+// AccessTTL is the lifetime of access tokens (ACCESS_TOKEN_TTL, default 15m).
+func AccessTTL() time.Duration {
+	if s := os.Getenv("ACCESS_TOKEN_TTL"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			return d
+		}
 	}
-	return []byte("replace-this-with-a-strong-secret")
+	return 15 * time.Minute
 }
 
-func Make(userID string, shardID int) (string, error) {
+type Claims struct {
+	UserID    string
+	ShardID   int
+	ID        string // jti
+	SessionID string // sid; empty in tokens issued before sessions existed
+	Role      string // "user" in tokens issued before roles existed
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Make signs an access token for one session of the user, carrying their
+// role, with the active key; see keys.go.
+func Make(userID string, shardID int, sessionID, role string) (string, error) {
+	kr := ring.Load()
+	if kr == nil {
+		return "", errors.New("jwt: keys not loaded")
+	}
+	k := kr.keys[kr.active]
+	now := time.Now()
 	claims := jw.MapClaims{
-		"sub": userID,
-		"sh":  shardID,
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"sub":  userID,
+		"sh":   shardID,
+		"sid":  sessionID,
+		"role": role,
+		"jti":  newID(),
+		"iat":  now.Unix(),
+		"exp":  now.Add(AccessTTL()).Unix(),
 	}
-	return jw.NewWithClaims(jw.SigningMethodHS256, claims).SignedString(secret())
+	t := jw.NewWithClaims(k.method, claims)
+	t.Header["kid"] = k.kid
+	return t.SignedString(k.priv)
 }
 
-func Parse(tok string) (string, int, error) {
-	t, err := jw.Parse(tok, func(t *jw.Token) (any, error) { return secret(), nil })
+// keyFunc accepts only tokens naming one of our keys.
+func keyFunc(t *jw.Token) (any, error) {
+	kr := ring.Load()
+	kid, _ := t.Header["kid"].(string)
+	if kr == nil || kid == "" {
+		return nil, errors.New("unknown key")
+	}
+	k, ok := kr.keys[kid]
+	if !ok || k.method.Alg() != t.Method.Alg() {
+		return nil, errors.New("unknown key")
+	}
+	return k.priv.Public(), nil
+}
+
+func ParseClaims(tok string) (Claims, error) {
+	t, err := jw.Parse(tok, keyFunc, jw.WithValidMethods([]string{"EdDSA", "RS256"}))
 	if err != nil || !t.Valid {
-		return "", 0, errors.New("invalid token")
+		return Claims{}, errors.New("invalid token")
 	}
 	mc, ok := t.Claims.(jw.MapClaims)
 	if !ok {
-		return "", 0, errors.New("bad claims")
+		return Claims{}, errors.New("bad claims")
 	}
 	uid, _ := mc["sub"].(string)
 	shf, ok := mc["sh"].(float64)
 	if !ok {
-		return "", 0, errors.New("missing shard")
+		return Claims{}, errors.New("missing shard")
+	}
+	c := Claims{UserID: uid, ShardID: int(shf)}
+	c.ID, _ = mc["jti"].(string)
+	c.SessionID, _ = mc["sid"].(string)
+	if c.Role, _ = mc["role"].(string); c.Role == "" {
+		c.Role = "user"
+	}
+	if iat, ok := mc["iat"].(float64); ok {
+		c.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	if exp, ok := mc["exp"].(float64); ok {
+		c.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	return c, nil
+}
+
+func Parse(tok string) (string, int, error) {
+	c, err := ParseClaims(tok)
+	if err != nil {
+		return "", 0, err
 	}
-	return uid, int(shf), nil
+	return c.UserID, c.ShardID, nil
 }
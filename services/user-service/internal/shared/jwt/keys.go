@@ -0,0 +1,284 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	jw "github.com/golang-jwt/jwt/v5"
+)
+
+// Signing keys live in JWT_KEYS_DIR, one PEM private key (PKCS#8, or PKCS#1
+// for RSA) per file named {kid}.pem. Every key in the directory is published
+// in the JWKS at once, but signs only once its file is older than the JWKS
+// max-age plus the reload interval, so every verifier and every user-service
+// instance has seen it first. New tokens are signed with JWT_SIGNING_KID, or
+// else the ready kid that sorts last. Kids made by GenerateKey are UTC
+// timestamps, so rotating is: generate a key, wait, and delete the old file
+// once the tokens it signed have expired.
+
+// jwksMaxAge is how long verifiers may cache the JWKS.
+const jwksMaxAge = 5 * time.Minute
+
+// readyAfter is how old a key file must be before it signs; see LoadKeys.
+var readyAfter atomic.Int64
+
+type signingKey struct {
+	kid    string
+	method jw.SigningMethod
+	priv   crypto.Signer
+	// published is when the key file appeared; zero for in-memory keys.
+	published time.Time
+}
+
+type keyRing struct {
+	keys   map[string]signingKey
+	active string
+	jwks   []byte
+}
+
+var ring atomic.Pointer[keyRing]
+
+// LoadKeys reads JWT_KEYS_DIR. An empty directory gets a fresh Ed25519 key.
+// With no directory configured an in-memory key is made, which only works
+// for a single user-service instance that is never restarted. reload is the
+// WatchKeys interval of every instance.
+func LoadKeys(reload time.Duration) error {
+	readyAfter.Store(int64(jwksMaxAge + reload))
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		log.Printf("jwt: JWT_KEYS_DIR not set, signing with a temporary key")
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		kid := "tmp-" + newID()[:8]
+		return setRing([]signingKey{{kid: kid, method: jw.SigningMethodEdDSA, priv: priv}})
+	}
+	keys, err := readKeys(dir)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		kid, err := GenerateKey(dir, "EdDSA")
+		if err != nil {
+			return err
+		}
+		log.Printf("jwt: no keys in %s, generated %s", dir, kid)
+		if keys, err = readKeys(dir); err != nil {
+			return err
+		}
+	}
+	return setRing(keys)
+}
+
+// WatchKeys reloads JWT_KEYS_DIR every interval so rotated keys are picked
+// up without a restart. A failed reload keeps the current keys.
+func WatchKeys(ctx context.Context, every time.Duration) {
+	if os.Getenv("JWT_KEYS_DIR") == "" {
+		return
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			keys, err := readKeys(os.Getenv("JWT_KEYS_DIR"))
+			if err == nil && len(keys) == 0 {
+				err = errors.New("no keys")
+			}
+			if err == nil {
+				err = setRing(keys)
+			}
+			if err != nil {
+				log.Printf("jwt: reload keys: %v", err)
+			}
+		}
+	}
+}
+
+func readKeys(dir string) ([]signingKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	var out []signingKey
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		k, err := parseKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		k.kid = strings.TrimSuffix(filepath.Base(p), ".pem")
+		k.published = fi.ModTime()
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+func parseKey(b []byte) (signingKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return signingKey{}, errors.New("no PEM block")
+	}
+	var priv any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return signingKey{}, err
+	}
+	switch k := priv.(type) {
+	case ed25519.PrivateKey:
+		return signingKey{method: jw.SigningMethodEdDSA, priv: k}, nil
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return signingKey{}, errors.New("RSA key shorter than 2048 bits")
+		}
+		return signingKey{method: jw.SigningMethodRS256, priv: k}, nil
+	}
+	return signingKey{}, fmt.Errorf("unsupported key type %T", priv)
+}
+
+func setRing(keys []signingKey) error {
+	r := &keyRing{keys: make(map[string]signingKey, len(keys))}
+	kids := make([]string, 0, len(keys))
+	for _, k := range keys {
+		r.keys[k.kid] = k
+		kids = append(kids, k.kid)
+	}
+	sort.Strings(kids)
+	cutoff := time.Now().Add(-time.Duration(readyAfter.Load()))
+	for _, kid := range kids {
+		if !r.keys[kid].published.After(cutoff) {
+			r.active = kid
+		}
+	}
+	if r.active == "" {
+		// No key is ready, as right after the first one is made; the oldest
+		// is the likeliest to be known.
+		r.active = kids[0]
+		for _, kid := range kids[1:] {
+			if r.keys[kid].published.Before(r.keys[r.active].published) {
+				r.active = kid
+			}
+		}
+	}
+	if want := os.Getenv("JWT_SIGNING_KID"); want != "" {
+		if _, ok := r.keys[want]; !ok {
+			return fmt.Errorf("JWT_SIGNING_KID %q not among the keys", want)
+		}
+		r.active = want
+	}
+	set := jwkSet{Keys: make([]jwk, 0, len(kids))}
+	for _, kid := range kids {
+		set.Keys = append(set.Keys, publicJWK(r.keys[kid]))
+	}
+	r.jwks, _ = json.Marshal(set)
+	if old := ring.Swap(r); old != nil && old.active != r.active {
+		log.Printf("jwt: signing with %s", r.active)
+	}
+	return nil
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+func publicJWK(k signingKey) jwk {
+	enc := base64.RawURLEncoding.EncodeToString
+	out := jwk{Kid: k.kid, Alg: k.method.Alg(), Use: "sig"}
+	switch pub := k.priv.Public().(type) {
+	case ed25519.PublicKey:
+		out.Kty, out.Crv, out.X = "OKP", "Ed25519", enc(pub)
+	case *rsa.PublicKey:
+		out.Kty, out.N, out.E = "RSA", enc(pub.N.Bytes()), enc(big.NewInt(int64(pub.E)).Bytes())
+	}
+	return out
+}
+
+// JWKSHandler serves the public keys at /.well-known/jwks.json.
+func JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kr := ring.Load()
+		if kr == nil {
+			http.Error(w, "keys not loaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+		_, _ = w.Write(kr.jwks)
+	})
+}
+
+// GenerateKey writes a new private key to dir and returns its kid. alg is
+// "EdDSA" or "RS256".
+func GenerateKey(dir, alg string) (string, error) {
+	var priv any
+	var err error
+	switch alg {
+	case "EdDSA":
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	case "RS256":
+		priv, err = rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	kid := time.Now().UTC().Format("20060102T150405Z")
+	f, err := os.OpenFile(filepath.Join(dir, kid+".pem"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return kid, pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
@@ -1,6 +1,7 @@
 package social
 
 import (
+	"errors"
 	"net/http"
 
 	"users-service/internal/shared/httpx"
@@ -48,13 +49,58 @@ func (h *Handler) ListFollowing(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
+	return writeList(w, r,
+		func(limit, offset int) ([]string, error) { return h.svc.ListFollowing(uid, limit, offset) },
+		func(after string, limit int) (*Page, error) { return h.svc.PageFollowing(uid, after, limit) })
+}
+
+func (h *Handler) ListFollowers(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	return writeList(w, r,
+		func(limit, offset int) ([]string, error) { return h.svc.ListFollowers(uid, limit, offset) },
+		func(after string, limit int) (*Page, error) { return h.svc.PageFollowers(uid, after, limit) })
+}
+
+// writeList serves a user id list. Clients that pass ?offset= keep the old
+// offset paging; everyone else pages by ?cursor= and gets next_cursor back.
+func writeList(w http.ResponseWriter, r *http.Request,
+	byOffset func(limit, offset int) ([]string, error),
+	byCursor func(after string, limit int) (*Page, error)) error {
 	limit := httpx.QueryInt(r, "limit", 50)
-	offset := httpx.QueryInt(r, "offset", 0)
-	items, err := h.svc.ListFollowing(uid, limit, offset)
+	if offset := httpx.QueryInt(r, "offset", 0); offset > 0 {
+		items, err := byOffset(limit, offset)
+		if err != nil {
+			return err
+		}
+		httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+		return nil
+	}
+	page, err := byCursor(r.URL.Query().Get("cursor"), limit)
 	if err != nil {
 		return err
 	}
-	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	httpx.WriteJSON(w, page, http.StatusOK)
+	return nil
+}
+
+// FollowCounts returns follower and following counts for ?user_id=, or for the
+// caller when it is omitted.
+func (h *Handler) FollowCounts(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	if q := r.URL.Query().Get("user_id"); q != "" {
+		uid = q
+	}
+	followers, following, err := h.svc.CountFollows(uid)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"user_id": uid, "followers": followers, "following": following}, http.StatusOK)
 	return nil
 }
 
@@ -67,10 +113,11 @@ func (h *Handler) Befriend(w http.ResponseWriter, r *http.Request) error {
 	if friend == "" {
 		return httpx.ErrUnauthorized
 	}
-	if err := h.svc.Befriend(uid, friend); err != nil {
+	fr, err := h.svc.SendFriendRequest(uid, friend)
+	if err != nil {
 		return err
 	}
-	httpx.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+	httpx.WriteJSON(w, fr, http.StatusOK)
 	return nil
 }
 
@@ -91,13 +138,148 @@ func (h *Handler) Unfriend(w http.ResponseWriter, r *http.Request) error {
 }
 
 func (h *Handler) ListFriends(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	owner := r.URL.Query().Get("user_id")
+	if owner == "" {
+		owner = uid
+	}
+	return writeList(w, r,
+		func(limit, offset int) ([]string, error) { return h.svc.ListFriendsOf(uid, owner, limit, offset) },
+		func(after string, limit int) (*Page, error) { return h.svc.PageFriendsOf(uid, owner, after, limit) })
+}
+
+func (h *Handler) SendFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[SendFriendRequestReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	fr, err := h.svc.SendFriendRequest(uid, in.ToUserID)
+	if err != nil {
+		return err
+	}
+	code := http.StatusCreated
+	if fr.Status == RequestAccepted {
+		code = http.StatusOK
+	}
+	httpx.WriteJSON(w, fr, code)
+	return nil
+}
+
+func (h *Handler) ListFriendRequests(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	dir := r.URL.Query().Get("type")
+	if dir == "" {
+		dir = "incoming"
+	}
+	if dir != "incoming" && dir != "outgoing" {
+		return errors.New("type must be incoming or outgoing")
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = RequestPending
+	case "all":
+		status = ""
+	}
+	limit := httpx.QueryInt(r, "limit", 50)
+	offset := httpx.QueryInt(r, "offset", 0)
+	items, err := h.svc.ListFriendRequests(uid, dir == "incoming", status, limit, offset)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"friend_requests": items, "type": dir, "limit": limit, "offset": offset}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) UpdateFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[UpdateFriendRequestReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	fr, err := h.svc.UpdateFriendRequest(uid, r.PathValue("request_id"), in.Status)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, fr, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) CancelFriendRequest(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	fr, err := h.svc.UpdateFriendRequest(uid, r.PathValue("request_id"), RequestCancelled)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, fr, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Block(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[BlockReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	if err := h.svc.Block(uid, in.BlockedID); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusCreated)
+	return nil
+}
+
+func (h *Handler) Unblock(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	blocked := r.URL.Query().Get("blocked_id")
+	if blocked == "" {
+		return errors.New("blocked_id is required")
+	}
+	if err := h.svc.Unblock(uid, blocked); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) ListBlocked(w http.ResponseWriter, r *http.Request) error {
 	uid, _, err := httpx.UserFromCtx(r)
 	if err != nil {
 		return err
 	}
 	limit := httpx.QueryInt(r, "limit", 50)
 	offset := httpx.QueryInt(r, "offset", 0)
-	items, err := h.svc.ListFriends(uid, limit, offset)
+	items, err := h.svc.ListBlocked(uid, limit, offset)
 	if err != nil {
 		return err
 	}
@@ -105,6 +287,31 @@ func (h *Handler) ListFriends(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// BlockSet is an internal endpoint for other services: every user that
+// user_id has blocked or is blocked by.
+func (h *Handler) BlockSet(w http.ResponseWriter, r *http.Request) error {
+	uid := r.PathValue("user_id")
+	items, err := h.svc.ListBlockedWith(uid)
+	if err != nil {
+		return err
+	}
+	if items == nil {
+		items = []string{}
+	}
+	httpx.WriteJSON(w, map[string]any{"user_id": uid, "items": items}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) BlockCheck(w http.ResponseWriter, r *http.Request) error {
+	a, b := r.PathValue("user_id"), r.PathValue("other_id")
+	blocked, err := h.svc.IsBlocked(a, b)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"user_id": a, "other_id": b, "blocked": blocked}, http.StatusOK)
+	return nil
+}
+
 type relReq struct {
 	RelatedID string `json:"related_id" validate:"required"`
 	Type      int    `json:"type" validate:"required"`
@@ -168,3 +375,16 @@ func (h *Handler) ListRelationships(w http.ResponseWriter, r *http.Request) erro
 	}, http.StatusOK)
 	return nil
 }
+
+func (h *Handler) RelationStatus(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	st, err := h.svc.RelationStatus(uid, r.PathValue("other_id"))
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, st, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,427 @@
+package social
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"users-service/internal/shared/db"
+	"users-service/internal/shared/shard"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// outboxTx runs fn in a transaction on uid's shard. Entries fn queues are
+// written in that transaction and applied right after it commits; the relay
+// picks up any that fail.
+func (r *repo) outboxTx(uid string, fn func(tx *gorm.DB, queue func(*FriendOutbox) error) error) error {
+	sh, _ := shard.Extract(uid)
+	w := r.store.Write(sh)
+	if w.Error != nil {
+		return w.Error
+	}
+	var entries []*FriendOutbox
+	err := w.Transaction(func(tx *gorm.DB) error {
+		entries = entries[:0]
+		return fn(tx, func(e *FriendOutbox) error {
+			now := time.Now()
+			e.NextAttempt, e.CreatedAt = now, now
+			if err := tx.Create(e).Error; err != nil {
+				return err
+			}
+			entries = append(entries, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := r.relay(r.store.Physical(sh), 50, e); err != nil {
+			log.Printf("friend outbox %d: %v", e.ID, err)
+		}
+	}
+	return nil
+}
+
+// writeFriendship sets (op add) or clears (op remove) the edge a -> b on a's
+// shard and queues the mirror b -> a, or writes both directly when the two
+// users share a database.
+func (r *repo) writeFriendship(op, a, b string) error {
+	return r.outboxTx(a, func(tx *gorm.DB, queue func(*FriendOutbox) error) error {
+		return r.friendEdge(tx, queue, op, a, b)
+	})
+}
+
+// friendEdge is writeFriendship inside tx, which must be on a's shard.
+func (r *repo) friendEdge(tx *gorm.DB, queue func(*FriendOutbox) error, op, a, b string) error {
+	sha, _ := shard.Extract(a)
+	shb, _ := shard.Extract(b)
+	local := r.store.Physical(sha) == r.store.Physical(shb)
+	if local {
+		if err := r.store.Write(shb).Error; err != nil {
+			return err
+		}
+	}
+	if err := applyEdge(tx, op, a, b); err != nil {
+		return err
+	}
+	if op == OutboxRemove {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).
+			Create(&FriendRemoval{UserID: a, FriendID: b, RemovedAt: time.Now()}).Error; err != nil {
+			return err
+		}
+	}
+	if local {
+		return applyEdge(tx, op, b, a)
+	}
+	return queue(&FriendOutbox{Op: op, UserID: b, FriendID: a})
+}
+
+// transitionRequest moves fr from pending to status inside tx, which must be
+// on the sender's shard, and queues the same change for the recipient's copy.
+// It reports false if the request was no longer pending.
+func (r *repo) transitionRequest(tx *gorm.DB, queue func(*FriendOutbox) error, fr *FriendRequest, status string) (bool, error) {
+	// The sender's copy is the source of truth; whoever flips it first wins.
+	res := tx.Model(&FriendRequest{}).
+		Where("request_id = ? AND status = ?", fr.RequestID, RequestPending).
+		Updates(map[string]any{"status": status, "updated_at": time.Now()})
+	if res.Error != nil || res.RowsAffected != 1 {
+		return false, res.Error
+	}
+	shards := requestShards(fr)
+	if len(shards) == 1 || r.store.Physical(shards[0]) == r.store.Physical(shards[1]) {
+		return true, nil
+	}
+	return true, queue(&FriendOutbox{
+		Op: OutboxRequest, UserID: fr.ToUserID, FriendID: fr.FromUserID,
+		RequestID: fr.RequestID, Status: status,
+	})
+}
+
+func applyEdge(tx *gorm.DB, op, uid, friend string) error {
+	if op == OutboxRemove {
+		return tx.Delete(&Friend{}, "user_id=? AND friend_id=?", uid, friend).Error
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Friend{UserID: uid, FriendID: friend}).Error
+}
+
+func (r *repo) applyMirror(e *FriendOutbox) error {
+	sh, ok := shard.Extract(e.UserID)
+	if !ok {
+		return errors.New("bad user_id")
+	}
+	w := r.store.Write(sh)
+	if w.Error != nil {
+		return w.Error
+	}
+	if e.Op == OutboxRequest {
+		return w.Model(&FriendRequest{}).Where("request_id = ?", e.RequestID).
+			Updates(map[string]any{"status": e.Status, "updated_at": e.CreatedAt}).Error
+	}
+	return applyEdge(w, e.Op, e.UserID, e.FriendID)
+}
+
+// relayLock keeps concurrent relays (other instances, or the inline apply in
+// writeFriendship) from reordering entries of one shard's outbox.
+const relayLock = "friend_outbox"
+
+func outboxBackoff(attempts int) time.Duration {
+	d := time.Second << min(attempts, 10)
+	return min(d, 10*time.Minute)
+}
+
+func (r *repo) RelayFriendOutbox(physical, limit int) (int, error) {
+	return r.relay(physical, limit, nil)
+}
+
+// relay applies due outbox entries on one physical shard, oldest first. With
+// only set, just the entries of that edge are looked at. It returns how many
+// were applied; failures are rescheduled, not returned.
+func (r *repo) relay(physical, limit int, only *FriendOutbox) (int, error) {
+	applied := 0
+	err := r.store.WritePhysical(physical).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext(?))", relayLock).Scan(&locked).Error; err != nil || !locked {
+			return err
+		}
+		q := tx.Order("id").Limit(limit)
+		if only != nil {
+			q = q.Where("user_id = ? AND friend_id = ?", only.UserID, only.FriendID)
+		}
+		var batch []FriendOutbox
+		if err := q.Find(&batch).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		held := make(map[string]bool)
+		for i := range batch {
+			e := &batch[i]
+			edge := e.UserID + "|" + e.FriendID
+			if held[edge] || e.NextAttempt.After(now) {
+				held[edge] = true
+				continue
+			}
+			if err := r.applyMirror(e); err != nil {
+				held[edge] = true
+				msg := err.Error()
+				if len(msg) > 500 {
+					msg = msg[:500]
+				}
+				if err := tx.Model(e).Updates(map[string]any{
+					"attempts":     e.Attempts + 1,
+					"last_error":   msg,
+					"next_attempt": now.Add(outboxBackoff(e.Attempts + 1)),
+				}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Delete(e).Error; err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// RepairFriends looks at the Friend edges of one physical shard created before
+// olderThan and settles those whose mirror is missing and not queued. The
+// pair's last FriendRemoval is weighed against its latest accepted request:
+// the dangling edge is removed if the pair was unfriended since, or is
+// blocked, and the mirror is restored otherwise. Edges predating mirroring
+// have neither record and are kept. It returns how many edges it fixed.
+func (r *repo) RepairFriends(physical int, olderThan time.Time) (int, error) {
+	var owned []string
+	for _, l := range r.store.LogicalOn(physical) {
+		owned = append(owned, strconv.Itoa(l))
+	}
+	if len(owned) == 0 {
+		return 0, nil
+	}
+	fixed := 0
+	var lastUser, lastFriend string
+	for {
+		var batch []Friend
+		if err := r.store.WritePhysical(physical).
+			Where("split_part(user_id, '-', 1) IN ?", owned).
+			Where("created_at < ?", olderThan).
+			Where("(user_id, friend_id) > (?, ?)", lastUser, lastFriend).
+			Order("user_id, friend_id").Limit(500).Find(&batch).Error; err != nil {
+			return fixed, err
+		}
+		if len(batch) == 0 {
+			return fixed, nil
+		}
+		lastUser, lastFriend = batch[len(batch)-1].UserID, batch[len(batch)-1].FriendID
+
+		missing, err := r.missingMirrors(batch)
+		if err != nil {
+			return fixed, err
+		}
+		for _, f := range missing {
+			ok, err := r.repairEdge(f)
+			if err != nil {
+				log.Printf("repair friend %s -> %s: %v", f.UserID, f.FriendID, err)
+				continue
+			}
+			if ok {
+				fixed++
+			}
+		}
+		if len(batch) < 500 {
+			return fixed, nil
+		}
+	}
+}
+
+// missingMirrors returns the edges of batch whose reverse edge does not exist.
+func (r *repo) missingMirrors(batch []Friend) ([]Friend, error) {
+	byShard := make(map[int][]Friend)
+	for _, f := range batch {
+		sh, _ := shard.Extract(f.FriendID)
+		byShard[sh] = append(byShard[sh], f)
+	}
+	var out []Friend
+	for sh, edges := range byShard {
+		owners := make([]string, 0, len(edges))
+		friends := make([]string, 0, len(edges))
+		for _, f := range edges {
+			owners = append(owners, f.FriendID)
+			friends = append(friends, f.UserID)
+		}
+		var back []Friend
+		if err := r.store.Write(sh).Where("user_id IN ? AND friend_id IN ?", owners, friends).Find(&back).Error; err != nil {
+			return nil, err
+		}
+		have := make(map[string]bool, len(back))
+		for _, b := range back {
+			have[b.UserID+"|"+b.FriendID] = true
+		}
+		for _, f := range edges {
+			if !have[f.FriendID+"|"+f.UserID] {
+				out = append(out, f)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *repo) repairEdge(f Friend) (bool, error) {
+	shu, _ := shard.Extract(f.UserID)
+	shf, _ := shard.Extract(f.FriendID)
+	// A queued mirror in either direction will settle the pair by itself.
+	var pending int64
+	if err := r.store.Write(shu).Model(&FriendOutbox{}).
+		Where("user_id = ? AND friend_id = ?", f.FriendID, f.UserID).Count(&pending).Error; err != nil || pending > 0 {
+		return false, err
+	}
+	if err := r.store.Write(shf).Model(&FriendOutbox{}).
+		Where("user_id = ? AND friend_id = ?", f.UserID, f.FriendID).Count(&pending).Error; err != nil || pending > 0 {
+		return false, err
+	}
+
+	blocked, err := r.IsBlocked(f.UserID, f.FriendID)
+	if err != nil {
+		return false, err
+	}
+	removed, err := r.lastRemoval(f.UserID, f.FriendID)
+	if err != nil {
+		return false, err
+	}
+	accepted, err := r.lastAccepted(f.UserID, f.FriendID)
+	if err != nil {
+		return false, err
+	}
+	if blocked || (!removed.IsZero() && !accepted.After(removed)) {
+		err = applyEdge(r.store.Write(shu), OutboxRemove, f.UserID, f.FriendID)
+	} else {
+		err = applyEdge(r.store.Write(shf), OutboxAdd, f.FriendID, f.UserID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("apply: %w", err)
+	}
+	return true, nil
+}
+
+// lastRemoval returns when either of a and b last unfriended the other, or
+// the zero time if neither has.
+func (r *repo) lastRemoval(a, b string) (time.Time, error) {
+	var last time.Time
+	for _, p := range [][2]string{{a, b}, {b, a}} {
+		sh, _ := shard.Extract(p[0])
+		var t sql.NullTime
+		if err := r.store.Write(sh).Model(&FriendRemoval{}).
+			Where("user_id = ? AND friend_id = ?", p[0], p[1]).
+			Select("MAX(removed_at)").Row().Scan(&t); err != nil {
+			return time.Time{}, err
+		}
+		if t.Valid && t.Time.After(last) {
+			last = t.Time
+		}
+	}
+	return last, nil
+}
+
+// lastAccepted returns when a friend request between a and b was last
+// accepted, looking at the copies on both users' shards.
+func (r *repo) lastAccepted(a, b string) (time.Time, error) {
+	var last time.Time
+	for _, uid := range []string{a, b} {
+		sh, _ := shard.Extract(uid)
+		var t sql.NullTime
+		if err := r.store.Write(sh).Model(&FriendRequest{}).
+			Where("status = ? AND ((from_user_id = ? AND to_user_id = ?) OR (from_user_id = ? AND to_user_id = ?))",
+				RequestAccepted, a, b, b, a).
+			Select("MAX(updated_at)").Row().Scan(&t); err != nil {
+			return time.Time{}, err
+		}
+		if t.Valid && t.Time.After(last) {
+			last = t.Time
+		}
+	}
+	return last, nil
+}
+
+// Worker drains the friend outbox and periodically repairs one-sided
+// friendships on every physical shard.
+type Worker struct {
+	repo  Repository
+	store *db.Store
+
+	RelayEvery  time.Duration
+	RepairEvery time.Duration
+	// RepairGrace leaves recent edges alone so in-flight writes can settle.
+	RepairGrace time.Duration
+}
+
+func NewWorker(r Repository, s *db.Store) *Worker {
+	return &Worker{
+		repo:        r,
+		store:       s,
+		RelayEvery:  durationEnv("FRIEND_RELAY_INTERVAL", 2*time.Second),
+		RepairEvery: durationEnv("FRIEND_REPAIR_INTERVAL", time.Hour),
+		RepairGrace: durationEnv("FRIEND_REPAIR_GRACE", 10*time.Minute),
+	}
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	relay := time.NewTicker(w.RelayEvery)
+	defer relay.Stop()
+	repair := time.NewTicker(w.RepairEvery)
+	defer repair.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-relay.C:
+			w.relayAll()
+		case <-repair.C:
+			w.repairAll()
+		}
+	}
+}
+
+func (w *Worker) relayAll() {
+	for _, id := range w.store.PhysicalIDs() {
+		for {
+			n, err := w.repo.RelayFriendOutbox(id, 200)
+			if err != nil {
+				log.Printf("friend outbox shard %d: %v", id, err)
+			}
+			if err != nil || n < 200 {
+				break
+			}
+		}
+	}
+}
+
+func (w *Worker) repairAll() {
+	before := time.Now().Add(-w.RepairGrace)
+	for _, id := range w.store.PhysicalIDs() {
+		n, err := w.repo.RepairFriends(id, before)
+		if err != nil {
+			log.Printf("repair friends shard %d: %v", id, err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("repair friends shard %d: %d edges fixed", id, n)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package social
+
+import "time"
+
+// Page is one page of a user id list. NextCursor is empty on the last page.
+type Page struct {
+	Items      []string `json:"items"`
+	Limit      int      `json:"limit"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// Edge is one entry of a follow or friend list: the other user and when the
+// edge was made. Lists are ordered newest first, ties broken by user id, and
+// an Edge is also the cursor position the next page starts after.
+type Edge struct {
+	UserID    string    `json:"u"`
+	CreatedAt time.Time `json:"t"`
+}
+
+const maxPageLimit = 200
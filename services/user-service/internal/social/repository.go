@@ -2,24 +2,71 @@ package social
 
 import (
 	"errors"
+	"time"
 
 	"users-service/internal/shared/db"
 	"users-service/internal/shared/shard"
 	"users-service/internal/user"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
 	Follow(uid, target string) error
 	Unfollow(uid, target string) error
 	ListFollowing(uid string, limit, offset int) ([]string, error)
+	ListFollowers(uid string, limit, offset int) ([]string, error)
+	// FollowingAfter, FollowersAfter and FriendsAfter return up to limit
+	// edges that come after the given one, or the newest when it is nil.
+	FollowingAfter(uid string, after *Edge, limit int) ([]Edge, error)
+	FollowersAfter(uid string, after *Edge, limit int) ([]Edge, error)
+	FriendsAfter(uid string, after *Edge, limit int) ([]Edge, error)
+	CountFollows(uid string) (followers, following int64, err error)
+	// SyncFollowers recreates missing Follower rows for every Follow stored on
+	// a physical shard and returns how many were written.
+	SyncFollowers(physical int) (int64, error)
 
-	Befriend(a, b string) error
+	// AcceptFriendRequest accepts a pending request and befriends its two
+	// users in one transaction on the sender's shard. It reports false if
+	// the request was no longer pending.
+	AcceptFriendRequest(fr *FriendRequest) (bool, error)
 	Unfriend(a, b string) error
+	// RelayFriendOutbox applies up to limit queued mirror changes from one
+	// physical shard and returns how many succeeded.
+	RelayFriendOutbox(physical, limit int) (int, error)
+	// RepairFriends fixes one-sided Friend rows on a physical shard that are
+	// older than olderThan and returns how many it changed.
+	RepairFriends(physical int, olderThan time.Time) (int, error)
 	ListFriends(uid string, limit, offset int) ([]string, error)
+	AreFriends(a, b string) (bool, error)
+	// IsFriend reports whether owner counts viewer as a friend, through a
+	// Friend edge or a RelTypeFriend relationship.
+	IsFriend(owner, viewer string) (bool, error)
+
+	CreateFriendRequest(fr *FriendRequest) error
+	GetFriendRequest(uid, requestID string) (*FriendRequest, error)
+	FindPendingRequest(from, to string) (*FriendRequest, error)
+	// TransitionFriendRequest moves a pending request to status; the
+	// recipient's copy follows through the friend outbox. It reports false if
+	// the request was no longer pending.
+	TransitionFriendRequest(fr *FriendRequest, status string) (bool, error)
+	ListFriendRequests(uid string, incoming bool, status string, limit, offset int) ([]FriendRequest, error)
+
+	Block(blocker, blocked string) error
+	Unblock(blocker, blocked string) error
+	// IsBlocked reports whether either user has blocked the other.
+	IsBlocked(a, b string) (bool, error)
+	// ListBlockedWith returns everyone uid has blocked or been blocked by.
+	ListBlockedWith(uid string) ([]string, error)
 
 	CreateRelationship(uid, related string, typ int) error
 	DeleteRelationship(uid, related string, typ int) error
 	ListRelationships(uid string, typ, limit, offset int) ([]string, error)
+
+	// Status reports how viewer relates to other, except for mutual friends.
+	Status(viewer, other string) (*RelationStatus, error)
+	CountMutualFriends(a, b string) (int64, error)
 }
 
 type repo struct {
@@ -42,18 +89,29 @@ func (r *repo) Follow(uid, target string) error {
 		return errors.New("target not found")
 	}
 	sh, _ := shard.Extract(uid)
-	return r.store.Write(sh).FirstOrCreate(&Follow{UserID: uid, TargetID: target}).Error
+	sht, _ := shard.Extract(target)
+	if err := r.store.Write(sh).FirstOrCreate(&Follow{UserID: uid, TargetID: target}).Error; err != nil {
+		return err
+	}
+	if err := r.store.Write(sht).FirstOrCreate(&Follower{UserID: target, FollowerID: uid}).Error; err != nil {
+		_ = r.store.Write(sh).Delete(&Follow{}, "user_id=? AND target_id=?", uid, target).Error
+		return err
+	}
+	return nil
 }
 func (r *repo) Unfollow(uid, target string) error {
 	sh, _ := shard.Extract(uid)
-	return r.store.Write(sh).Delete(&Follow{}, "user_id=? AND target_id=?", uid, target).Error
+	sht, _ := shard.Extract(target)
+	if err := r.store.Write(sh).Delete(&Follow{}, "user_id=? AND target_id=?", uid, target).Error; err != nil {
+		return err
+	}
+	return r.store.Write(sht).Delete(&Follower{}, "user_id=? AND follower_id=?", target, uid).Error
 }
 func (r *repo) ListFollowing(uid string, limit, offset int) ([]string, error) {
-	sh, _ := shard.Extract(uid)
 	type Row struct{ TargetID string }
 	var rows []Row
-	if err := r.store.Use(sh).Model(&Follow{}).
-		Where("user_id = ?", uid).Order("created_at DESC").
+	if err := r.store.UseFor(uid).Model(&Follow{}).
+		Where("user_id = ?", uid).Order("created_at DESC, target_id DESC").
 		Limit(limit).Offset(offset).Select("target_id").Find(&rows).Error; err != nil {
 		return nil, err
 	}
@@ -64,34 +122,111 @@ func (r *repo) ListFollowing(uid string, limit, offset int) ([]string, error) {
 	return out, nil
 }
 
-func (r *repo) Befriend(a, b string) error {
-	if a == b {
-		return errors.New("cannot friend self")
+func (r *repo) ListFollowers(uid string, limit, offset int) ([]string, error) {
+	type Row struct{ FollowerID string }
+	var rows []Row
+	if err := r.store.UseFor(uid).Model(&Follower{}).
+		Where("user_id = ?", uid).Order("created_at DESC, follower_id DESC").
+		Limit(limit).Offset(offset).Select("follower_id").Find(&rows).Error; err != nil {
+		return nil, err
 	}
-	if err := r.ensureUser(b); err != nil {
-		return errors.New("target not found")
+	out := make([]string, len(rows))
+	for i := range rows {
+		out[i] = rows[i].FollowerID
 	}
-	sha, _ := shard.Extract(a)
-	shb, _ := shard.Extract(b)
-	if err := r.store.Write(sha).FirstOrCreate(&Friend{UserID: a, FriendID: b}).Error; err != nil {
-		return err
+	return out, nil
+}
+
+func (r *repo) FollowingAfter(uid string, after *Edge, limit int) ([]Edge, error) {
+	return r.edgesAfter(&Follow{}, "target_id", uid, after, limit)
+}
+
+func (r *repo) FollowersAfter(uid string, after *Edge, limit int) ([]Edge, error) {
+	return r.edgesAfter(&Follower{}, "follower_id", uid, after, limit)
+}
+
+func (r *repo) FriendsAfter(uid string, after *Edge, limit int) ([]Edge, error) {
+	return r.edgesAfter(&Friend{}, "friend_id", uid, after, limit)
+}
+
+// edgesAfter pages through uid's rows of model by (created_at, col), newest
+// first; migration 0006 indexes each table for it.
+func (r *repo) edgesAfter(model any, col, uid string, after *Edge, limit int) ([]Edge, error) {
+	tx := r.store.UseFor(uid).Model(model).Where("user_id = ?", uid)
+	if after != nil {
+		tx = tx.Where("(created_at, "+col+") < (?, ?)", after.CreatedAt, after.UserID)
 	}
-	return r.store.Write(shb).FirstOrCreate(&Friend{UserID: b, FriendID: a}).Error
+	var out []Edge
+	err := tx.Order("created_at DESC, " + col + " DESC").Limit(limit).
+		Select(col + " AS user_id, created_at").Find(&out).Error
+	return out, err
 }
-func (r *repo) Unfriend(a, b string) error {
-	sha, _ := shard.Extract(a)
-	shb, _ := shard.Extract(b)
-	if err := r.store.Write(sha).Delete(&Friend{}, "user_id=? AND friend_id=?", a, b).Error; err != nil {
-		return err
+
+func (r *repo) CountFollows(uid string) (int64, int64, error) {
+	var followers, following int64
+	if err := r.store.UseFor(uid).Model(&Follower{}).Where("user_id = ?", uid).Count(&followers).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := r.store.UseFor(uid).Model(&Follow{}).Where("user_id = ?", uid).Count(&following).Error; err != nil {
+		return 0, 0, err
+	}
+	return followers, following, nil
+}
+
+func (r *repo) SyncFollowers(physical int) (int64, error) {
+	var total int64
+	var lastUser, lastTarget string
+	for {
+		var batch []Follow
+		if err := r.store.UsePhysical(physical).
+			Where("(user_id, target_id) > (?, ?)", lastUser, lastTarget).
+			Order("user_id, target_id").Limit(1000).Find(&batch).Error; err != nil {
+			return total, err
+		}
+		for _, f := range batch {
+			sht, _ := shard.Extract(f.TargetID)
+			res := r.store.Write(sht).Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&Follower{UserID: f.TargetID, FollowerID: f.UserID, CreatedAt: f.CreatedAt})
+			if res.Error != nil {
+				return total, res.Error
+			}
+			total += res.RowsAffected
+		}
+		if len(batch) < 1000 {
+			return total, nil
+		}
+		lastUser, lastTarget = batch[len(batch)-1].UserID, batch[len(batch)-1].TargetID
+	}
+}
+
+func (r *repo) AcceptFriendRequest(fr *FriendRequest) (bool, error) {
+	a, b := fr.FromUserID, fr.ToUserID
+	if a == b {
+		return false, errors.New("cannot friend self")
+	}
+	if err := r.ensureUser(b); err != nil {
+		return false, errors.New("target not found")
+	}
+	var ok bool
+	err := r.outboxTx(a, func(tx *gorm.DB, queue func(*FriendOutbox) error) error {
+		var err error
+		if ok, err = r.transitionRequest(tx, queue, fr, RequestAccepted); err != nil || !ok {
+			return err
+		}
+		return r.friendEdge(tx, queue, OutboxAdd, a, b)
+	})
+	if err != nil || !ok {
+		return false, err
 	}
-	return r.store.Write(shb).Delete(&Friend{}, "user_id=? AND friend_id=?", b, a).Error
+	fr.Status, fr.UpdatedAt = RequestAccepted, time.Now()
+	return true, nil
 }
+func (r *repo) Unfriend(a, b string) error { return r.writeFriendship(OutboxRemove, a, b) }
 func (r *repo) ListFriends(uid string, limit, offset int) ([]string, error) {
-	sh, _ := shard.Extract(uid)
 	type Row struct{ FriendID string }
 	var rows []Row
-	if err := r.store.Use(sh).Model(&Friend{}).
-		Where("user_id = ?", uid).Order("created_at DESC").
+	if err := r.store.UseFor(uid).Model(&Friend{}).
+		Where("user_id = ?", uid).Order("created_at DESC, friend_id DESC").
 		Limit(limit).Offset(offset).Select("friend_id").Find(&rows).Error; err != nil {
 		return nil, err
 	}
@@ -102,6 +237,146 @@ func (r *repo) ListFriends(uid string, limit, offset int) ([]string, error) {
 	return out, nil
 }
 
+func (r *repo) AreFriends(a, b string) (bool, error) {
+	var n int64
+	err := r.store.UseFor(a).Model(&Friend{}).Where("user_id = ? AND friend_id = ?", a, b).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *repo) IsFriend(owner, viewer string) (bool, error) {
+	if ok, err := r.AreFriends(owner, viewer); err != nil || ok {
+		return ok, err
+	}
+	var n int64
+	err := r.store.UseFor(owner).Model(&Relationship{}).
+		Where("user_id = ? AND related_id = ? AND type = ?", owner, viewer, RelTypeFriend).Count(&n).Error
+	return n > 0, err
+}
+
+// requestShards returns the shards holding copies of a request, sender's first.
+func requestShards(fr *FriendRequest) []int {
+	shf, _ := shard.Extract(fr.FromUserID)
+	sht, _ := shard.Extract(fr.ToUserID)
+	if shf == sht {
+		return []int{shf}
+	}
+	return []int{shf, sht}
+}
+
+func (r *repo) CreateFriendRequest(fr *FriendRequest) error {
+	if err := r.ensureUser(fr.ToUserID); err != nil {
+		return errors.New("target not found")
+	}
+	shards := requestShards(fr)
+	if err := r.store.Write(shards[0]).Create(fr).Error; err != nil {
+		return err
+	}
+	if len(shards) == 2 {
+		mirror := *fr
+		if err := r.store.Write(shards[1]).Create(&mirror).Error; err != nil {
+			_ = r.store.Write(shards[0]).Delete(&FriendRequest{}, "request_id = ?", fr.RequestID).Error
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) GetFriendRequest(uid, requestID string) (*FriendRequest, error) {
+	sh, _ := shard.Extract(uid)
+	var fr FriendRequest
+	if err := r.store.Write(sh).Where("request_id = ?", requestID).First(&fr).Error; err != nil {
+		return nil, err
+	}
+	return &fr, nil
+}
+
+func (r *repo) FindPendingRequest(from, to string) (*FriendRequest, error) {
+	sh, _ := shard.Extract(from)
+	var fr FriendRequest
+	err := r.store.Write(sh).
+		Where("from_user_id = ? AND to_user_id = ? AND status = ?", from, to, RequestPending).
+		First(&fr).Error
+	if err != nil {
+		return nil, err
+	}
+	return &fr, nil
+}
+
+func (r *repo) TransitionFriendRequest(fr *FriendRequest, status string) (bool, error) {
+	var ok bool
+	err := r.outboxTx(fr.FromUserID, func(tx *gorm.DB, queue func(*FriendOutbox) error) error {
+		var err error
+		ok, err = r.transitionRequest(tx, queue, fr, status)
+		return err
+	})
+	if err != nil || !ok {
+		return false, err
+	}
+	fr.Status, fr.UpdatedAt = status, time.Now()
+	return true, nil
+}
+
+func (r *repo) ListFriendRequests(uid string, incoming bool, status string, limit, offset int) ([]FriendRequest, error) {
+	col := "from_user_id"
+	if incoming {
+		col = "to_user_id"
+	}
+	q := r.store.UseFor(uid).Where(col+" = ?", uid)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var out []FriendRequest
+	err := q.Order("created_at DESC").Limit(limit).Offset(offset).Find(&out).Error
+	return out, err
+}
+
+func (r *repo) Block(blocker, blocked string) error {
+	if blocker == blocked {
+		return errors.New("cannot block self")
+	}
+	if err := r.ensureUser(blocked); err != nil {
+		return errors.New("target not found")
+	}
+	sha, _ := shard.Extract(blocker)
+	shb, _ := shard.Extract(blocked)
+	if err := r.store.Write(sha).FirstOrCreate(&Relationship{UserID: blocker, RelatedID: blocked, Type: RelTypeBlock}).Error; err != nil {
+		return err
+	}
+	return r.store.Write(shb).FirstOrCreate(&BlockedBy{UserID: blocked, BlockerID: blocker}).Error
+}
+
+func (r *repo) Unblock(blocker, blocked string) error {
+	sha, _ := shard.Extract(blocker)
+	shb, _ := shard.Extract(blocked)
+	if err := r.store.Write(sha).Delete(&Relationship{}, "user_id=? AND related_id=? AND type=?", blocker, blocked, RelTypeBlock).Error; err != nil {
+		return err
+	}
+	return r.store.Write(shb).Delete(&BlockedBy{}, "user_id=? AND blocker_id=?", blocked, blocker).Error
+}
+
+func (r *repo) IsBlocked(a, b string) (bool, error) {
+	var n int64
+	if err := r.store.UseFor(a).Model(&Relationship{}).
+		Where("user_id = ? AND related_id = ? AND type = ?", a, b, RelTypeBlock).Count(&n).Error; err != nil || n > 0 {
+		return n > 0, err
+	}
+	err := r.store.UseFor(a).Model(&BlockedBy{}).Where("user_id = ? AND blocker_id = ?", a, b).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *repo) ListBlockedWith(uid string) ([]string, error) {
+	var out, by []string
+	if err := r.store.UseFor(uid).Model(&Relationship{}).
+		Where("user_id = ? AND type = ?", uid, RelTypeBlock).Pluck("related_id", &out).Error; err != nil {
+		return nil, err
+	}
+	if err := r.store.UseFor(uid).Model(&BlockedBy{}).
+		Where("user_id = ?", uid).Pluck("blocker_id", &by).Error; err != nil {
+		return nil, err
+	}
+	return append(out, by...), nil
+}
+
 func (r *repo) CreateRelationship(uid, related string, typ int) error {
 	if uid == related {
 		return errors.New("cannot relate to self")
@@ -120,10 +395,9 @@ func (r *repo) DeleteRelationship(uid, related string, typ int) error {
 }
 
 func (r *repo) ListRelationships(uid string, typ, limit, offset int) ([]string, error) {
-	sh, _ := shard.Extract(uid)
 	type Row struct{ RelatedID string }
 	var rows []Row
-	dbq := r.store.Use(sh).Model(&Relationship{}).Where("user_id = ?", uid)
+	dbq := r.store.UseFor(uid).Model(&Relationship{}).Where("user_id = ?", uid)
 	if typ != 0 {
 		dbq = dbq.Where("type = ?", typ)
 	}
@@ -1,37 +1,449 @@
 package social
 
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"users-service/internal/events"
+	"users-service/internal/kafka"
+	"users-service/internal/profile"
+	"users-service/internal/shared/cursor"
+	"users-service/internal/shared/httpx"
+)
+
 type Service interface {
 	Follow(uid, target string) error
 	Unfollow(uid, target string) error
 	ListFollowing(uid string, limit, offset int) ([]string, error)
-	Befriend(a, b string) error
+	ListFollowers(uid string, limit, offset int) ([]string, error)
+	CountFollows(uid string) (followers, following int64, err error)
 	Unfriend(a, b string) error
 	ListFriends(uid string, limit, offset int) ([]string, error)
+	// ListFriendsOf lists owner's friends for viewer, subject to owner's
+	// privacy settings.
+	ListFriendsOf(viewer, owner string, limit, offset int) ([]string, error)
+	// PageFollowing, PageFollowers and PageFriendsOf are the cursor-paged
+	// forms of the lists above; after is the NextCursor of the previous page.
+	PageFollowing(uid, after string, limit int) (*Page, error)
+	PageFollowers(uid, after string, limit int) (*Page, error)
+	PageFriendsOf(viewer, owner, after string, limit int) (*Page, error)
 	CreateRelationship(uid, related string, typ int) error
 	DeleteRelationship(uid, related string, typ int) error
 	ListRelationships(uid string, typ, limit, offset int) ([]string, error)
+	// RelationStatus sums up how viewer relates to other in one call.
+	RelationStatus(viewer, other string) (*RelationStatus, error)
+
+	// SendFriendRequest asks `to` for friendship. If `to` already has a
+	// pending request to `from`, that request is accepted instead.
+	SendFriendRequest(from, to string) (*FriendRequest, error)
+	ListFriendRequests(uid string, incoming bool, status string, limit, offset int) ([]FriendRequest, error)
+	// UpdateFriendRequest applies uid's decision: the recipient may accept or
+	// decline, the sender may cancel.
+	UpdateFriendRequest(uid, requestID, status string) (*FriendRequest, error)
+
+	// Block hides the two users from each other: existing follows, friendship
+	// and pending requests in either direction are removed.
+	Block(uid, target string) error
+	Unblock(uid, target string) error
+	ListBlocked(uid string, limit, offset int) ([]string, error)
+	IsBlocked(a, b string) (bool, error)
+	ListBlockedWith(uid string) ([]string, error)
+
+	// Sever removes every follow, friendship, block and pending friend
+	// request between uid and anyone else, on both sides. It is part of
+	// deleting an account.
+	Sever(uid string) error
 }
 
-type service struct{ repo Repository }
+type service struct {
+	repo   Repository
+	blocks *kafka.Writer
+	events *events.Publisher
+	vis    Visibility
+}
 
-func NewService(r Repository) Service { return &service{repo: r} }
+// Visibility applies users' privacy settings.
+type Visibility interface {
+	CanSee(owner, viewer, field string) (bool, error)
+}
 
-func (s *service) Follow(uid, target string) error   { return s.repo.Follow(uid, target) }
-func (s *service) Unfollow(uid, target string) error { return s.repo.Unfollow(uid, target) }
+func NewService(r Repository, blocks *kafka.Writer, pub *events.Publisher, vis Visibility) Service {
+	return &service{repo: r, blocks: blocks, events: pub, vis: vis}
+}
+
+var (
+	ErrAlreadyFriends   = errors.New("already friends")
+	ErrRequestPending   = errors.New("friend request already pending")
+	ErrRequestNotFound  = fmt.Errorf("%w: friend request", httpx.ErrNotFound)
+	ErrRequestNotActive = errors.New("friend request is no longer pending")
+	ErrBlocked          = fmt.Errorf("%w: blocked", httpx.ErrForbidden)
+)
+
+func (s *service) checkNotBlocked(a, b string) error {
+	blocked, err := s.repo.IsBlocked(a, b)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+func (s *service) Follow(uid, target string) error {
+	if err := s.checkNotBlocked(uid, target); err != nil {
+		return err
+	}
+	if err := s.repo.Follow(uid, target); err != nil {
+		return err
+	}
+	s.publishFollow(events.TopicFollowed, uid, target)
+	return nil
+}
+func (s *service) Unfollow(uid, target string) error {
+	if err := s.repo.Unfollow(uid, target); err != nil {
+		return err
+	}
+	s.publishFollow(events.TopicUnfollowed, uid, target)
+	return nil
+}
 func (s *service) ListFollowing(uid string, limit, offset int) ([]string, error) {
 	return s.repo.ListFollowing(uid, limit, offset)
 }
-func (s *service) Befriend(a, b string) error { return s.repo.Befriend(a, b) }
+func (s *service) ListFollowers(uid string, limit, offset int) ([]string, error) {
+	return s.repo.ListFollowers(uid, limit, offset)
+}
+func (s *service) CountFollows(uid string) (int64, int64, error) {
+	return s.repo.CountFollows(uid)
+}
 func (s *service) Unfriend(a, b string) error { return s.repo.Unfriend(a, b) }
 func (s *service) ListFriends(uid string, limit, offset int) ([]string, error) {
 	return s.repo.ListFriends(uid, limit, offset)
 }
+func (s *service) ListFriendsOf(viewer, owner string, limit, offset int) ([]string, error) {
+	ok, err := s.vis.CanSee(owner, viewer, profile.FieldFriends)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, profile.ErrHidden
+	}
+	return s.repo.ListFriends(owner, limit, offset)
+}
+func (s *service) PageFollowing(uid, after string, limit int) (*Page, error) {
+	return page(after, limit, func(from *Edge, n int) ([]Edge, error) {
+		return s.repo.FollowingAfter(uid, from, n)
+	})
+}
+func (s *service) PageFollowers(uid, after string, limit int) (*Page, error) {
+	return page(after, limit, func(from *Edge, n int) ([]Edge, error) {
+		return s.repo.FollowersAfter(uid, from, n)
+	})
+}
+func (s *service) PageFriendsOf(viewer, owner, after string, limit int) (*Page, error) {
+	ok, err := s.vis.CanSee(owner, viewer, profile.FieldFriends)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, profile.ErrHidden
+	}
+	return page(after, limit, func(from *Edge, n int) ([]Edge, error) {
+		return s.repo.FriendsAfter(owner, from, n)
+	})
+}
+
+// page decodes the cursor, fetches one edge more than asked to learn whether
+// another page follows, and builds the Page.
+func page(after string, limit int, fetch func(from *Edge, n int) ([]Edge, error)) (*Page, error) {
+	if limit <= 0 || limit > maxPageLimit {
+		limit = 50
+	}
+	var from *Edge
+	var pos Edge
+	if ok, err := cursor.Decode(after, &pos); err != nil {
+		return nil, err
+	} else if ok {
+		from = &pos
+	}
+	edges, err := fetch(from, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	p := &Page{Items: make([]string, 0, len(edges)), Limit: limit}
+	if len(edges) > limit {
+		edges = edges[:limit]
+		p.NextCursor = cursor.Encode(edges[limit-1])
+	}
+	for _, e := range edges {
+		p.Items = append(p.Items, e.UserID)
+	}
+	return p, nil
+}
+
 func (s *service) CreateRelationship(uid, related string, typ int) error {
+	if typ == RelTypeBlock {
+		return s.Block(uid, related)
+	}
+	if err := s.checkNotBlocked(uid, related); err != nil {
+		return err
+	}
 	return s.repo.CreateRelationship(uid, related, typ)
 }
 func (s *service) DeleteRelationship(uid, related string, typ int) error {
+	if typ == RelTypeBlock {
+		return s.Unblock(uid, related)
+	}
 	return s.repo.DeleteRelationship(uid, related, typ)
 }
 func (s *service) ListRelationships(uid string, typ, limit, offset int) ([]string, error) {
 	return s.repo.ListRelationships(uid, typ, limit, offset)
 }
+
+func (s *service) RelationStatus(viewer, other string) (*RelationStatus, error) {
+	if viewer == other {
+		return nil, errors.New("cannot relate to self")
+	}
+	st, err := s.repo.Status(viewer, other)
+	if err != nil || st.Blocking || st.BlockedBy {
+		return st, err
+	}
+	ok, err := s.vis.CanSee(other, viewer, profile.FieldFriends)
+	if err != nil || !ok {
+		return st, err
+	}
+	n, err := s.repo.CountMutualFriends(viewer, other)
+	if err != nil {
+		return nil, err
+	}
+	st.MutualFriends = &n
+	return st, nil
+}
+
+func newRequestID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (s *service) SendFriendRequest(from, to string) (*FriendRequest, error) {
+	if from == to {
+		return nil, errors.New("cannot friend self")
+	}
+	if err := s.checkNotBlocked(from, to); err != nil {
+		return nil, err
+	}
+	if friends, err := s.repo.AreFriends(from, to); err != nil {
+		return nil, err
+	} else if friends {
+		return nil, ErrAlreadyFriends
+	}
+	if rev, err := s.repo.FindPendingRequest(to, from); err == nil {
+		return s.accept(rev)
+	}
+	if _, err := s.repo.FindPendingRequest(from, to); err == nil {
+		return nil, ErrRequestPending
+	}
+	fr := &FriendRequest{RequestID: newRequestID(), FromUserID: from, ToUserID: to, Status: RequestPending}
+	if err := s.repo.CreateFriendRequest(fr); err != nil {
+		return nil, err
+	}
+	return fr, nil
+}
+
+func (s *service) ListFriendRequests(uid string, incoming bool, status string, limit, offset int) ([]FriendRequest, error) {
+	return s.repo.ListFriendRequests(uid, incoming, status, limit, offset)
+}
+
+func (s *service) UpdateFriendRequest(uid, requestID, status string) (*FriendRequest, error) {
+	fr, err := s.repo.GetFriendRequest(uid, requestID)
+	if err != nil {
+		return nil, ErrRequestNotFound
+	}
+	if status == "rejected" {
+		status = RequestDeclined
+	}
+	switch {
+	case status == RequestCancelled && fr.FromUserID == uid:
+	case (status == RequestAccepted || status == RequestDeclined) && fr.ToUserID == uid:
+	case fr.FromUserID == uid || fr.ToUserID == uid:
+		return nil, httpx.ErrForbidden
+	default:
+		return nil, ErrRequestNotFound
+	}
+	if fr.Status != RequestPending {
+		return nil, ErrRequestNotActive
+	}
+	if status == RequestAccepted {
+		if err := s.checkNotBlocked(fr.FromUserID, fr.ToUserID); err != nil {
+			return nil, err
+		}
+		return s.accept(fr)
+	}
+	ok, err := s.repo.TransitionFriendRequest(fr, status)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRequestNotActive
+	}
+	return fr, nil
+}
+
+func (s *service) accept(fr *FriendRequest) (*FriendRequest, error) {
+	ok, err := s.repo.AcceptFriendRequest(fr)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRequestNotActive
+	}
+	s.events.Publish(events.TopicFriended, fr.FromUserID, events.Friended{
+		Meta:   events.NewMeta(events.TopicFriended),
+		UserID: fr.FromUserID, FriendID: fr.ToUserID, RequestID: fr.RequestID,
+	})
+	return fr, nil
+}
+
+func (s *service) Block(uid, target string) error {
+	if err := s.repo.Block(uid, target); err != nil {
+		return err
+	}
+	for _, pair := range [][2]string{{uid, target}, {target, uid}} {
+		// Through s.Unfollow so feeds and suggestions hear about it.
+		if err := s.Unfollow(pair[0], pair[1]); err != nil {
+			return err
+		}
+		if err := s.repo.DeleteRelationship(pair[0], pair[1], RelTypeFollow); err != nil {
+			return err
+		}
+		if fr, err := s.repo.FindPendingRequest(pair[0], pair[1]); err == nil {
+			if _, err := s.repo.TransitionFriendRequest(fr, RequestCancelled); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.repo.Unfriend(uid, target); err != nil {
+		return err
+	}
+	s.publishBlock("blocked", uid, target)
+	return nil
+}
+
+func (s *service) Unblock(uid, target string) error {
+	if err := s.repo.Unblock(uid, target); err != nil {
+		return err
+	}
+	s.publishBlock("unblocked", uid, target)
+	return nil
+}
+
+func (s *service) ListBlocked(uid string, limit, offset int) ([]string, error) {
+	return s.repo.ListRelationships(uid, RelTypeBlock, limit, offset)
+}
+
+func (s *service) IsBlocked(a, b string) (bool, error) { return s.repo.IsBlocked(a, b) }
+
+func (s *service) ListBlockedWith(uid string) ([]string, error) { return s.repo.ListBlockedWith(uid) }
+
+func (s *service) Sever(uid string) error {
+	following, err := collect(func(limit, offset int) ([]string, error) { return s.repo.ListFollowing(uid, limit, offset) })
+	if err != nil {
+		return err
+	}
+	for _, id := range following {
+		if err := s.Unfollow(uid, id); err != nil {
+			return err
+		}
+	}
+	followers, err := collect(func(limit, offset int) ([]string, error) { return s.repo.ListFollowers(uid, limit, offset) })
+	if err != nil {
+		return err
+	}
+	for _, id := range followers {
+		if err := s.Unfollow(id, uid); err != nil {
+			return err
+		}
+	}
+	friends, err := collect(func(limit, offset int) ([]string, error) { return s.repo.ListFriends(uid, limit, offset) })
+	if err != nil {
+		return err
+	}
+	for _, id := range friends {
+		if err := s.repo.Unfriend(uid, id); err != nil {
+			return err
+		}
+	}
+	blocked, err := s.repo.ListBlockedWith(uid)
+	if err != nil {
+		return err
+	}
+	for _, id := range blocked {
+		if err := s.repo.Unblock(uid, id); err != nil {
+			return err
+		}
+		if err := s.repo.Unblock(id, uid); err != nil {
+			return err
+		}
+		s.publishBlock("unblocked", uid, id)
+	}
+	for _, incoming := range []bool{true, false} {
+		var reqs []FriendRequest
+		for offset := 0; ; offset += 200 {
+			page, err := s.repo.ListFriendRequests(uid, incoming, RequestPending, 200, offset)
+			if err != nil {
+				return err
+			}
+			reqs = append(reqs, page...)
+			if len(page) < 200 {
+				break
+			}
+		}
+		for i := range reqs {
+			if _, err := s.repo.TransitionFriendRequest(&reqs[i], RequestCancelled); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// collect reads a whole list page by page before anything in it is changed.
+func collect(list func(limit, offset int) ([]string, error)) ([]string, error) {
+	const page = 500
+	var out []string
+	for offset := 0; ; offset += page {
+		ids, err := list(page, offset)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ids...)
+		if len(ids) < page {
+			return out, nil
+		}
+	}
+}
+
+// publishBlock lets other services drop cached block sets right away instead
+// of waiting for their TTL. Failures only delay propagation, so they are logged.
+func (s *service) publishBlock(typ, blocker, blocked string) {
+	if s.blocks == nil {
+		return
+	}
+	b, _ := json.Marshal(BlockEvent{Type: typ, BlockerID: blocker, BlockedID: blocked, At: time.Now().UTC()})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.blocks.Publish(ctx, blocker, b); err != nil {
+		log.Printf("publish %s event: %v", typ, err)
+	}
+}
+
+func (s *service) publishFollow(topic, uid, target string) {
+	s.events.Publish(topic, uid, events.Followed{Meta: events.NewMeta(topic), UserID: uid, TargetID: target})
+}
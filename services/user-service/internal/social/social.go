@@ -7,14 +7,103 @@ type Follow struct {
 	TargetID  string `gorm:"primaryKey;size:64"`
 	CreatedAt time.Time
 }
+
+// Follower is the reverse edge of Follow, kept on the target's shard so a
+// user's followers can be listed and counted locally.
+type Follower struct {
+	UserID     string `gorm:"primaryKey;size:64"`
+	FollowerID string `gorm:"primaryKey;size:64"`
+	CreatedAt  time.Time
+}
 type Friend struct {
 	UserID    string `gorm:"primaryKey;size:64"`
 	FriendID  string `gorm:"primaryKey;size:64"`
 	CreatedAt time.Time
 }
+
+// FriendRemoval records when uid last dropped friend, on uid's shard. It is
+// written with the local edge removal so the repair job can tell an edge left
+// behind by an unfriend from one whose mirror was never written.
+type FriendRemoval struct {
+	UserID    string `gorm:"primaryKey;size:64"`
+	FriendID  string `gorm:"primaryKey;size:64"`
+	RemovedAt time.Time
+}
+
+const (
+	OutboxAdd    = "add"
+	OutboxRemove = "remove"
+	// OutboxRequest sets the recipient's copy of friend request RequestID to
+	// Status.
+	OutboxRequest = "request"
+)
+
+// FriendOutbox is the mirrored half of a friendship change. It is written in
+// the same transaction as the local Friend edge or friend request and applied
+// to the other user's shard by the relay, which retries until it succeeds.
+// Entries for the same pair are applied in id order.
+type FriendOutbox struct {
+	ID          uint64    `gorm:"primaryKey"`
+	Op          string    `gorm:"size:16"`
+	UserID      string    `gorm:"size:64;index:idx_friend_outbox_edge"`
+	FriendID    string    `gorm:"size:64;index:idx_friend_outbox_edge"`
+	RequestID   string    `gorm:"size:64"`
+	Status      string    `gorm:"size:16"`
+	Attempts    int       `gorm:"not null;default:0"`
+	LastError   string    `gorm:"size:500"`
+	NextAttempt time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
+
+// BlockedBy mirrors a RelTypeBlock relationship onto the blocked user's shard
+// so both directions of a block can be answered from a single shard.
+type BlockedBy struct {
+	UserID    string `gorm:"primaryKey;size:64"`
+	BlockerID string `gorm:"primaryKey;size:64"`
+	CreatedAt time.Time
+}
 type Relationship struct {
 	UserID    string `gorm:"primaryKey;size:64"`
 	RelatedID string `gorm:"primaryKey;size:64"`
 	Type      int    `gorm:"primaryKey"`
 	CreatedAt time.Time
 }
+
+const (
+	RequestPending   = "pending"
+	RequestAccepted  = "accepted"
+	RequestDeclined  = "declined"
+	RequestCancelled = "cancelled"
+)
+
+// FriendRequest is stored on both the sender's and the recipient's shard so
+// that either side can list and act on it locally.
+type FriendRequest struct {
+	RequestID  string    `gorm:"primaryKey;size:64" json:"request_id"`
+	FromUserID string    `gorm:"size:64;index;index:idx_friend_requests_pending,unique,where:status = 'pending'" json:"from_user_id"`
+	ToUserID   string    `gorm:"size:64;index;index:idx_friend_requests_pending,unique,where:status = 'pending'" json:"to_user_id"`
+	Status     string    `gorm:"size:16;index" json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type SendFriendRequestReq struct {
+	ToUserID string `json:"to_user_id" validate:"required"`
+}
+
+type UpdateFriendRequestReq struct {
+	Status string `json:"status" validate:"required,oneof=accepted rejected declined cancelled"`
+}
+
+type BlockReq struct {
+	BlockedID string `json:"blocked_id" validate:"required"`
+}
+
+const BlocksTopic = "social.blocks"
+
+type BlockEvent struct {
+	Type      string    `json:"type"` // blocked | unblocked
+	BlockerID string    `json:"blocker_id"`
+	BlockedID string    `json:"blocked_id"`
+	At        time.Time `json:"at"`
+}
@@ -0,0 +1,96 @@
+package social
+
+import (
+	"errors"
+	"fmt"
+
+	"users-service/internal/shared/httpx"
+
+	"gorm.io/gorm"
+)
+
+// RelationStatus is how the viewer and another user are connected, as seen by
+// the viewer. MutualFriends is omitted when the other user's friend list is
+// hidden from the viewer or either has blocked the other.
+type RelationStatus struct {
+	UserID        string          `json:"user_id"`
+	Following     bool            `json:"following"`
+	FollowedBy    bool            `json:"followed_by"`
+	Friends       bool            `json:"friends"`
+	FriendRequest *PendingRequest `json:"friend_request,omitempty"`
+	Blocking      bool            `json:"blocking"`
+	BlockedBy     bool            `json:"blocked_by"`
+	MutualFriends *int64          `json:"mutual_friends,omitempty"`
+}
+
+// PendingRequest is a friend request waiting between the two users; Outgoing
+// is set when the viewer sent it.
+type PendingRequest struct {
+	RequestID string `json:"request_id"`
+	Outgoing  bool   `json:"outgoing"`
+}
+
+// maxMutualScan bounds how many of the viewer's friends are matched against
+// the other user's.
+const maxMutualScan = 5000
+
+func has(tx *gorm.DB, model any, query string, args ...any) (bool, error) {
+	var n int64
+	err := tx.Model(model).Where(query, args...).Limit(1).Count(&n).Error
+	return n > 0, err
+}
+
+// Status reads each direction of the relation from the shard of the user it
+// starts from: the viewer's follows and blocks from the viewer's shard, the
+// other user's from theirs.
+func (r *repo) Status(viewer, other string) (*RelationStatus, error) {
+	if err := r.ensureUser(other); err != nil {
+		return nil, fmt.Errorf("%w: user", httpx.ErrNotFound)
+	}
+	st := &RelationStatus{UserID: other}
+	mine, theirs := r.store.UseFor(viewer), r.store.UseFor(other)
+	checks := []struct {
+		dst   *bool
+		tx    *gorm.DB
+		model any
+		query string
+		args  []any
+	}{
+		{&st.Following, mine, &Follow{}, "user_id = ? AND target_id = ?", []any{viewer, other}},
+		{&st.FollowedBy, theirs, &Follow{}, "user_id = ? AND target_id = ?", []any{other, viewer}},
+		{&st.Friends, mine, &Friend{}, "user_id = ? AND friend_id = ?", []any{viewer, other}},
+		{&st.Blocking, mine, &Relationship{}, "user_id = ? AND related_id = ? AND type = ?", []any{viewer, other, RelTypeBlock}},
+		{&st.BlockedBy, theirs, &Relationship{}, "user_id = ? AND related_id = ? AND type = ?", []any{other, viewer, RelTypeBlock}},
+	}
+	for _, c := range checks {
+		ok, err := has(c.tx, c.model, c.query, c.args...)
+		if err != nil {
+			return nil, err
+		}
+		*c.dst = ok
+	}
+
+	var fr FriendRequest
+	err := mine.Where("status = ? AND ((from_user_id = ? AND to_user_id = ?) OR (from_user_id = ? AND to_user_id = ?))",
+		RequestPending, viewer, other, other, viewer).Take(&fr).Error
+	switch {
+	case err == nil:
+		st.FriendRequest = &PendingRequest{RequestID: fr.RequestID, Outgoing: fr.FromUserID == viewer}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+	return st, nil
+}
+
+// CountMutualFriends matches a's friends, read from a's shard, against b's on
+// b's shard.
+func (r *repo) CountMutualFriends(a, b string) (int64, error) {
+	var mine []string
+	if err := r.store.UseFor(a).Model(&Friend{}).Where("user_id = ?", a).
+		Limit(maxMutualScan).Pluck("friend_id", &mine).Error; err != nil || len(mine) == 0 {
+		return 0, err
+	}
+	var n int64
+	err := r.store.UseFor(b).Model(&Friend{}).Where("user_id = ? AND friend_id IN ?", b, mine).Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,67 @@
+package suggest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache keeps each user's ranked list under suggest:people:{uid}.
+type Cache struct {
+	r   *redis.Client
+	ttl time.Duration
+}
+
+func OpenCacheFromEnv() *Cache {
+	addr := os.Getenv("SUGGEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "redis-users:6379"
+	}
+	ttl, err := time.ParseDuration(os.Getenv("SUGGEST_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = 6 * time.Hour
+	}
+	return &Cache{ttl: ttl, r: redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 500 * time.Millisecond,
+	})}
+}
+
+func key(uid string) string { return "suggest:people:" + uid }
+
+// Get reports false when uid has no cached list.
+func (c *Cache) Get(ctx context.Context, uid string) ([]Suggestion, bool, error) {
+	b, err := c.r.Get(ctx, key(uid)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var out []Suggestion
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, false, nil
+	}
+	return out, true, nil
+}
+
+func (c *Cache) Set(ctx context.Context, uid string, list []Suggestion) error {
+	b, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return c.r.Set(ctx, key(uid), b, c.ttl).Err()
+}
+
+// Drop removes uid's list and reports whether there was one.
+func (c *Cache) Drop(ctx context.Context, uid string) (bool, error) {
+	n, err := c.r.Del(ctx, key(uid)).Result()
+	return n > 0, err
+}
+
+func (c *Cache) Close() error { return c.r.Close() }
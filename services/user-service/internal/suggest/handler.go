@@ -0,0 +1,26 @@
+package suggest
+
+import (
+	"net/http"
+
+	"users-service/internal/shared/httpx"
+)
+
+type Handler struct{ svc Service }
+
+func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+
+func (h *Handler) People(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	limit := httpx.QueryInt(r, "limit", 20)
+	offset := httpx.QueryInt(r, "offset", 0)
+	items, err := h.svc.People(r.Context(), uid, limit, offset)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,107 @@
+package suggest
+
+import (
+	"context"
+	"sort"
+
+	"users-service/internal/shared/db"
+	"users-service/internal/shared/shard"
+)
+
+type Repository interface {
+	// LoadViewer reads uid's own interests and city.
+	LoadViewer(uid string) (*Viewer, error)
+	// Mutuals expands friends into their friends and counts, for each one,
+	// how many of friends lead to them. Friends who keep their friend list
+	// private are not expanded. At most perShard people come from each
+	// physical shard.
+	Mutuals(ctx context.Context, friends []string, perShard int) (map[string]int, error)
+	// Describe fills in name, shared interests and city for the given
+	// users, dropping suspended and unknown ones. Only public interests and
+	// cities count.
+	Describe(ctx context.Context, v *Viewer, ids []string) ([]Suggestion, error)
+}
+
+type repo struct{ store *db.Store }
+
+func NewRepository(s *db.Store) Repository { return &repo{store: s} }
+
+func (r *repo) LoadViewer(uid string) (*Viewer, error) {
+	v := &Viewer{UserID: uid}
+	tx := r.store.UseFor(uid)
+	if err := tx.Table("interest_users").Where("user_id = ?", uid).Pluck("interest_id", &v.Interests).Error; err != nil {
+		return nil, err
+	}
+	var cities []uint64
+	if err := tx.Table("profiles").Where("user_id = ?", uid).Pluck("city_id", &cities).Error; err != nil {
+		return nil, err
+	}
+	if len(cities) > 0 {
+		v.CityID = cities[0]
+	}
+	return v, nil
+}
+
+// byPhysical groups user ids by the physical shard holding them.
+func (r *repo) byPhysical(ids []string) map[int][]string {
+	out := make(map[int][]string)
+	for _, id := range ids {
+		if l, ok := shard.Extract(id); ok {
+			p := r.store.Physical(l)
+			out[p] = append(out[p], id)
+		}
+	}
+	return out
+}
+
+func (r *repo) Mutuals(ctx context.Context, friends []string, perShard int) (map[string]int, error) {
+	out := make(map[string]int)
+	for physical, ids := range r.byPhysical(friends) {
+		var rows []struct {
+			FriendID string
+			Mutual   int
+		}
+		err := r.store.UsePhysical(physical).WithContext(ctx).Table("friends f").
+			Select("f.friend_id, count(*) AS mutual").
+			Joins("LEFT JOIN privacy_settings ps ON ps.user_id = f.user_id").
+			Where("f.user_id IN ? AND COALESCE(ps.friends, '') <> 'private'", ids).
+			Group("f.friend_id").Order("mutual DESC, f.friend_id").Limit(perShard).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			out[row.FriendID] += row.Mutual
+		}
+	}
+	return out, nil
+}
+
+func (r *repo) Describe(ctx context.Context, v *Viewer, ids []string) ([]Suggestion, error) {
+	var out []Suggestion
+	for physical, group := range r.byPhysical(ids) {
+		args := map[string]any{
+			"ids":       group,
+			"logical":   r.store.LogicalOn(physical),
+			"interests": v.Interests,
+			"city":      v.CityID,
+		}
+		var rows []Suggestion
+		err := r.store.UsePhysical(physical).WithContext(ctx).Table("users u").
+			Select(`u.user_id, u.name,
+				CASE WHEN COALESCE(NULLIF(ps.interests, ''), 'public') = 'public' THEN
+					(SELECT count(*) FROM interest_users iu WHERE iu.user_id = u.user_id AND iu.interest_id IN @interests)
+				ELSE 0 END AS shared_interests,
+				COALESCE(@city <> 0 AND p.city_id = @city AND COALESCE(NULLIF(ps.city, ''), 'public') = 'public', false) AS same_city`, args).
+			Joins("LEFT JOIN profiles p ON p.user_id = u.user_id").
+			Joins("LEFT JOIN privacy_settings ps ON ps.user_id = u.user_id").
+			Where("u.user_id IN @ids AND u.shard_id IN @logical AND u.suspended_at IS NULL", args).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rows...)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UserID < out[j].UserID })
+	return out, nil
+}
@@ -0,0 +1,188 @@
+package suggest
+
+import (
+	"context"
+	"log"
+	"sort"
+)
+
+type Service interface {
+	// People returns a page of uid's suggestions, computing and caching the
+	// full list on a miss.
+	People(ctx context.Context, uid string, limit, offset int) ([]Suggestion, error)
+	// Refresh recomputes uid's cached list, if it has one, after something
+	// that affects it changed.
+	Refresh(ctx context.Context, uid string) error
+}
+
+// Graph is the viewer's side of the social graph.
+type Graph interface {
+	ListFriends(uid string, limit, offset int) ([]string, error)
+	ListFollowing(uid string, limit, offset int) ([]string, error)
+	ListBlockedWith(uid string) ([]string, error)
+}
+
+// Discovery finds users by shared interests and city across shards.
+type Discovery interface {
+	Candidates(ctx context.Context, viewer string, limit int) ([]string, error)
+}
+
+type service struct {
+	repo  Repository
+	cache *Cache
+	graph Graph
+	disc  Discovery
+}
+
+func NewService(r Repository, c *Cache, g Graph, d Discovery) Service {
+	return &service{repo: r, cache: c, graph: g, disc: d}
+}
+
+// Caps bounding the work behind one list. Friends past maxExpand are still
+// excluded but not expanded; maxExclude bounds how many friends and follows
+// are read for exclusion.
+const (
+	maxExpand     = 200
+	maxExclude    = 5000
+	perShard      = 500
+	maxCandidates = 300
+	maxDiscovered = 100
+	listSize      = 100
+	maxLimit      = 50
+)
+
+func (s *service) People(ctx context.Context, uid string, limit, offset int) ([]Suggestion, error) {
+	if limit <= 0 || limit > maxLimit {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	list, ok, err := s.cache.Get(ctx, uid)
+	if err != nil {
+		log.Printf("suggest cache %s: %v", uid, err)
+	}
+	if !ok {
+		if list, err = s.compute(ctx, uid); err != nil {
+			return nil, err
+		}
+		if err := s.cache.Set(ctx, uid, list); err != nil {
+			log.Printf("suggest cache %s: %v", uid, err)
+		}
+	}
+	// Blocks take effect at once, whatever the cache holds.
+	blocked, err := s.graph.ListBlockedWith(uid)
+	if err != nil {
+		return nil, err
+	}
+	hidden := toSet(blocked)
+	out := make([]Suggestion, 0, limit)
+	for _, sg := range list {
+		if hidden[sg.UserID] {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(out) == limit {
+			break
+		}
+		out = append(out, sg)
+	}
+	return out, nil
+}
+
+func (s *service) Refresh(ctx context.Context, uid string) error {
+	had, err := s.cache.Drop(ctx, uid)
+	if err != nil || !had {
+		return err
+	}
+	list, err := s.compute(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, uid, list)
+}
+
+// compute builds uid's ranked list from friends of friends and from users
+// sharing interests or city, leaving out uid, their friends, the people they
+// follow and everyone they have a block with.
+func (s *service) compute(ctx context.Context, uid string) ([]Suggestion, error) {
+	friends, err := s.graph.ListFriends(uid, maxExclude, 0)
+	if err != nil {
+		return nil, err
+	}
+	following, err := s.graph.ListFollowing(uid, maxExclude, 0)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := s.graph.ListBlockedWith(uid)
+	if err != nil {
+		return nil, err
+	}
+	skip := toSet(friends, following, blocked)
+	skip[uid] = true
+
+	mutuals, err := s.repo.Mutuals(ctx, friends[:min(len(friends), maxExpand)], perShard)
+	if err != nil {
+		return nil, err
+	}
+	type cand struct {
+		id     string
+		mutual int
+	}
+	var cands []cand
+	for id, n := range mutuals {
+		if !skip[id] {
+			cands = append(cands, cand{id, n})
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].mutual != cands[j].mutual {
+			return cands[i].mutual > cands[j].mutual
+		}
+		return cands[i].id < cands[j].id
+	})
+	ids := make([]string, 0, maxCandidates)
+	seen := make(map[string]bool)
+	for _, c := range cands[:min(len(cands), maxCandidates-maxDiscovered)] {
+		ids = append(ids, c.id)
+		seen[c.id] = true
+	}
+	found, err := s.disc.Candidates(ctx, uid, maxDiscovered)
+	if err != nil {
+		log.Printf("suggest discovery %s: %v", uid, err)
+	}
+	for _, id := range found {
+		if !skip[id] && !seen[id] {
+			ids = append(ids, id)
+			seen[id] = true
+		}
+	}
+
+	v, err := s.repo.LoadViewer(uid)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.Describe(ctx, v, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].MutualFriends = mutuals[list[i].UserID]
+		list[i].rank()
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Score > list[j].Score })
+	return list[:min(len(list), listSize)], nil
+}
+
+func toSet(lists ...[]string) map[string]bool {
+	out := make(map[string]bool)
+	for _, l := range lists {
+		for _, id := range l {
+			out[id] = true
+		}
+	}
+	return out
+}
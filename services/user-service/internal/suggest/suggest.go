@@ -0,0 +1,34 @@
+// Package suggest ranks people a user may know by mutual friends, shared
+// interests and city, and keeps each user's list cached in Redis.
+package suggest
+
+// Suggestion is one ranked candidate. Score weighs the signals with the
+// weights below.
+type Suggestion struct {
+	UserID          string  `json:"user_id"`
+	Name            string  `json:"name"`
+	MutualFriends   int     `json:"mutual_friends"`
+	SharedInterests int     `json:"shared_interests"`
+	SameCity        bool    `json:"same_city"`
+	Score           float64 `json:"score"`
+}
+
+const (
+	weightMutual   = 3.0
+	weightInterest = 2.0
+	weightCity     = 1.0
+)
+
+func (s *Suggestion) rank() {
+	s.Score = weightMutual*float64(s.MutualFriends) + weightInterest*float64(s.SharedInterests)
+	if s.SameCity {
+		s.Score += weightCity
+	}
+}
+
+// Viewer is what candidates are compared against.
+type Viewer struct {
+	UserID    string
+	Interests []uint64
+	CityID    uint64
+}
@@ -0,0 +1,60 @@
+package suggest
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"users-service/internal/events"
+	"users-service/internal/social"
+
+	kf "github.com/segmentio/kafka-go"
+)
+
+// Watch refreshes the cached lists of the users named in social and profile
+// events. The cache is shared, so all instances consume as one group.
+func Watch(ctx context.Context, brokers string, svc Service) error {
+	if strings.TrimSpace(brokers) == "" {
+		brokers = "kafka:9092"
+	}
+	r := kf.NewReader(kf.ReaderConfig{
+		Brokers: strings.Split(brokers, ","),
+		GroupID: "user-service-suggestions",
+		GroupTopics: []string{
+			events.TopicFriended, events.TopicFollowed, events.TopicUnfollowed,
+			events.TopicProfileUpdated, social.BlocksTopic,
+		},
+		StartOffset: kf.LastOffset,
+		MaxWait:     time.Second,
+	})
+	defer r.Close()
+	for {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		// The fields naming users across all watched events.
+		var ev struct {
+			UserID    string `json:"user_id"`
+			FriendID  string `json:"friend_id"`
+			BlockerID string `json:"blocker_id"`
+			BlockedID string `json:"blocked_id"`
+		}
+		if err := json.Unmarshal(m.Value, &ev); err != nil {
+			log.Printf("suggest event %s: bad payload: %v", m.Topic, err)
+			continue
+		}
+		// A follow only changes the follower's list.
+		ids := []string{ev.UserID, ev.FriendID, ev.BlockerID, ev.BlockedID}
+		for _, id := range ids {
+			if id == "" {
+				continue
+			}
+			if err := svc.Refresh(ctx, id); err != nil {
+				log.Printf("suggest refresh %s: %v", id, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CardCache keeps built cards under user:card:{uid}. A nil CardCache caches
+// nothing.
+type CardCache struct {
+	r   *redis.Client
+	ttl time.Duration
+}
+
+func OpenCardCacheFromEnv() *CardCache {
+	addr := os.Getenv("CARD_REDIS_ADDR")
+	if addr == "" {
+		addr = "redis-users:6379"
+	}
+	ttl, err := time.ParseDuration(os.Getenv("CARD_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &CardCache{ttl: ttl, r: redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 500 * time.Millisecond,
+	})}
+}
+
+func cardKey(uid string) string { return "user:card:" + uid }
+
+// get returns the cached cards among ids, by user id.
+func (c *CardCache) get(ctx context.Context, ids []string) (map[string]Card, error) {
+	out := make(map[string]Card)
+	if c == nil || len(ids) == 0 {
+		return out, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = cardKey(id)
+	}
+	vals, err := c.r.MGet(ctx, keys...).Result()
+	if err != nil {
+		return out, err
+	}
+	for _, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var card Card
+		if json.Unmarshal([]byte(s), &card) == nil {
+			out[card.UserID] = card
+		}
+	}
+	return out, nil
+}
+
+func (c *CardCache) put(ctx context.Context, cards []Card) error {
+	if c == nil || len(cards) == 0 {
+		return nil
+	}
+	pipe := c.r.Pipeline()
+	for _, card := range cards {
+		b, _ := json.Marshal(card)
+		pipe.Set(ctx, cardKey(card.UserID), b, c.ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *CardCache) forget(ctx context.Context, uid string) error {
+	if c == nil {
+		return nil
+	}
+	return c.r.Del(ctx, cardKey(uid)).Err()
+}
+
+func (c *CardCache) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.r.Close()
+}
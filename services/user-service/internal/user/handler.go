@@ -3,14 +3,17 @@ package user
 import (
 	"net/http"
 
+	"users-service/internal/auth"
 	"users-service/internal/shared/httpx"
-	"users-service/internal/shared/jwt"
 	"users-service/internal/shared/validate"
 )
 
-type Handler struct{ svc Service }
+type Handler struct {
+	svc    Service
+	tokens auth.Service
+}
 
-func NewHandler(s Service) *Handler { return &Handler{svc: s} }
+func NewHandler(s Service, tokens auth.Service) *Handler { return &Handler{svc: s, tokens: tokens} }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) error {
 	body, err := httpx.Decode[RegisterReq](r)
@@ -24,13 +27,21 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	token, _ := jwt.Make(u.UserID, u.ShardID)
+	pair, err := h.tokens.Issue(u.UserID, u.ShardID, device(r, body.DeviceName))
+	if err != nil {
+		return err
+	}
 	httpx.WriteJSON(w, map[string]any{
-		"user_id": u.UserID, "name": u.Name, "email": u.Email, "access_token": token,
+		"user_id": u.UserID, "name": u.Name, "email": u.Email,
+		"access_token": pair.AccessToken, "refresh_token": pair.RefreshToken, "expires_in": pair.ExpiresIn,
 	}, http.StatusCreated)
 	return nil
 }
 
+func device(r *http.Request, name string) auth.Device {
+	return auth.Device{Name: name, IP: httpx.ClientIP(r), UserAgent: r.UserAgent()}
+}
+
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) error {
 	body, err := httpx.Decode[LoginReq](r)
 	if err != nil {
@@ -39,17 +50,154 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) error {
 	if err = validate.Struct(body); err != nil {
 		return err
 	}
-	u, err := h.svc.Login(body.Email, body.Password)
+	u, err := h.svc.Login(body.Email, body.Password, Client{IP: httpx.ClientIP(r), UserAgent: r.UserAgent()})
+	if err != nil {
+		return err
+	}
+	pair, err := h.tokens.Issue(u.UserID, u.ShardID, device(r, body.DeviceName))
 	if err != nil {
 		return err
 	}
-	token, _ := jwt.Make(u.UserID, u.ShardID)
 	httpx.WriteJSON(w, map[string]any{
-		"message": "login successful", "user_id": u.UserID, "name": u.Name, "email": u.Email, "access_token": token,
+		"message": "login successful", "user_id": u.UserID, "name": u.Name, "email": u.Email,
+		"access_token": pair.AccessToken, "refresh_token": pair.RefreshToken, "expires_in": pair.ExpiresIn,
 	}, http.StatusOK)
 	return nil
 }
 
+func (h *Handler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	body, err := httpx.Decode[PasswordResetReq](r)
+	if err != nil {
+		return err
+	}
+	if err = validate.Struct(body); err != nil {
+		return err
+	}
+	if err := h.svc.RequestPasswordReset(body.Email); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"message": "if the email is registered, a reset link has been sent"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	body, err := httpx.Decode[PasswordResetConfirmReq](r)
+	if err != nil {
+		return err
+	}
+	if err = validate.Struct(body); err != nil {
+		return err
+	}
+	if err := h.svc.ResetPassword(body.Token, body.NewPassword); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"message": "password updated"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) RequestUnlock(w http.ResponseWriter, r *http.Request) error {
+	body, err := httpx.Decode[UnlockReq](r)
+	if err != nil {
+		return err
+	}
+	if err = validate.Struct(body); err != nil {
+		return err
+	}
+	if err := h.svc.RequestUnlock(body.Email); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"message": "if the account is locked, an unlock link has been sent"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) ConfirmUnlock(w http.ResponseWriter, r *http.Request) error {
+	body, err := httpx.Decode[UnlockConfirmReq](r)
+	if err != nil {
+		return err
+	}
+	if err = validate.Struct(body); err != nil {
+		return err
+	}
+	if err := h.svc.Unlock(body.Token); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"message": "account unlocked"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) error {
+	body, err := httpx.Decode[EmailTokenReq](r)
+	if err != nil {
+		return err
+	}
+	if err = validate.Struct(body); err != nil {
+		return err
+	}
+	if err := h.svc.VerifyEmail(body.Token); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"message": "email verified"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	if err := h.svc.SendVerification(uid); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"message": "a verification link has been sent"}, http.StatusAccepted)
+	return nil
+}
+
+func (h *Handler) RequestEmailChange(w http.ResponseWriter, r *http.Request) error {
+	uid, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	body, err := httpx.Decode[EmailChangeReq](r)
+	if err != nil {
+		return err
+	}
+	if err = validate.Struct(body); err != nil {
+		return err
+	}
+	if err := h.svc.RequestEmailChange(uid, body.Password, body.NewEmail); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"message": "a confirmation link has been sent to the new email"}, http.StatusAccepted)
+	return nil
+}
+
+func (h *Handler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) error {
+	body, err := httpx.Decode[EmailTokenReq](r)
+	if err != nil {
+		return err
+	}
+	if err = validate.Struct(body); err != nil {
+		return err
+	}
+	u, err := h.svc.ConfirmEmailChange(body.Token)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"user_id": u.UserID, "email": u.Email, "email_verified": u.EmailVerified}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) LoginAttempts(w http.ResponseWriter, r *http.Request) error {
+	limit := httpx.QueryInt(r, "limit", 50)
+	offset := httpx.QueryInt(r, "offset", 0)
+	items, err := h.svc.ListLoginAttempts(r.PathValue("user_id"), limit, offset)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"items": items, "limit": limit, "offset": offset}, http.StatusOK)
+	return nil
+}
+
 func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) error {
 	uid := r.PathValue("user_id")
 	u, err := h.svc.GetByUserID(uid)
@@ -60,6 +208,22 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (h *Handler) Batch(w http.ResponseWriter, r *http.Request) error {
+	body, err := httpx.Decode[BatchReq](r)
+	if err != nil {
+		return err
+	}
+	if err = validate.Struct(body); err != nil {
+		return err
+	}
+	cards, missing, err := h.svc.Cards(r.Context(), body.UserIDs)
+	if err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]any{"items": cards, "missing": missing}, http.StatusOK)
+	return nil
+}
+
 func (h *Handler) ListMine(w http.ResponseWriter, r *http.Request) error {
 	_, shardID, err := httpx.UserFromCtx(r)
 	if err != nil {
@@ -74,3 +238,39 @@ func (h *Handler) ListMine(w http.ResponseWriter, r *http.Request) error {
 	httpx.WriteJSON(w, map[string]any{"shard_id": shardID, "limit": limit, "offset": offset, "items": users}, http.StatusOK)
 	return nil
 }
+
+func (h *Handler) Suspend(w http.ResponseWriter, r *http.Request) error {
+	if err := h.svc.SuspendBy(httpx.RoleFromCtx(r), r.PathValue("user_id")); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"status": "suspended"}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) error {
+	actor, _, err := httpx.UserFromCtx(r)
+	if err != nil {
+		return err
+	}
+	in, err := httpx.Decode[RoleReq](r)
+	if err != nil {
+		return err
+	}
+	if err := validate.Struct(in); err != nil {
+		return err
+	}
+	uid := r.PathValue("user_id")
+	if err := h.svc.SetRole(actor, uid, in.Role); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"user_id": uid, "role": in.Role}, http.StatusOK)
+	return nil
+}
+
+func (h *Handler) Unsuspend(w http.ResponseWriter, r *http.Request) error {
+	if err := h.svc.UnsuspendBy(httpx.RoleFromCtx(r), r.PathValue("user_id")); err != nil {
+		return err
+	}
+	httpx.WriteJSON(w, map[string]string{"status": "active"}, http.StatusOK)
+	return nil
+}
@@ -0,0 +1,135 @@
+package user
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"users-service/internal/ratelimit"
+	"users-service/internal/shared/httpx"
+)
+
+// Lockout throttles password guessing. Failed sign-ins are counted per email
+// and per client IP; once a counter passes its allowance the key is locked,
+// for twice as long with every further failure. Redis trouble fails open so
+// sign-in keeps working without it.
+type Lockout struct {
+	limits      *ratelimit.Limiter
+	maxPerEmail int64
+	maxPerIP    int64
+	window      time.Duration
+	base        time.Duration
+	ceiling     time.Duration
+}
+
+func NewLockoutFromEnv(l *ratelimit.Limiter) *Lockout {
+	return &Lockout{
+		limits:      l,
+		maxPerEmail: int64Env("LOGIN_MAX_FAILURES", 5),
+		maxPerIP:    int64Env("LOGIN_IP_MAX_FAILURES", 20),
+		window:      durationEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		base:        durationEnv("LOGIN_LOCK_BASE", time.Minute),
+		ceiling:     durationEnv("LOGIN_LOCK_MAX", time.Hour),
+	}
+}
+
+// LockedError is returned while an email or IP is locked out.
+type LockedError struct{ For time.Duration }
+
+func (e *LockedError) Error() string {
+	return "too many failed sign-in attempts, try again later"
+}
+func (e *LockedError) Unwrap() error             { return httpx.ErrTooManyRequests }
+func (e *LockedError) RetryAfter() time.Duration { return e.For }
+
+func emailKey(email string) string { return "login:email:" + strings.ToLower(strings.TrimSpace(email)) }
+func ipKey(ip string) string       { return "login:ip:" + ip }
+
+func (g *Lockout) keys(email, ip string) []string {
+	ks := []string{emailKey(email)}
+	if ip != "" {
+		ks = append(ks, ipKey(ip))
+	}
+	return ks
+}
+
+// check returns a LockedError if email or ip is locked.
+func (g *Lockout) check(ctx context.Context, email, ip string) error {
+	d, err := g.limits.LockedFor(ctx, g.keys(email, ip)...)
+	if err != nil {
+		log.Printf("lockout check: %v", err)
+		return nil
+	}
+	if d > 0 {
+		return &LockedError{For: d}
+	}
+	return nil
+}
+
+// locked reports whether email alone is locked.
+func (g *Lockout) locked(ctx context.Context, email string) bool {
+	d, err := g.limits.LockedFor(ctx, emailKey(email))
+	return err == nil && d > 0
+}
+
+// fail counts a failed attempt and locks what went over its allowance. It
+// reports whether the email has just been locked for the first time in this
+// window.
+func (g *Lockout) fail(ctx context.Context, email, ip string) bool {
+	first := false
+	lock := func(key string, allowed int64) {
+		n, err := g.limits.Fail(ctx, key, g.window)
+		if err != nil {
+			log.Printf("lockout count: %v", err)
+			return
+		}
+		if n < allowed {
+			return
+		}
+		if err := g.limits.Lock(ctx, key, g.backoff(n-allowed)); err != nil {
+			log.Printf("lockout lock: %v", err)
+		}
+		if key == emailKey(email) && n == allowed {
+			first = true
+		}
+	}
+	lock(emailKey(email), g.maxPerEmail)
+	if ip != "" {
+		lock(ipKey(ip), g.maxPerIP)
+	}
+	return first
+}
+
+func (g *Lockout) backoff(over int64) time.Duration {
+	d := g.base
+	for ; over > 0 && d < g.ceiling; over-- {
+		d *= 2
+	}
+	return min(d, g.ceiling)
+}
+
+// clear forgets the failures and lock of email.
+func (g *Lockout) clear(ctx context.Context, email string) {
+	if err := g.limits.Clear(ctx, emailKey(email)); err != nil {
+		log.Printf("lockout clear: %v", err)
+	}
+}
+
+func int64Env(key string, def int64) int64 {
+	n, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
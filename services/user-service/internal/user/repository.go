@@ -1,16 +1,48 @@
 package user
 
 import (
+	"context"
 	"errors"
+	"sync"
+	"time"
+
+	"users-service/internal/profile"
 	"users-service/internal/shared/db"
+	"users-service/internal/shared/httpx"
 	"users-service/internal/shared/shard"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
 	Create(u *User) (*User, error)
 	GetByEmail(email string, shardID int) (*User, error)
+	// EmailOwner returns the id of the user with email on shardID, read from
+	// the writer, or "" if there is none.
+	EmailOwner(email string, shardID int) (string, error)
 	GetByUserID(uid string) (*User, error)
 	ListByShard(shardID, limit, offset int) ([]User, error)
+	UpdatePassword(uid, passHash string) error
+	SetSuspended(uid string, at *time.Time) error
+	SetRole(uid, role string) error
+	// SetEmail changes uid's email and marks it verified.
+	SetEmail(uid, email string) error
+	// MarkVerified marks uid's email verified if it is still email.
+	MarkVerified(uid, email string) (bool, error)
+
+	// LookupEmail, ClaimEmail and DropEmail work on the email directory; see
+	// EmailEntry. LookupEmail returns "" for an email with no entry.
+	LookupEmail(email string) (string, error)
+	// ClaimEmail adds an entry for uid, failing with ErrEmailTaken if the
+	// email already points at someone else.
+	ClaimEmail(email, uid string) error
+	DropEmail(email, uid string) error
+	// GetCards builds the cards of the given users, querying their shards in
+	// parallel. Unknown and suspended users are left out.
+	GetCards(ctx context.Context, ids []string) ([]Card, error)
+	RecordAttempt(shardID int, a *LoginAttempt) error
+	ListAttempts(uid string, limit, offset int) ([]LoginAttempt, error)
 }
 
 type repo struct{ store *db.Store }
@@ -31,19 +63,199 @@ func (r *repo) GetByEmail(email string, shardID int) (*User, error) {
 	}
 	return &u, nil
 }
+func (r *repo) EmailOwner(email string, shardID int) (string, error) {
+	var u User
+	err := r.store.Write(shardID).Select("user_id").Where("email = ?", email).Take(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return u.UserID, err
+}
 func (r *repo) GetByUserID(uid string) (*User, error) {
-	sh, ok := shard.Extract(uid)
-	if !ok {
+	if _, ok := shard.Extract(uid); !ok {
 		return nil, errors.New("bad user_id")
 	}
 	var u User
-	if err := r.store.Use(sh).Where("user_id = ?", uid).First(&u).Error; err != nil {
+	if err := r.store.UseFor(uid).Where("user_id = ?", uid).First(&u).Error; err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 func (r *repo) ListByShard(shardID, limit, offset int) ([]User, error) {
 	var out []User
-	err := r.store.Use(shardID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&out).Error
+	err := r.store.Use(shardID).Where("shard_id = ?", shardID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&out).Error
 	return out, err
 }
+func (r *repo) UpdatePassword(uid, passHash string) error {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return errors.New("bad user_id")
+	}
+	return r.store.Write(sh).Model(&User{}).Where("user_id = ?", uid).Update("pass_hash", passHash).Error
+}
+func (r *repo) SetSuspended(uid string, at *time.Time) error {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return errors.New("bad user_id")
+	}
+	res := r.store.Write(sh).Model(&User{}).Where("user_id = ?", uid).Update("suspended_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return httpx.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repo) SetRole(uid, role string) error {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return errors.New("bad user_id")
+	}
+	res := r.store.Write(sh).Model(&User{}).Where("user_id = ?", uid).Update("role", role)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return httpx.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repo) RecordAttempt(shardID int, a *LoginAttempt) error {
+	return r.store.Write(shardID).Create(a).Error
+}
+
+func (r *repo) ListAttempts(uid string, limit, offset int) ([]LoginAttempt, error) {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return nil, errors.New("bad user_id")
+	}
+	var out []LoginAttempt
+	err := r.store.Use(sh).Where("user_id = ?", uid).Order("created_at DESC").Limit(limit).Offset(offset).Find(&out).Error
+	return out, err
+}
+
+func (r *repo) GetCards(ctx context.Context, ids []string) ([]Card, error) {
+	groups := make(map[int][]string)
+	for _, id := range ids {
+		if l, ok := shard.Extract(id); ok {
+			p := r.store.Physical(l)
+			groups[p] = append(groups[p], id)
+		}
+	}
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		out  []Card
+		errs []error
+	)
+	for physical, group := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cards, err := r.cardsOn(ctx, physical, group)
+			mu.Lock()
+			defer mu.Unlock()
+			out = append(out, cards...)
+			errs = append(errs, err)
+		}()
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
+
+func (r *repo) cardsOn(ctx context.Context, physical int, ids []string) ([]Card, error) {
+	var rows []struct {
+		UserID      string
+		Name        string
+		Handle      string
+		AvatarURL   string
+		Description string
+		CityID      uint64
+		PsDesc      string
+		PsCity      string
+	}
+	err := r.store.UsePhysical(physical).WithContext(ctx).Table("users u").
+		Select(`u.user_id, u.name, COALESCE(u.handle, '') AS handle, COALESCE(p.avatar_url, '') AS avatar_url,
+			COALESCE(p.description, '') AS description, COALESCE(p.city_id, 0) AS city_id,
+			COALESCE(ps.description, '') AS ps_desc, COALESCE(ps.city, '') AS ps_city`).
+		Joins("LEFT JOIN profiles p ON p.user_id = u.user_id").
+		Joins("LEFT JOIN privacy_settings ps ON ps.user_id = u.user_id").
+		Where("u.user_id IN ? AND u.shard_id IN ? AND u.suspended_at IS NULL", ids, r.store.LogicalOn(physical)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Card, len(rows))
+	for i, row := range rows {
+		c := Card{UserID: row.UserID, Name: row.Name, Handle: row.Handle, AvatarURL: row.AvatarURL, Description: row.Description, CityID: row.CityID}
+		priv := profile.Privacy{Description: row.PsDesc, City: row.PsCity}
+		if priv.Level(profile.FieldDescription) != profile.VisibilityPublic {
+			c.Description = ""
+			c.Hidden = append(c.Hidden, profile.FieldDescription)
+		}
+		if priv.Level(profile.FieldCity) != profile.VisibilityPublic {
+			c.CityID = 0
+			c.Hidden = append(c.Hidden, profile.FieldCity)
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+func (r *repo) SetEmail(uid, email string) error {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return errors.New("bad user_id")
+	}
+	res := r.store.Write(sh).Model(&User{}).Where("user_id = ?", uid).
+		Updates(map[string]any{"email": email, "email_verified": true})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return httpx.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repo) MarkVerified(uid, email string) (bool, error) {
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return false, errors.New("bad user_id")
+	}
+	res := r.store.Write(sh).Model(&User{}).Where("user_id = ? AND email = ?", uid, email).Update("email_verified", true)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *repo) directory() *gorm.DB { return r.store.WritePhysical(r.store.ControlID()) }
+
+func (r *repo) LookupEmail(email string) (string, error) {
+	var e EmailEntry
+	err := r.directory().Where("email = ?", email).Take(&e).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return e.UserID, err
+}
+
+func (r *repo) ClaimEmail(email, uid string) error {
+	res := r.directory().Clauses(clause.OnConflict{DoNothing: true}).Create(&EmailEntry{Email: email, UserID: uid})
+	if res.Error != nil || res.RowsAffected == 1 {
+		return res.Error
+	}
+	owner, err := r.LookupEmail(email)
+	if err != nil {
+		return err
+	}
+	if owner != uid {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+func (r *repo) DropEmail(email, uid string) error {
+	return r.directory().Where("email = ? AND user_id = ?", email, uid).Delete(&EmailEntry{}).Error
+}
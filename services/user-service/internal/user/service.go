@@ -1,13 +1,22 @@
 package user
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
+	"net/url"
 	"os"
-	"strconv"
+	"time"
 
+	"users-service/internal/auth"
+	"users-service/internal/events"
+	"users-service/internal/mail"
+	"users-service/internal/shared/db"
+	"users-service/internal/shared/httpx"
 	"users-service/internal/shared/shard"
 
 	"golang.org/x/crypto/bcrypt"
@@ -15,30 +24,163 @@ import (
 
 type Service interface {
 	Register(email, password, name string) (*User, error)
-	Login(email, password string) (*User, error)
+	// Login checks credentials, subject to the lockout, and records the
+	// attempt.
+	Login(email, password string, from Client) (*User, error)
 	GetByUserID(uid string) (*User, error)
+	// CheckPassword confirms the user's password before an irreversible
+	// step.
+	CheckPassword(uid, password string) error
 	ListMine(shardID, limit, offset int) ([]User, error)
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+	// Suspend locks the account and signs it out everywhere; Unsuspend
+	// lets the user sign in again.
+	Suspend(uid string) error
+	Unsuspend(uid string) error
+	// SuspendBy and UnsuspendBy do the same for a caller holding role. They
+	// refuse targets whose role is at or above the caller's.
+	SuspendBy(role, uid string) error
+	UnsuspendBy(role, uid string) error
+	// SetRole changes uid's role on behalf of actor. The user's access
+	// tokens are expired so the new role applies from their next refresh.
+	SetRole(actor, uid, role string) error
+	// Cards returns the cards of ids in the order asked, each once, and the
+	// ids that have none.
+	Cards(ctx context.Context, ids []string) (cards []Card, missing []string, err error)
+	// ForgetCard drops uid's cached card after a change to it.
+	ForgetCard(uid string)
+	// RequestUnlock mails an unlock link if the address is registered and
+	// locked out. Like RequestPasswordReset it reports success either way.
+	RequestUnlock(email string) error
+	// Unlock lifts the lockout of the email a token was sent to.
+	Unlock(token string) error
+	ListLoginAttempts(uid string, limit, offset int) ([]LoginAttempt, error)
+	// SendVerification mails uid a link that confirms their email.
+	SendVerification(uid string) error
+	VerifyEmail(token string) error
+	// RequestEmailChange mails a confirmation link to the new address; the
+	// email changes only once it is followed.
+	RequestEmailChange(uid, password, newEmail string) error
+	ConfirmEmailChange(token string) (*User, error)
 }
+
+var (
+	ErrSuspended = fmt.Errorf("%w: account suspended", httpx.ErrForbidden)
+	ErrOwnRole   = fmt.Errorf("%w: cannot change your own role", httpx.ErrForbidden)
+	ErrOutranked = fmt.Errorf("%w: user's role is not below yours", httpx.ErrForbidden)
+	ErrPassword  = fmt.Errorf("%w: wrong password", httpx.ErrForbidden)
+
+	ErrEmailTaken = fmt.Errorf("%w: email is taken", httpx.ErrConflict)
+	ErrVerified   = fmt.Errorf("%w: email already verified", httpx.ErrConflict)
+)
+
 type service struct {
 	repo      Repository
+	tokens    auth.Service
+	mailer    mail.Sender
+	events    *events.Publisher
+	cards     *CardCache
+	lockout   *Lockout
 	numShards int
+	resetTTL  time.Duration
+	resetURL  string
+	unlockTTL time.Duration
+	unlockURL string
+	verifyTTL time.Duration
+	verifyURL string
+	changeTTL time.Duration
+	changeURL string
+}
+
+func NewService(r Repository, tokens auth.Service, mailer mail.Sender, pub *events.Publisher, cards *CardCache, lockout *Lockout) Service {
+	n := db.LogicalShardsFromEnv(1)
+	ttl := time.Hour
+	if s := os.Getenv("PASSWORD_RESET_TTL"); s != "" {
+		if d, e := time.ParseDuration(s); e == nil && d > 0 {
+			ttl = d
+		}
+	}
+	link := os.Getenv("PASSWORD_RESET_URL")
+	if link == "" {
+		link = "http://localhost/reset-password?token="
+	}
+	unlockURL := os.Getenv("ACCOUNT_UNLOCK_URL")
+	if unlockURL == "" {
+		unlockURL = "http://localhost/unlock-account?token="
+	}
+	verifyURL := os.Getenv("EMAIL_VERIFY_URL")
+	if verifyURL == "" {
+		verifyURL = "http://localhost/verify-email?token="
+	}
+	changeURL := os.Getenv("EMAIL_CHANGE_URL")
+	if changeURL == "" {
+		changeURL = "http://localhost/confirm-email?token="
+	}
+	return &service{
+		repo: r, tokens: tokens, mailer: mailer, events: pub, cards: cards, lockout: lockout,
+		numShards: n, resetTTL: ttl, resetURL: link,
+		unlockTTL: durationEnv("ACCOUNT_UNLOCK_TTL", time.Hour), unlockURL: unlockURL,
+		verifyTTL: durationEnv("EMAIL_VERIFY_TTL", 48*time.Hour), verifyURL: verifyURL,
+		changeTTL: durationEnv("EMAIL_CHANGE_TTL", time.Hour), changeURL: changeURL,
+	}
+}
+
+// locate returns the shard holding the user with email: the one in the
+// directory entry's user id, or for users older than the directory, the one
+// the email picks.
+func (s *service) locate(email string) (int, error) {
+	uid, err := s.repo.LookupEmail(email)
+	if err != nil || uid == "" {
+		return shard.Pick(email, s.numShards), err
+	}
+	sh, ok := shard.Extract(uid)
+	if !ok {
+		return 0, errors.New("bad user_id")
+	}
+	return sh, nil
+}
+
+// claim enters email in the directory for uid. Users older than the
+// directory have no entry, so the shard the email picks is then checked on
+// its writer; the claim is dropped again if someone there has the email.
+func (s *service) claim(email, uid string) error {
+	if err := s.repo.ClaimEmail(email, uid); err != nil {
+		return err
+	}
+	owner, err := s.repo.EmailOwner(email, shard.Pick(email, s.numShards))
+	if err == nil && owner != "" && owner != uid {
+		err = ErrEmailTaken
+	}
+	if err != nil {
+		s.release(email, uid)
+	}
+	return err
+}
+
+func (s *service) release(email, uid string) {
+	if err := s.repo.DropEmail(email, uid); err != nil {
+		log.Printf("email directory drop %s: %v", uid, err)
+	}
 }
 
-func NewService(r Repository) Service {
-	n := 1
-	if s := os.Getenv("NUM_SHARDS"); s != "" {
-		if v, e := strconv.Atoi(s); e == nil && v > 0 {
-			n = v
+// taken reports whether email belongs to anyone but uid. It is a courtesy
+// check for early errors; claim is what keeps emails unique.
+func (s *service) taken(email, uid string) (bool, error) {
+	owner, err := s.repo.LookupEmail(email)
+	if err != nil {
+		return false, err
+	}
+	if owner == "" {
+		if owner, err = s.repo.EmailOwner(email, shard.Pick(email, s.numShards)); err != nil {
+			return false, err
 		}
 	}
-	return &service{repo: r, numShards: n}
+	return owner != "" && owner != uid, nil
 }
 
 func (s *service) Register(email, password, name string) (*User, error) {
 	sh := shard.Pick(email, s.numShards)
-	if exist, _ := s.repo.GetByEmail(email, sh); exist != nil {
-		return nil, errors.New("user exists")
-	}
 	var b [8]byte
 	_, _ = rand.Read(b[:])
 	uid := fmt.Sprintf("%d-%x", sh, binary.BigEndian.Uint64(b[:]))
@@ -46,22 +188,413 @@ func (s *service) Register(email, password, name string) (*User, error) {
 	if err != nil {
 		return nil, errors.New("hash fail")
 	}
-	return s.repo.Create(&User{
+	if err := s.claim(email, uid); errors.Is(err, ErrEmailTaken) {
+		return nil, errors.New("user exists")
+	} else if err != nil {
+		return nil, err
+	}
+	u, err := s.repo.Create(&User{
 		UserID: uid, ShardID: sh, Email: email, PassHash: string(hash), Name: name,
 	})
+	if err != nil {
+		s.release(email, uid)
+		return nil, err
+	}
+	s.events.Publish(events.TopicUserRegistered, uid, events.UserRegistered{
+		Meta: events.NewMeta(events.TopicUserRegistered), UserID: uid, Name: name,
+	})
+	s.mailVerification(u)
+	return u, nil
 }
-func (s *service) Login(email, password string) (*User, error) {
-	sh := shard.Pick(email, s.numShards)
+
+var errWrongCredentials = errors.New("wrong credentials")
+
+func (s *service) Login(email, password string, from Client) (*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	sh, err := s.locate(email)
+	if err != nil {
+		return nil, err
+	}
+	attempt := &LoginAttempt{Email: email, IP: from.IP, UserAgent: from.UserAgent}
+	defer s.record(sh, attempt)
+
+	if err := s.lockout.check(ctx, email, from.IP); err != nil {
+		attempt.Outcome = LoginLocked
+		return nil, err
+	}
 	u, err := s.repo.GetByEmail(email, sh)
 	if err != nil {
-		return nil, errors.New("wrong credentials")
+		attempt.Outcome = LoginNoAccount
+		s.lockout.fail(ctx, email, from.IP)
+		return nil, errWrongCredentials
 	}
+	attempt.UserID = u.UserID
 	if bcrypt.CompareHashAndPassword([]byte(u.PassHash), []byte(password)) != nil {
-		return nil, errors.New("wrong credentials")
+		attempt.Outcome = LoginBadPassword
+		if s.lockout.fail(ctx, email, from.IP) {
+			s.mailUnlock(u)
+		}
+		return nil, errWrongCredentials
+	}
+	if u.SuspendedAt != nil {
+		attempt.Outcome = LoginSuspended
+		return nil, ErrSuspended
+	}
+	attempt.Outcome = LoginSucceeded
+	s.lockout.clear(ctx, email)
+	return u, nil
+}
+
+func (s *service) record(shardID int, a *LoginAttempt) {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	a.AttemptID = hex.EncodeToString(b[:])
+	if err := s.repo.RecordAttempt(shardID, a); err != nil {
+		log.Printf("login attempt for %s: %v", a.Email, err)
+	}
+}
+
+func (s *service) RequestUnlock(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if !s.lockout.locked(ctx, email) {
+		return nil
+	}
+	sh, err := s.locate(email)
+	if err != nil {
+		return err
+	}
+	u, err := s.repo.GetByEmail(email, sh)
+	if err != nil {
+		return nil
+	}
+	s.mailUnlock(u)
+	return nil
+}
+
+func (s *service) Unlock(token string) error {
+	uid, err := s.tokens.ConsumeOneTime(token, auth.PurposeAccountUnlock)
+	if err != nil {
+		return err
+	}
+	u, err := s.repo.GetByUserID(uid)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	s.lockout.clear(ctx, u.Email)
+	return nil
+}
+
+// mailUnlock sends u a link that lifts the lockout on their email.
+func (s *service) mailUnlock(u *User) {
+	tok, err := s.tokens.IssueOneTime(u.UserID, auth.PurposeAccountUnlock, s.unlockTTL)
+	if err != nil {
+		log.Printf("unlock token for %s: %v", u.UserID, err)
+		return
+	}
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Sign-in to your account was paused",
+		Body: fmt.Sprintf("Hi %s,\n\nThere were several failed attempts to sign in to your account, so sign-in has been paused for a while. If that was you, use the link below to sign in again right away. It expires in %s and works once.\n\n%s%s\n\nIf it was not you, consider changing your password.\n",
+			u.Name, s.unlockTTL, s.unlockURL, url.QueryEscape(tok)),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("unlock mail to %s: %v", u.UserID, err)
+		}
+	}()
+}
+
+// mailVerification sends u a link that confirms they own their email.
+func (s *service) mailVerification(u *User) {
+	tok, err := s.tokens.IssueOneTimeFor(u.UserID, auth.PurposeEmailVerify, u.Email, s.verifyTTL)
+	if err != nil {
+		log.Printf("verify token for %s: %v", u.UserID, err)
+		return
+	}
+	s.send(u.UserID, "verify", mail.Message{
+		To:      u.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to confirm this is your email. It expires in %s and works once.\n\n%s%s\n\nIf you did not sign up, ignore this email.\n",
+			u.Name, s.verifyTTL, s.verifyURL, url.QueryEscape(tok)),
+	})
+}
+
+func (s *service) send(uid, kind string, msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("%s mail to %s: %v", kind, uid, err)
+		}
+	}()
+}
+
+func (s *service) SendVerification(uid string) error {
+	u, err := s.repo.GetByUserID(uid)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerified {
+		return ErrVerified
+	}
+	s.mailVerification(u)
+	return nil
+}
+
+func (s *service) VerifyEmail(token string) error {
+	uid, email, err := s.tokens.ConsumeOneTimeFor(token, auth.PurposeEmailVerify)
+	if err != nil {
+		return err
 	}
+	ok, err := s.repo.MarkVerified(uid, email)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: email has changed since the link was sent", httpx.ErrConflict)
+	}
+	return nil
+}
+
+func (s *service) RequestEmailChange(uid, password, newEmail string) error {
+	if err := s.CheckPassword(uid, password); err != nil {
+		return err
+	}
+	u, err := s.repo.GetByUserID(uid)
+	if err != nil {
+		return err
+	}
+	if newEmail == u.Email {
+		return fmt.Errorf("%w: that is already your email", httpx.ErrConflict)
+	}
+	if taken, err := s.taken(newEmail, uid); err != nil {
+		return err
+	} else if taken {
+		return ErrEmailTaken
+	}
+	tok, err := s.tokens.IssueOneTimeFor(uid, auth.PurposeEmailChange, newEmail, s.changeTTL)
+	if err != nil {
+		return err
+	}
+	s.send(uid, "email change", mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to make this your account's email. It expires in %s and works once.\n\n%s%s\n\nIf you did not ask for this, ignore this email.\n",
+			u.Name, s.changeTTL, s.changeURL, url.QueryEscape(tok)),
+	})
+	s.send(uid, "email change notice", mail.Message{
+		To:      u.Email,
+		Subject: "Your email is about to change",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change your account's email to %s. It changes only once the link sent there is followed. If this was not you, change your password.\n",
+			u.Name, newEmail),
+	})
+	return nil
+}
+
+// ConfirmEmailChange moves the user to the email in token. The user keeps
+// their shard even if the new email picks another one; the directory entry
+// is how sign-in finds them.
+func (s *service) ConfirmEmailChange(token string) (*User, error) {
+	uid, email, err := s.tokens.ConsumeOneTimeFor(token, auth.PurposeEmailChange)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.repo.GetByUserID(uid)
+	if err != nil {
+		return nil, err
+	}
+	old := u.Email
+	if old == email {
+		return u, nil
+	}
+	if err := s.claim(email, uid); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetEmail(uid, email); err != nil {
+		s.release(email, uid)
+		return nil, err
+	}
+	s.release(old, uid)
+	s.send(uid, "email changed", mail.Message{
+		To:      old,
+		Subject: "Your email was changed",
+		Body:    fmt.Sprintf("Hi %s,\n\nYour account's email is now %s. If this was not you, contact support.\n", u.Name, email),
+	})
+	u.Email, u.EmailVerified = email, true
 	return u, nil
 }
+
+func (s *service) ListLoginAttempts(uid string, limit, offset int) ([]LoginAttempt, error) {
+	return s.repo.ListAttempts(uid, limit, offset)
+}
 func (s *service) GetByUserID(uid string) (*User, error) { return s.repo.GetByUserID(uid) }
+func (s *service) CheckPassword(uid, password string) error {
+	u, err := s.repo.GetByUserID(uid)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PassHash), []byte(password)) != nil {
+		return ErrPassword
+	}
+	return nil
+}
 func (s *service) ListMine(shardID, limit, offset int) ([]User, error) {
 	return s.repo.ListByShard(shardID, limit, offset)
 }
+
+// RequestPasswordReset mails a reset link if the address is registered. It
+// reports success either way so the endpoint cannot be used to probe emails.
+func (s *service) RequestPasswordReset(email string) error {
+	sh, err := s.locate(email)
+	if err != nil {
+		return err
+	}
+	u, err := s.repo.GetByEmail(email, sh)
+	if err != nil {
+		return nil
+	}
+	tok, err := s.tokens.IssueOneTime(u.UserID, auth.PurposePasswordReset, s.resetTTL)
+	if err != nil {
+		return err
+	}
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s and works once.\n\n%s%s\n\nIf you did not ask for this, ignore this email.\n",
+			u.Name, s.resetTTL, s.resetURL, url.QueryEscape(tok)),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("password reset mail to %s: %v", u.UserID, err)
+		}
+	}()
+	return nil
+}
+
+func (s *service) ResetPassword(token, newPassword string) error {
+	uid, err := s.tokens.ConsumeOneTime(token, auth.PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("hash fail")
+	}
+	if err := s.repo.UpdatePassword(uid, string(hash)); err != nil {
+		return err
+	}
+	return s.tokens.RevokeAll(uid)
+}
+
+func (s *service) Suspend(uid string) error {
+	now := time.Now().UTC()
+	if err := s.repo.SetSuspended(uid, &now); err != nil {
+		return err
+	}
+	s.ForgetCard(uid)
+	return s.tokens.RevokeAll(uid)
+}
+
+func (s *service) Unsuspend(uid string) error {
+	if err := s.repo.SetSuspended(uid, nil); err != nil {
+		return err
+	}
+	s.ForgetCard(uid)
+	return nil
+}
+
+func (s *service) SuspendBy(role, uid string) error {
+	if err := s.checkOutranks(role, uid); err != nil {
+		return err
+	}
+	return s.Suspend(uid)
+}
+
+func (s *service) UnsuspendBy(role, uid string) error {
+	if err := s.checkOutranks(role, uid); err != nil {
+		return err
+	}
+	return s.Unsuspend(uid)
+}
+
+// checkOutranks refuses a caller holding role to act on uid unless uid's role
+// is below it.
+func (s *service) checkOutranks(role, uid string) error {
+	u, err := s.repo.GetByUserID(uid)
+	if err != nil {
+		return err
+	}
+	if httpx.HasRole(u.Role, role) {
+		return ErrOutranked
+	}
+	return nil
+}
+
+func (s *service) SetRole(actor, uid, role string) error {
+	if actor == uid {
+		return ErrOwnRole
+	}
+	if err := s.repo.SetRole(uid, role); err != nil {
+		return err
+	}
+	return s.tokens.ExpireAccess(uid)
+}
+
+func (s *service) Cards(ctx context.Context, ids []string) ([]Card, []string, error) {
+	var want []string
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			want = append(want, id)
+		}
+	}
+	found, err := s.cards.get(ctx, want)
+	if err != nil {
+		log.Printf("card cache: %v", err)
+	}
+	var misses []string
+	for _, id := range want {
+		if _, ok := found[id]; !ok {
+			misses = append(misses, id)
+		}
+	}
+	if len(misses) > 0 {
+		built, err := s.repo.GetCards(ctx, misses)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range built {
+			found[c.UserID] = c
+		}
+		if err := s.cards.put(ctx, built); err != nil {
+			log.Printf("card cache: %v", err)
+		}
+	}
+	cards := make([]Card, 0, len(want))
+	var missing []string
+	for _, id := range want {
+		if c, ok := found[id]; ok {
+			cards = append(cards, c)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return cards, missing, nil
+}
+
+func (s *service) ForgetCard(uid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.cards.forget(ctx, uid); err != nil {
+		log.Printf("card cache forget %s: %v", uid, err)
+	}
+}
@@ -3,22 +3,116 @@ package user
 import "time"
 
 type User struct {
-	UserID    string    `gorm:"uniqueIndex;size:64" json:"user_id"`
-	ShardID   int       `gorm:"index" json:"shard_id"`
-	ID        uint      `gorm:"primaryKey" json:"-"`
-	Email     string    `gorm:"uniqueIndex;size:120" json:"email"`
-	PassHash  string    `gorm:"size:255" json:"-"`
-	Name      string    `gorm:"size:100" json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UserID  string `gorm:"uniqueIndex;size:64" json:"user_id"`
+	ShardID int    `gorm:"index" json:"shard_id"`
+	ID      uint   `gorm:"primaryKey" json:"-"`
+	Email   string `gorm:"uniqueIndex;size:120" json:"email"`
+	// EmailVerified is set once the user follows a link mailed to Email.
+	EmailVerified bool   `gorm:"not null;default:false" json:"email_verified"`
+	PassHash      string `gorm:"size:255" json:"-"`
+	Name          string `gorm:"size:100" json:"name"`
+	// Handle copies the user's entry in the handle directory; see package
+	// handle.
+	Handle      string     `gorm:"size:30" json:"handle,omitempty"`
+	Role        string     `gorm:"size:16;default:user" json:"role"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type RegisterReq struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
-	Name     string `json:"name" validate:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,min=6"`
+	Name       string `json:"name" validate:"required"`
+	DeviceName string `json:"device_name" validate:"max=100"`
 }
 type LoginReq struct {
-	Email    string `json:"email" validate:"required,email"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required"`
+	DeviceName string `json:"device_name" validate:"max=100"`
+}
+
+type RoleReq struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+type PasswordResetReq struct {
+	Email string `json:"email" validate:"required,email"`
+}
+type PasswordResetConfirmReq struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// Card is the short form of a user shown next to their posts, comments and
+// messages. It carries only what everyone may see; fields the user keeps
+// from the public are blank and listed in Hidden.
+type Card struct {
+	UserID      string   `json:"user_id"`
+	Name        string   `json:"name"`
+	Handle      string   `json:"handle,omitempty"`
+	AvatarURL   string   `json:"avatar_url,omitempty"`
+	Description string   `json:"description,omitempty"`
+	CityID      uint64   `json:"city_id,omitempty"`
+	Hidden      []string `json:"hidden,omitempty"`
+}
+
+type BatchReq struct {
+	UserIDs []string `json:"user_ids" validate:"required,min=1,max=300,dive,required,max=64"`
+}
+
+// Outcomes of a sign-in attempt.
+const (
+	LoginSucceeded   = "success"
+	LoginBadPassword = "bad_password"
+	LoginNoAccount   = "unknown_email"
+	LoginLocked      = "locked"
+	LoginSuspended   = "suspended"
+)
+
+// LoginAttempt is one sign-in try, kept on the shard the email maps to,
+// which is also its owner's shard. UserID is empty for unknown emails.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	AttemptID string    `gorm:"size:32;uniqueIndex" json:"attempt_id"`
+	UserID    string    `gorm:"size:64;index" json:"user_id,omitempty"`
+	Email     string    `gorm:"size:120;index" json:"email"`
+	IP        string    `gorm:"size:64" json:"ip"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	Outcome   string    `gorm:"size:16" json:"outcome"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// Client describes where a request came from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+type EmailChangeReq struct {
+	NewEmail string `json:"new_email" validate:"required,email,max=120"`
 	Password string `json:"password" validate:"required"`
 }
+type EmailTokenReq struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailEntry points an email at its owner. Its primary key is what keeps
+// emails unique across shards: a user never leaves the shard in their id, so
+// after an email change they need not be on the shard the email picks.
+// Entries live on the control shard; users registered before it existed
+// have none and are found on the shard their email picks.
+type EmailEntry struct {
+	Email     string `gorm:"primaryKey;size:120"`
+	UserID    string `gorm:"size:64;index"`
+	CreatedAt time.Time
+}
+
+func (EmailEntry) TableName() string { return "email_directory" }
+
+type UnlockReq struct {
+	Email string `json:"email" validate:"required,email"`
+}
+type UnlockConfirmReq struct {
+	Token string `json:"token" validate:"required"`
+}